@@ -0,0 +1,387 @@
+package main
+
+/*
+    This file contains the analysis report of a maze (-analyze flag), to compare and balance mazes with numbers.
+
+    The report has:
+    - the corridor graph of the maze: paths, junctions (3 or 4 ways), dead ends, the longest corridor between two
+      junctions (or dead ends), the loops (how many connections could be walled without splitting the maze) and the food
+      which can be reached from the start of PacMan
+    - the difficulty, from games the autopilot plays against the enemies on the maze. Each game has its own seed of the
+      random numbers (1, 2, 3, ...) like the autopilot report (see autopilottest.go), so the report is the same each time
+    - a heatmap image of where PacMan died, a brighter red maze point is a place PacMan died more often
+
+    The games use the settings of the level which has the maze (Ex: the enemy speed of level 2 for maze02.txt), or the
+    settings of level 1 for another maze. Ex: -analyze maze02.txt -games 200 -heatmap maze02-deaths.png
+*/
+import (
+    "fmt"
+    "image"
+    "image/color"
+    "image/png"
+    "log"
+    "math"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure which keeps the corridor graph statistics of a maze
+type MazeGraphStats struct {
+    paths int // holds the number of maze points which are not walls
+    junctions int // holds the number of paths with 3 or 4 ways to go
+    deadEnds int // holds the number of paths with only 1 way to go
+    longestCorridor int // holds the most steps between two junctions or dead ends
+    loops int // holds the number of independent loops (connections - paths + parts)
+    parts int // holds the number of parts of the maze which are not connected to each other
+    food int // holds the number of food and power pellets
+    reachableFood int // holds the number of food and power pellets PacMan can get to from its start
+}
+
+// Structure which keeps the results of the simulated games of a maze
+type MazeGameStats struct {
+    games int // holds the number of games played
+    clears int // holds the number of games the maze was cleared in
+    deaths int // holds the number of lives lost in all the games
+    clearFrames int // holds the frames of all the games the maze was cleared in
+    deathPoints map[[2]int]int // holds the number of deaths on each maze point
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the size (pixels) of a maze point in the heatmap image
+var heatmapScale = 8
+
+// Let's define the colors of the heatmap: walls, paths, and the paths with the most deaths
+var heatmapWallColor = color.RGBA{R: 33, G: 33, B: 110, A: 255}
+var heatmapPathColor = color.RGBA{R: 30, G: 30, B: 30, A: 255}
+var heatmapDeathColor = color.RGBA{R: 255, G: 40, B: 20, A: 255}
+
+// Let's define the number of places with the most deaths shown in the report
+var analyzeTopDeaths = 5
+
+/*
+    #################################
+    ## Functions to analyze a maze ##
+    #################################
+*/
+
+/*
+    Function: getMazeGraphStats
+    Get the corridor graph statistics of a maze
+    Input: maze
+*/
+func getMazeGraphStats(maze Maze) MazeGraphStats {
+    stats := MazeGraphStats{}
+    connections := 0
+    start := [2]int{-1, -1}
+    for row, line := range maze.rows {
+        for col := range line {
+            if !isMazePath(maze.rows, col, row) {
+                continue
+            }
+            stats.paths++
+            ways := len(getMazeNeighbors(maze, col, row))
+            connections = connections+ways
+            if ways >= 3 {
+                stats.junctions++
+            } else if ways == 1 {
+                stats.deadEnds++
+            }
+            if line[col] == '.' || line[col] == 'o' {
+                stats.food++
+            }
+            if line[col] == 'P' {
+                start = [2]int{col, row}
+            }
+        }
+    }
+
+    // each connection is counted from both of its paths
+    connections = connections/2
+    stats.parts = getMazeParts(maze)
+    stats.loops = connections-stats.paths+stats.parts
+    stats.longestCorridor = getLongestCorridor(maze)
+
+    if start[0] >= 0 {
+        for point := range getReachablePoints(maze, start[0], start[1]) {
+            if tile := maze.rows[point[1]][point[0]]; tile == '.' || tile == 'o' {
+                stats.reachableFood++
+            }
+        }
+    }
+    return stats
+}
+
+/*
+    Function: getMazeParts
+    Get the number of parts of a maze which are not connected to each other
+    Input: maze
+*/
+func getMazeParts(maze Maze) int {
+    parts := 0
+    reached := map[[2]int]bool{}
+    for row, line := range maze.rows {
+        for col := range line {
+            if !isMazePath(maze.rows, col, row) || reached[[2]int{col, row}] {
+                continue
+            }
+            parts++
+            for point := range getReachablePoints(maze, col, row) {
+                reached[point] = true
+            }
+        }
+    }
+    return parts
+}
+
+/*
+    Function: getLongestCorridor
+    Get the most steps between two junctions or dead ends, going along the paths with 2 ways to go
+    Input: maze
+*/
+func getLongestCorridor(maze Maze) int {
+    longest := 0
+    for row, line := range maze.rows {
+        for col := range line {
+            if !isMazePath(maze.rows, col, row) || len(getMazeNeighbors(maze, col, row)) == 2 {
+                continue
+            }
+            // let's walk each corridor which starts here until the next junction or dead end
+            for _, next := range getMazeNeighbors(maze, col, row) {
+                previous := [2]int{col, row}
+                steps := 1
+                for next != [2]int{col, row} {
+                    neighbors := getMazeNeighbors(maze, next[0], next[1])
+                    if len(neighbors) != 2 {
+                        break
+                    }
+                    following := neighbors[0]
+                    if following == previous {
+                        following = neighbors[1]
+                    }
+                    previous, next = next, following
+                    steps++
+                }
+                if steps > longest {
+                    longest = steps
+                }
+            }
+        }
+    }
+    return longest
+}
+
+/*
+    Function: simulateMazeGames
+    Let the autopilot play one player games on the maze of a level and keep where PacMan died
+    A game ends when the maze is cleared, when the game is over or when it takes too long (see autopilotTestFrames)
+    Inputs: settings of the level with the maze and the number of games
+*/
+func simulateMazeGames(info LevelInfo, games int) MazeGameStats {
+    stats := MazeGameStats{games: games, deathPoints: map[[2]int]int{}}
+
+    // the level of the maze is played alone, and the high score doesn't change (like the autopilot report)
+    levels := LEVELS
+    LEVELS = map[int]LevelInfo{1: info}
+    isDemo = true
+    defer func() {
+        LEVELS = levels
+        isDemo = false
+    }()
+
+    // the PacMan who is caught is still where it died when the death is emitted
+    addEventListener(func(event string) {
+        if event == eventDeath && len(pacmen) > 0 {
+            col, row := getMazePointFromPosition(pacmen[0].x, pacmen[0].y)
+            stats.deathPoints[[2]int{col, row}]++
+            stats.deaths++
+        }
+    })
+    for game := 1; game <= games; game++ {
+        seedRandom(int64(game))
+        newGame()
+        startPlayers(1)
+        isNewGame = false
+
+        for frame := 0; frame < autopilotTestFrames; frame++ {
+            if gameInfo.isGameOver {
+                break
+            }
+            if gameInfo.isLevelComplete {
+                stats.clears++
+                stats.clearFrames = stats.clearFrames+frame
+                break
+            }
+            updateGame()
+        }
+    }
+    return stats
+}
+
+/*
+    Function: getDifficulty
+    Get the difficulty of a maze from the lives the autopilot lost in a game, as a part of all its lives (0 to 1) and
+    as a word
+    Input: results of the simulated games
+*/
+func (stats MazeGameStats) getDifficulty() (float64, string) {
+    // extra lives can make PacMan lose more lives than it starts with
+    difficulty := math.Min(float64(stats.deaths)/float64(stats.games*startingLives), 1)
+    switch {
+    case difficulty < 0.25:
+        return difficulty, "easy"
+    case difficulty < 0.5:
+        return difficulty, "medium"
+    case difficulty < 0.75:
+        return difficulty, "hard"
+    }
+    return difficulty, "very hard"
+}
+
+/*
+    Function: writeHeatmapFile
+    Write the heatmap of the deaths as a PNG image. A maze point is redder with more deaths on it
+    Inputs: path to the image, maze and the number of deaths on each maze point
+*/
+func writeHeatmapFile(fileName string, maze Maze, deathPoints map[[2]int]int) error {
+    cols := 0
+    for _, line := range maze.rows {
+        if len(line) > cols {
+            cols = len(line)
+        }
+    }
+    mostDeaths := 0
+    for _, deaths := range deathPoints {
+        if deaths > mostDeaths {
+            mostDeaths = deaths
+        }
+    }
+
+    img := image.NewRGBA(image.Rect(0, 0, cols*heatmapScale, len(maze.rows)*heatmapScale))
+    for row := range maze.rows {
+        for col := 0; col < cols; col++ {
+            clr := heatmapWallColor
+            if isMazePath(maze.rows, col, row) {
+                clr = heatmapPathColor
+                if deaths := deathPoints[[2]int{col, row}]; deaths > 0 {
+                    clr = mixColors(heatmapPathColor, heatmapDeathColor, float64(deaths)/float64(mostDeaths))
+                }
+            }
+            for y := row*heatmapScale; y < (row+1)*heatmapScale; y++ {
+                for x := col*heatmapScale; x < (col+1)*heatmapScale; x++ {
+                    img.Set(x, y, clr)
+                }
+            }
+        }
+    }
+
+    file, err := os.Create(fileName)
+    if err != nil {
+        return err
+    }
+    if err := png.Encode(file, img); err != nil {
+        file.Close()
+        return err
+    }
+    return file.Close()
+}
+
+/*
+    Function: mixColors
+    Mix two colors, 0 is the first color and 1 is the second color
+    Inputs: colors and how much of the second color is used
+*/
+func mixColors(from color.RGBA, to color.RGBA, amount float64) color.RGBA {
+    mix := func(a uint8, b uint8) uint8 {
+        return uint8(float64(a)+(float64(b)-float64(a))*amount)
+    }
+    return color.RGBA{R: mix(from.R, to.R), G: mix(from.G, to.G), B: mix(from.B, to.B), A: 255}
+}
+
+/*
+    Function: runMazeAnalysis
+    Print the analysis report of a maze and write the heatmap of the deaths
+    Inputs: path to the maze file, number of games to simulate and the path to the heatmap image (next to the maze if empty)
+*/
+func runMazeAnalysis(fileName string, games int, heatmapFile string) {
+    // let's use the settings of the level which has the maze, or level 1 with the maze
+    level := 1
+    mazePath, _ := filepath.Abs(fileName)
+    for number := 1; number <= len(LEVELS); number++ {
+        if levelPath, _ := filepath.Abs(LEVELS[number].mazeFile); levelPath == mazePath {
+            level = number
+            break
+        }
+    }
+    info := LEVELS[level]
+    info.mazeFile = fileName
+    maze := readMazeFile(fileName)
+
+    fmt.Printf("maze: %s\n", fileName)
+    if info := getMazeInfo(maze); info != "" {
+        fmt.Println(info)
+    }
+    graph := getMazeGraphStats(maze)
+    fmt.Printf("paths: %d\n", graph.paths)
+    fmt.Printf("junctions: %d\n", graph.junctions)
+    fmt.Printf("dead ends: %d\n", graph.deadEnds)
+    fmt.Printf("longest corridor: %d steps\n", graph.longestCorridor)
+    fmt.Printf("loops: %d\n", graph.loops)
+    if graph.parts > 1 {
+        fmt.Printf("parts: %d (some paths are not connected)\n", graph.parts)
+    }
+    fmt.Printf("food reachable from P: %d/%d\n", graph.reachableFood, graph.food)
+
+    if games <= 0 {
+        return
+    }
+    stats := simulateMazeGames(info, games)
+    difficulty, word := stats.getDifficulty()
+    fmt.Printf("simulated %d games with the settings of level %d\n", games, level)
+    fmt.Printf("cleared: %d/%d (%.1f%%)\n", stats.clears, games, float64(stats.clears)*100/float64(games))
+    fmt.Printf("lives lost per game: %.2f of %d\n", float64(stats.deaths)/float64(games), startingLives)
+    if stats.clears > 0 {
+        fmt.Printf("average time to clear: %.1f seconds\n", float64(stats.clearFrames)/float64(stats.clears)/60)
+    }
+    fmt.Printf("difficulty: %s (%.0f%% of the lives lost)\n", word, difficulty*100)
+
+    // let's show the places with the most deaths, the same places first each time
+    points := [][2]int{}
+    for point := range stats.deathPoints {
+        points = append(points, point)
+    }
+    sort.Slice(points, func(i, j int) bool {
+        if stats.deathPoints[points[i]] != stats.deathPoints[points[j]] {
+            return stats.deathPoints[points[i]] > stats.deathPoints[points[j]]
+        }
+        if points[i][1] != points[j][1] {
+            return points[i][1] < points[j][1]
+        }
+        return points[i][0] < points[j][0]
+    })
+    for i, point := range points {
+        if i == analyzeTopDeaths {
+            break
+        }
+        fmt.Printf("deaths at %d,%d: %d\n", point[0], point[1], stats.deathPoints[point])
+    }
+
+    if heatmapFile == "" {
+        heatmapFile = strings.TrimSuffix(fileName, filepath.Ext(fileName))+"-deaths.png"
+    }
+    if err := writeHeatmapFile(heatmapFile, maze, stats.deathPoints); err != nil {
+        log.Fatal(err)
+    }
+    fmt.Printf("heatmap: %s\n", heatmapFile)
+}
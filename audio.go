@@ -0,0 +1,232 @@
+package main
+
+/*
+    This file contains the sounds of the game.
+
+    Sounds are played when game events happen (see events.go). While PacMan is playing, a siren is looping in
+    the background. The pitch of the siren goes up as the food left in the maze (maxScore - score) gets less.
+
+    Sounds are played by a sound backend:
+    - ebiten audio backend: plays the sounds with the audio package of ebiten (see ebitenaudio.go)
+    - no audio backend    : does nothing. It is used when there's no audio device (Ex: headless servers) or sound is turned off
+*/
+import (
+    "log"
+    "math"
+    "sync"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Interface which has to be implemented by a sound backend
+type SoundBackend interface {
+    playEffect(name string) // play the sound effect with the given name once
+    setSiren(playing bool, pitch float64) // start or stop the siren, pitch is from 0 (lowest) to 1 (highest)
+    setVolume(volume float64) // set the volume of all the sounds, from 0 (silent) to 1 (loudest)
+}
+
+// Structure of the sound backend which does nothing
+type NoAudioBackend struct{}
+
+// Structure which generates the siren sound as an endless stream of PCM data
+type SirenStream struct {
+    mutex sync.Mutex // the stream is read by the audio player from another goroutine, so the pitch is guarded by a mutex
+    pitch float64 // holds the pitch of the siren, from 0 (lowest) to 1 (highest)
+    phase float64 // holds the position in the wave (0 to 1), so the wave continues smoothly when the pitch changes
+    wobblePhase float64 // holds the position in the slow up and down movement of the frequency (0 to 1)
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's have a variable to define the sample rate of all the sounds (samples per second)
+var sampleRate = 44100
+
+// Let's have variables to define the lowest and the highest frequency of the siren
+var sirenMinFrequency = 300.0
+var sirenMaxFrequency = 700.0
+
+// Let's have a variable to define how fast the siren goes up and down (times per second)
+var sirenWobble = 4.0
+
+// Let's have variables to hold the sound settings
+var soundVolume = 0.5
+var isMuted = false
+
+// Let's define the sound effect of each game event
+var eventEffects = map[string]string{
+    eventFoodEaten: "food",
+    eventPelletEaten: "pellet",
+    eventFruitEaten: "fruit",
+    eventEnemyEaten: "enemyEaten",
+    eventDeath: "death",
+    eventExtraLife: "extraLife",
+    eventLevelClear: "levelClear",
+}
+
+// Variable to hold the sound backend used by the game
+var soundBackend SoundBackend = NoAudioBackend{}
+
+/*
+    ######################################
+    ## No audio backend (does nothing) ##
+    ######################################
+*/
+func (NoAudioBackend) playEffect(name string) {}
+func (NoAudioBackend) setSiren(playing bool, pitch float64) {}
+func (NoAudioBackend) setVolume(volume float64) {}
+
+/*
+    ##################
+    ## Siren stream ##
+    ##################
+*/
+
+/*
+    Function: setPitch
+    Change the pitch of the siren
+    Input: pitch, from 0 (lowest) to 1 (highest)
+*/
+func (stream *SirenStream) setPitch(pitch float64) {
+    stream.mutex.Lock()
+    defer stream.mutex.Unlock()
+    stream.pitch = math.Max(0, math.Min(1, pitch))
+}
+
+/*
+    Function: Read
+    Fill the buffer with the next samples of the siren (the audio player calls this to get the sound)
+    The siren is a triangle wave which goes up and down around a base frequency given by the pitch
+*/
+func (stream *SirenStream) Read(buffer []byte) (int, error) {
+    stream.mutex.Lock()
+    defer stream.mutex.Unlock()
+
+    baseFrequency := sirenMinFrequency+(sirenMaxFrequency-sirenMinFrequency)*stream.pitch
+
+    // each sample has 4 bytes: left and right channels, 16 bits each
+    samples := len(buffer)/4
+    for i := 0; i < samples; i++ {
+        // the frequency goes up and down a little bit, so it sounds like a siren
+        frequency := baseFrequency*(1+0.15*math.Sin(2*math.Pi*stream.wobblePhase))
+        value := int16(triangleWave(stream.phase)*0.6*math.MaxInt16)
+
+        putSample(buffer[i*4:], value)
+
+        // move forward in the waves. Whole numbers are dropped, so the phases stay small while the game runs for a long time
+        stream.phase = math.Mod(stream.phase+frequency/float64(sampleRate), 1)
+        stream.wobblePhase = math.Mod(stream.wobblePhase+sirenWobble/float64(sampleRate), 1)
+    }
+    return samples*4, nil
+}
+
+/*
+    Function: Close
+    The siren never ends, so there's nothing to close
+*/
+func (stream *SirenStream) Close() error {
+    return nil
+}
+
+/*
+    #######################################
+    ## Functions to create sound samples ##
+    #######################################
+*/
+
+/*
+    Function: triangleWave
+    Get the value of a triangle wave (-1 to 1) at the given position of the wave
+    Input: position in the wave, every whole number is a full wave
+*/
+func triangleWave(phase float64) float64 {
+    phase = phase-math.Floor(phase)
+    return 4*math.Abs(phase-0.5)-1
+}
+
+/*
+    Function: putSample
+    Write a 16 bit sample to both the left and the right channel (little endian)
+    Inputs: buffer to write and the sample
+*/
+func putSample(buffer []byte, value int16) {
+    buffer[0] = byte(value)
+    buffer[1] = byte(value >> 8)
+    buffer[2] = byte(value)
+    buffer[3] = byte(value >> 8)
+}
+
+/*
+    #############################################
+    ## Functions to connect sounds to the game ##
+    #############################################
+*/
+
+/*
+    Function: initSound
+    Create the sound backend and listen to the game events
+    If sound is turned off or the audio can not be used, the backend which does nothing is used
+    Input: whether sound is turned off
+*/
+func initSound(noSound bool) {
+    if !noSound {
+        backend, err := newEbitenAudioBackend()
+        if err != nil {
+            log.Println("sound is turned off:", err)
+        } else {
+            soundBackend = backend
+        }
+    }
+    applyVolume()
+
+    // play the effect of each game event. Frames which are run again after a rollback (see rollback.go) already played their effects,
+    // and the demo has no sounds (see demo.go)
+    addEventListener(func(event string) {
+        if isResimulating || isDemo {
+            return
+        }
+        if effect, ok := eventEffects[event]; ok {
+            soundBackend.playEffect(effect)
+        }
+    })
+}
+
+/*
+    Function: applyVolume
+    Set the volume of the sound backend from the sound settings
+*/
+func applyVolume() {
+    if isMuted {
+        soundBackend.setVolume(0)
+    } else {
+        soundBackend.setVolume(soundVolume)
+    }
+}
+
+/*
+    Function: toggleMute
+    Turn the sound on or off
+*/
+func toggleMute() {
+    isMuted = !isMuted
+    applyVolume()
+}
+
+/*
+    Function: updateSiren
+    Play the siren while PacMan is playing, with the pitch going up as the food left gets less
+    Input: whether PacMan is playing at the moment
+*/
+func updateSiren(playing bool) {
+    pitch := 0.0
+    if gameInfo.maxScore > 1 {
+        pitch = 1-float64(gameInfo.maxScore-gameInfo.score)/float64(gameInfo.maxScore-1)
+    }
+    soundBackend.setSiren(playing, pitch)
+}
@@ -0,0 +1,249 @@
+package main
+
+/*
+    This file contains the autopilot, which moves a PacMan when there's no player to move it
+    (Ex: a player of a networked game is disconnected, the demo on the start screen or the assist of a player).
+
+    The autopilot looks for the nearest safe food with a breadth first search over the maze and takes the first step of
+    the shortest path to it. A maze point is safe when PacMan gets there well before any enemy which is not frightened:
+    the distance of each maze point from the nearest enemy is found with another breadth first search, and compared
+    with the distance from PacMan (scaled by the speeds of PacMan and the enemies). Frightened enemies are also food
+    while there's time to catch them. If no food can be reached safely, PacMan runs where it has the most room (the
+    most maze points it gets to before any enemy), so it doesn't get caught in a dead end.
+*/
+import (
+    "math"
+)
+
+// Let's define the order of the directions the autopilot tries, with the step (column, row) of each direction
+var autopilotDirections = []byte{'U', 'R', 'D', 'L'}
+var autopilotSteps = map[byte][]int{'U': {0, -1}, 'R': {1, 0}, 'D': {0, 1}, 'L': {-1, 0}}
+
+// Let's define how many maze points PacMan should stay ahead of the enemies on the way to food
+var autopilotSafeDistance = 2.0
+
+// Let's define the frames a frightened enemy should have left to be chased by the autopilot
+var autopilotChaseTime = 60
+
+/*
+    Function: getAutopilotInput
+    Get the direction the autopilot wants to move a PacMan
+    Outputs U=UP, R=RIGHT, D=DOWN, L=LEFT or I=IDLE (when there's nowhere to go)
+    Input: reference to a PacMan game object
+*/
+func getAutopilotInput(pacman *Sprite) byte {
+    col, row := getMazePointFromPosition(pacman.x, pacman.y)
+    if !isValidPoint(col, row) {
+        return 'I'
+    }
+
+    // PacMan keeps going until it gets to the center of the maze point it's moving to. Otherwise it can turn back and forth
+    // between two maze points forever, because the plan changes when PacMan passes the middle of the two points
+    if isApproachingMazePoint(pacman, col, row) {
+        return pacman.direction
+    }
+
+    // the distance of each maze point from the nearest enemy, and how much faster the fastest enemy is than PacMan
+    enemyDistances := getEnemyDistances()
+    speedRatio := 0.0
+    for _, enemy := range enemies {
+        speedRatio = math.Max(speedRatio, enemy.speed/pacman.speed)
+    }
+
+    // let's go to the nearest food which PacMan can get to safely
+    goalStep := byte('I')
+    searchAutopilotPaths(col, row, enemyDistances, speedRatio, autopilotSafeDistance, func(point [2]int, firstStep byte) bool {
+        if isAutopilotGoal(point[0], point[1]) {
+            goalStep = firstStep
+            return true
+        }
+        return false
+    })
+    if goalStep != 'I' {
+        return goalStep
+    }
+
+    // there's no safe way to food, so let's run to where PacMan has the most room: for each first step, count the maze points
+    // PacMan gets to before any enemy. This keeps PacMan out of dead ends, where an enemy coming from behind would catch him
+    room := map[byte]int{}
+    searchAutopilotPaths(col, row, enemyDistances, speedRatio, 0, func(point [2]int, firstStep byte) bool {
+        room[firstStep]++
+        return false
+    })
+    bestStep := byte('I')
+    for _, direction := range autopilotDirections {
+        if room[direction] > room[bestStep] {
+            bestStep = direction
+        }
+    }
+    if bestStep != 'I' {
+        return bestStep
+    }
+
+    // PacMan can't get anywhere before the enemies, so let's just run away from the nearest enemy
+    return getEscapeDirection(col, row, enemyDistances)
+}
+
+/*
+    Function: searchAutopilotPaths
+    Breadth first search from PacMan over the maze points which PacMan gets to before the enemies which are not frightened.
+    A maze point is left out if an enemy gets there before PacMan, or less than the given margin (maze points) after PacMan
+    Each maze point found is given to the visit function with the first step taken from PacMan to get there. The search
+    stops when the visit function returns true
+    Inputs: maze point of PacMan, distances from the nearest enemy (see getEnemyDistances), how much faster the enemies are,
+            the margin and the visit function
+*/
+func searchAutopilotPaths(col int, row int, enemyDistances map[[2]int]int, speedRatio float64, margin float64, visit func(point [2]int, firstStep byte) bool) {
+    // each maze point remembers the first step taken from PacMan to get there
+    firstSteps := map[[2]int]byte{{col, row}: 'I'}
+    distances := map[[2]int]int{{col, row}: 0}
+    queue := [][2]int{{col, row}}
+    for len(queue) > 0 {
+        point := queue[0]
+        queue = queue[1:]
+
+        for _, direction := range autopilotDirections {
+            if !isMovableDirection(point[0], point[1], direction) {
+                continue
+            }
+            next := [2]int{point[0]+autopilotSteps[direction][0], point[1]+autopilotSteps[direction][1]}
+            if _, visited := firstSteps[next]; visited {
+                continue
+            }
+
+            // the maze point is not safe if an enemy can get there before PacMan, or not long after PacMan
+            distances[next] = distances[point]+1
+            if enemyDistance, ok := enemyDistances[next]; ok && float64(enemyDistance) <= float64(distances[next])*speedRatio+margin {
+                continue
+            }
+
+            // the first step is the direction from PacMan, and it's passed on to all the points found after it
+            if point == [2]int{col, row} {
+                firstSteps[next] = direction
+            } else {
+                firstSteps[next] = firstSteps[point]
+            }
+            if visit(next, firstSteps[next]) {
+                return
+            }
+            queue = append(queue, next)
+        }
+    }
+}
+
+/*
+    Function: isApproachingMazePoint
+    Check if a PacMan is moving to the center of the given maze point, but it's not there yet
+    Inputs: reference to a PacMan game object and the maze point it's on
+*/
+func isApproachingMazePoint(pacman *Sprite, col int, row int) bool {
+    alignedX, alignedY := getPositionFromMazePoint(col, row)
+    switch pacman.direction {
+    case 'U':
+        return pacman.y > alignedY
+    case 'R':
+        return pacman.x < alignedX
+    case 'D':
+        return pacman.y < alignedY
+    case 'L':
+        return pacman.x > alignedX
+    }
+    return false
+}
+
+/*
+    Function: getAutopilotEnemyDistance
+    Get the distance of a maze point from the nearest enemy which is not frightened (infinite if no enemy can get there)
+    Inputs: maze point and the distances from the nearest enemy (see getEnemyDistances)
+*/
+func getAutopilotEnemyDistance(col int, row int, enemyDistances map[[2]int]int) float64 {
+    if enemyDistance, ok := enemyDistances[[2]int{col, row}]; ok {
+        return float64(enemyDistance)
+    }
+    return math.Inf(1)
+}
+
+/*
+    Function: isAutopilotGoal
+    Check if there's something for PacMan to eat at the given maze point (food, power pellet, the fruit or a frightened enemy)
+    Input: maze point
+*/
+func isAutopilotGoal(col int, row int) bool {
+    if gameInfo.maze[row][col] == '.' || gameInfo.maze[row][col] == 'o' {
+        return true
+    }
+    colFruit, rowFruit := getMazePointFromPosition(fruit.x, fruit.y)
+    if fruit.visibility && col == colFruit && row == rowFruit {
+        return true
+    }
+
+    // a frightened enemy is worth chasing while there's time to catch it
+    if gameInfo.frightenedTimer > autopilotChaseTime {
+        for _, enemy := range enemies {
+            colEnemy, rowEnemy := getMazePointFromPosition(enemy.x, enemy.y)
+            if enemy.isFrightened && col == colEnemy && row == rowEnemy {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+/*
+    Function: getEnemyDistances
+    Get the distance (maze points along the paths) of each maze point from the nearest enemy which is not frightened
+    Maze points which no enemy can reach are not in the map
+*/
+func getEnemyDistances() map[[2]int]int {
+    distances := map[[2]int]int{}
+    queue := [][2]int{}
+    for _, enemy := range enemies {
+        if enemy.isFrightened {
+            continue
+        }
+        col, row := getMazePointFromPosition(enemy.x, enemy.y)
+        if _, ok := distances[[2]int{col, row}]; !ok && isValidPoint(col, row) {
+            distances[[2]int{col, row}] = 0
+            queue = append(queue, [2]int{col, row})
+        }
+    }
+
+    // breadth first search from all the enemies at once
+    for len(queue) > 0 {
+        point := queue[0]
+        queue = queue[1:]
+        for _, direction := range autopilotDirections {
+            if !isMovableDirection(point[0], point[1], direction) {
+                continue
+            }
+            next := [2]int{point[0]+autopilotSteps[direction][0], point[1]+autopilotSteps[direction][1]}
+            if _, visited := distances[next]; !visited {
+                distances[next] = distances[point]+1
+                queue = append(queue, next)
+            }
+        }
+    }
+    return distances
+}
+
+/*
+    Function: getEscapeDirection
+    Get the movable direction which takes PacMan furthest from the nearest enemy which is not frightened
+    Inputs: maze point of PacMan and the distances from the nearest enemy (see getEnemyDistances)
+*/
+func getEscapeDirection(col int, row int, enemyDistances map[[2]int]int) byte {
+    bestDirection := byte('I')
+    bestDistance := -1.0
+    for _, direction := range autopilotDirections {
+        if !isMovableDirection(col, row, direction) {
+            continue
+        }
+        // a maze point which no enemy can reach is the safest
+        distance := getAutopilotEnemyDistance(col+autopilotSteps[direction][0], row+autopilotSteps[direction][1], enemyDistances)
+        if distance > bestDistance {
+            bestDirection = direction
+            bestDistance = distance
+        }
+    }
+    return bestDirection
+}
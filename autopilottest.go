@@ -0,0 +1,68 @@
+package main
+
+/*
+    This file contains the win rate report of the autopilot (-autopilottest flag).
+
+    The autopilot plays a number of one player games without opening the game window, each game with its own seed of
+    the random numbers (1, 2, 3, ...), so the report is the same each time and changes of the autopilot can be compared.
+    A game is won when all the levels are completed. The report has the result of each game, the win rate, the levels
+    completed and the average points.
+*/
+import (
+    "fmt"
+)
+
+// Let's define the most frames a game of the report can take (10 minutes), so a stuck autopilot doesn't run forever
+var autopilotTestFrames = 60*60*10
+
+/*
+    Function: runAutopilotTest
+    Let the autopilot play the given number of games and print the report
+    Input: number of games
+*/
+func runAutopilotTest(games int) {
+    isDemo = true
+    defer func() {
+        isDemo = false
+    }()
+
+    wins := 0
+    totalPoints := 0
+    levelsCompleted := map[int]int{}
+    for game := 1; game <= games; game++ {
+        seedRandom(int64(game))
+        newGame()
+        startPlayers(1)
+        isNewGame = false
+
+        result := "out of time"
+        frame := 0
+        for ; frame < autopilotTestFrames; frame++ {
+            if gameInfo.isGameOver {
+                result = "lost"
+                break
+            }
+            if gameInfo.isLevelComplete {
+                levelsCompleted[gameInfo.level]++
+                if gameInfo.level+1 > len(LEVELS) {
+                    result = "won"
+                    wins++
+                    break
+                }
+                initLevel(gameInfo.level+1)
+                gameInfo.isStarted = true
+            }
+            updateGame()
+        }
+
+        points := gameInfo.stats[0].points
+        totalPoints = totalPoints+points
+        fmt.Printf("game %d: %s on level %d with %d points after %d frames\n", game, result, gameInfo.level, points, frame)
+    }
+
+    fmt.Printf("win rate: %d/%d (%.1f%%)\n", wins, games, float64(wins)*100/float64(games))
+    for level := 1; level <= len(LEVELS); level++ {
+        fmt.Printf("level %d completed in %d games\n", level, levelsCompleted[level])
+    }
+    fmt.Printf("average points: %.0f\n", float64(totalPoints)/float64(games))
+}
@@ -0,0 +1,411 @@
+package main
+
+/*
+    This file contains the bot mode (-bot flag), where an external program plays PacMan of player 1.
+
+    The game starts the program and talks to it over its stdin and stdout. On each frame (tick) the game writes an
+    observation to the stdin of the bot as a single line of JSON (see BotObservation), and waits for the bot to write
+    its move to its stdout as a single line:
+    - a word: U, R, D, L (or up, right, down, left), I (or idle) to stop, or S (or space) to go on from a screen
+    - or JSON with the tick it answers: {"tick": 12, "move": "L"}. Late answers of older ticks are skipped
+    Only the JSON answers are matched to their tick. A word is taken as the answer of the tick the game is waiting for,
+    so a word which comes after the game stopped waiting is only used as the last move: before each observation is
+    written, the answers which came late are read and only the latest move of them is kept.
+    If the bot doesn't answer in time (-bottimeout) or the answer can't be read, the last move is kept and it's logged.
+    The observations are written in another goroutine, so a bot which doesn't read its stdin doesn't stop the game:
+    the observation which is still waiting to be written is replaced by the newer one, and it's counted as a timeout.
+    If the bot exits, player 1 plays with the keys again. Whatever the bot writes to its stderr is shown in the log.
+*/
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "os/exec"
+    "strings"
+    "time"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure which keeps the external program which plays the game
+type Bot struct {
+    cmd *exec.Cmd // holds the running program
+    stdin io.WriteCloser // holds the stdin of the program, where the observations are written
+    observations chan []byte // holds the observation waiting to be written to the program, written in another goroutine
+    writeErrors chan error // gets the error of writing to the program
+    replies chan string // holds the lines written by the program, read in another goroutine
+    exited chan error // gets the result of the program when it exits
+    tick int // holds the tick of the last observation
+    move byte // holds the last move of the bot: U, R, D, L or I
+    isSpace bool // holds whether the bot pressed space in this tick
+    isStopped bool // holds whether the bot exited or can't be used anymore
+    timeouts int // holds the number of ticks the bot didn't answer in time
+    malformed int // holds the number of answers which couldn't be read
+    lastLog time.Time // holds the time of the last logged problem, so problems are not logged on every frame
+}
+
+// Structure which is written to the bot on each tick. Fields are exported, so encoding/json can write them
+type BotObservation struct {
+    Tick int `json:"tick"`
+    Screen string `json:"screen"` // start, ready, playing, levelComplete or gameOver
+    Level int `json:"level"`
+    Points int `json:"points"` // points of player 1
+    Lives int `json:"lives"` // lives of player 1
+    FoodLeft int `json:"foodLeft"`
+    FrightenedTimer int `json:"frightenedTimer"` // frames left until the enemies are not frightened anymore
+    Maze []string `json:"maze"` // rows of the maze: 0 is a wall, . is food, o is a power pellet, anything else is a path
+    Pacman BotSprite `json:"pacman"` // PacMan of player 1
+    Enemies []BotSprite `json:"enemies"`
+    Fruit BotSprite `json:"fruit"`
+}
+
+// Structure which keeps a game object in an observation
+type BotSprite struct {
+    Col int `json:"col"` // column of the maze point the game object is on
+    Row int `json:"row"` // row of the maze point the game object is on
+    X float64 `json:"x"` // position in the maze in pixels, blockSize pixels for each maze point
+    Y float64 `json:"y"`
+    Direction string `json:"direction"` // U, R, D, L or I
+    Visible bool `json:"visible"`
+    Frightened bool `json:"frightened"` // whether PacMan can eat the enemy
+}
+
+// Structure which is read from a JSON answer of the bot
+type BotReply struct {
+    Tick *int `json:"tick"`
+    Move string `json:"move"`
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define how long the game waits for the bot to answer a tick
+var botTimeout = 15*time.Millisecond
+
+// Let's define how often (at most) problems of the bot are logged
+var botLogInterval = time.Second
+
+// Variable to hold the bot which plays player 1 (nil if there's no bot)
+var gameBot *Bot
+
+/*
+    Function: startBot
+    Start the program of the bot
+    Input: command to run the program (Ex: python3 bot.py)
+*/
+func startBot(command string) (*Bot, error) {
+    fields := strings.Fields(command)
+    if len(fields) == 0 {
+        return nil, errors.New("the bot command is empty")
+    }
+
+    cmd := exec.Command(fields[0], fields[1:]...)
+    cmd.Stderr = os.Stderr
+    stdin, err := cmd.StdinPipe()
+    if err != nil {
+        return nil, err
+    }
+    stdout, err := cmd.StdoutPipe()
+    if err != nil {
+        return nil, err
+    }
+    if err := cmd.Start(); err != nil {
+        return nil, err
+    }
+
+    bot := &Bot{
+        cmd: cmd,
+        stdin: stdin,
+        observations: make(chan []byte, 1),
+        writeErrors: make(chan error, 1),
+        replies: make(chan string, 16),
+        exited: make(chan error, 1),
+        move: 'I',
+    }
+    go bot.readReplies(stdout)
+    go bot.writeObservations()
+    return bot, nil
+}
+
+/*
+    Function: writeObservations
+    Write the observations to the bot until the bot is stopped or it can't be written to anymore
+*/
+func (bot *Bot) writeObservations() {
+    for observation := range bot.observations {
+        if _, err := bot.stdin.Write(observation); err != nil {
+            bot.writeErrors <- err
+            return
+        }
+    }
+}
+
+/*
+    Function: readReplies
+    Read the lines written by the bot until it exits
+    Input: stdout of the bot
+*/
+func (bot *Bot) readReplies(stdout io.Reader) {
+    scanner := bufio.NewScanner(stdout)
+    for scanner.Scan() {
+        bot.replies <- scanner.Text()
+    }
+    bot.exited <- bot.cmd.Wait()
+    close(bot.replies)
+}
+
+/*
+    Function: getExitReason
+    Get the reason the bot can't be used anymore. If the bot exited, it's the result of the bot
+    Input: error of writing to the bot (nil if the bot closed its stdout)
+*/
+func (bot *Bot) getExitReason(writeErr error) string {
+    select {
+    case err := <-bot.exited:
+        if err != nil {
+            return "the bot exited: "+err.Error()
+        }
+        return "the bot exited"
+    case <-time.After(botTimeout):
+        if writeErr == nil {
+            return "the bot closed its stdout"
+        }
+        return "can't write to the bot: "+writeErr.Error()
+    }
+}
+
+/*
+    Function: update
+    Write the observation of this tick to the bot and wait for its move. It's called on each frame before the game runs
+*/
+func (bot *Bot) update() {
+    if bot.isStopped {
+        return
+    }
+    bot.tick++
+    bot.isSpace = false
+
+    select {
+    case err := <-bot.writeErrors:
+        bot.stop(bot.getExitReason(err))
+        return
+    default:
+    }
+
+    // the answers which came late are not the answer of this tick, let's keep only the latest move of them
+    if !bot.readLateReplies() {
+        bot.stop(bot.getExitReason(nil))
+        return
+    }
+
+    observation, err := json.Marshal(getBotObservation(bot.tick))
+    if err != nil {
+        bot.stop(err.Error())
+        return
+    }
+    select {
+    case bot.observations <- append(observation, '\n'):
+    default:
+        // the bot didn't read the previous observation yet. Let's replace it with this one, the bot only needs the
+        // newest one, and keep the last move instead of waiting for an answer
+        select {
+        case <-bot.observations:
+        default:
+        }
+        bot.observations <- append(observation, '\n')
+        bot.timeouts++
+        bot.logProblem(fmt.Sprintf("the bot didn't read tick %d, the last move is kept (%d timeouts so far)", bot.tick-1, bot.timeouts))
+        return
+    }
+
+    timer := time.NewTimer(botTimeout)
+    defer timer.Stop()
+    for {
+        select {
+        case line, ok := <-bot.replies:
+            if !ok {
+                bot.stop(bot.getExitReason(nil))
+                return
+            }
+            move, tick, err := parseBotReply(line)
+            if err != nil {
+                bot.malformed++
+                bot.logProblem(fmt.Sprintf("the answer %q of the bot is not used: %v (%d so far)", line, err, bot.malformed))
+                return
+            }
+            if tick >= 0 && tick < bot.tick {
+                // a late answer of an earlier tick, let's wait for the answer of this tick
+                continue
+            }
+            if move == 'S' {
+                bot.isSpace = true
+            } else {
+                bot.move = move
+            }
+            return
+        case <-timer.C:
+            bot.timeouts++
+            bot.logProblem(fmt.Sprintf("the bot didn't answer tick %d in %v, the last move is kept (%d timeouts so far)", bot.tick, botTimeout, bot.timeouts))
+            return
+        }
+    }
+}
+
+/*
+    Function: readLateReplies
+    Read the answers the bot wrote after the game stopped waiting, without waiting for more. The latest move is kept
+    Output: false if the bot closed its stdout
+*/
+func (bot *Bot) readLateReplies() bool {
+    for {
+        select {
+        case line, ok := <-bot.replies:
+            if !ok {
+                return false
+            }
+            // space is pressed only in the tick of the answer, so a late space is not used
+            move, _, err := parseBotReply(line)
+            if err == nil && move != 'S' {
+                bot.move = move
+            }
+        default:
+            return true
+        }
+    }
+}
+
+/*
+    Function: parseBotReply
+    Read the move from an answer of the bot
+    Input: line written by the bot
+    Outputs the move (U, R, D, L, I or S) and the tick of the answer (-1 if the answer doesn't have a tick)
+*/
+func parseBotReply(line string) (byte, int, error) {
+    line = strings.TrimSpace(line)
+    tick := -1
+    if strings.HasPrefix(line, "{") {
+        reply := BotReply{}
+        if err := json.Unmarshal([]byte(line), &reply); err != nil {
+            return 0, 0, err
+        }
+        if reply.Tick != nil {
+            tick = *reply.Tick
+        }
+        line = reply.Move
+    }
+
+    switch strings.ToUpper(strings.TrimSpace(line)) {
+    case "U", "UP":
+        return 'U', tick, nil
+    case "R", "RIGHT":
+        return 'R', tick, nil
+    case "D", "DOWN":
+        return 'D', tick, nil
+    case "L", "LEFT":
+        return 'L', tick, nil
+    case "I", "IDLE":
+        return 'I', tick, nil
+    case "S", "SPACE":
+        return 'S', tick, nil
+    }
+    return 0, 0, errors.New("unknown move")
+}
+
+/*
+    Function: logProblem
+    Log a problem of the bot, at most once every botLogInterval so the log is not flooded on every frame
+    Input: message
+*/
+func (bot *Bot) logProblem(message string) {
+    if time.Since(bot.lastLog) < botLogInterval {
+        return
+    }
+    bot.lastLog = time.Now()
+    log.Println(message)
+}
+
+/*
+    Function: stop
+    Stop using the bot. Player 1 plays with the keys again
+    Input: reason to log
+*/
+func (bot *Bot) stop(reason string) {
+    log.Println(reason+", player 1 plays with the keys")
+    bot.isStopped = true
+    bot.move = 'I'
+    close(bot.observations)
+    bot.stdin.Close()
+}
+
+/*
+    Function: getBotObservation
+    Get what the bot sees in a tick
+    Input: tick
+*/
+func getBotObservation(tick int) BotObservation {
+    screen := "playing"
+    switch {
+    case !gameInfo.isStarted:
+        screen = "start"
+    case gameInfo.isGameOver:
+        screen = "gameOver"
+    case gameInfo.isLevelComplete:
+        screen = "levelComplete"
+    case gameInfo.freezeTimer > 0:
+        screen = "ready"
+    }
+
+    observation := BotObservation{
+        Tick: tick,
+        Screen: screen,
+        Level: gameInfo.level,
+        Points: gameInfo.stats[0].points,
+        Lives: gameInfo.stats[0].lives,
+        FoodLeft: gameInfo.maxScore-gameInfo.score,
+        FrightenedTimer: gameInfo.frightenedTimer,
+        Maze: gameInfo.maze,
+        Enemies: []BotSprite{},
+        Fruit: getBotSprite(&fruit),
+    }
+    for _, pacman := range pacmen {
+        if pacman.player == 0 {
+            observation.Pacman = getBotSprite(pacman)
+        }
+    }
+    for _, enemy := range enemies {
+        observation.Enemies = append(observation.Enemies, getBotSprite(enemy))
+    }
+    return observation
+}
+
+/*
+    Function: getBotSprite
+    Get a game object as the bot sees it
+    Input: game object
+*/
+func getBotSprite(sprite *Sprite) BotSprite {
+    col, row := getMazePointFromPosition(sprite.x, sprite.y)
+    direction := "I"
+    if sprite.direction != 0 {
+        direction = string(sprite.direction)
+    }
+    return BotSprite{
+        Col: col,
+        Row: row,
+        X: sprite.x,
+        Y: sprite.y,
+        Direction: direction,
+        Visible: sprite.visibility,
+        Frightened: sprite.isFrightened,
+    }
+}
@@ -0,0 +1,64 @@
+package main
+
+/*
+    This file contains the tests of the bot mode (see bot.go).
+*/
+import (
+    "testing"
+    "time"
+)
+
+/*
+    Function: newTestBot
+    Get a bot without a program. The test writes the answers of the bot to its replies
+*/
+func newTestBot() *Bot {
+    return &Bot{
+        observations: make(chan []byte, 1),
+        writeErrors: make(chan error, 1),
+        replies: make(chan string, 16),
+        exited: make(chan error, 1),
+        move: 'I',
+    }
+}
+
+func TestBotLateAnswerIsNotNextAnswer(t *testing.T) {
+    // a word which comes after the game stopped waiting is kept as the last move, not used as the answer of the next tick
+    timeout := botTimeout
+    botTimeout = 5*time.Millisecond
+    defer func() { botTimeout = timeout }()
+
+    setScreenSize()
+    newGame()
+    bot := newTestBot()
+    bot.replies <- "U"
+    bot.replies <- "L"
+    bot.update()
+    if bot.move != 'L' {
+        t.Errorf("the bot moves %c, expected the latest late answer L", bot.move)
+    }
+    if bot.timeouts != 1 {
+        t.Errorf("the bot has %d timeouts, the late answers shouldn't answer tick %d", bot.timeouts, bot.tick)
+    }
+}
+
+func TestBotAnswerOfTick(t *testing.T) {
+    // a JSON answer of an older tick is skipped while the game waits for the answer of this tick
+    timeout := botTimeout
+    botTimeout = time.Second
+    defer func() { botTimeout = timeout }()
+
+    setScreenSize()
+    newGame()
+    bot := newTestBot()
+    bot.tick = 1
+    go func() {
+        <-bot.observations
+        bot.replies <- `{"tick": 1, "move": "U"}`
+        bot.replies <- `{"tick": 2, "move": "R"}`
+    }()
+    bot.update()
+    if bot.move != 'R' || bot.timeouts != 0 {
+        t.Errorf("the bot moves %c with %d timeouts, expected the answer R of tick 2", bot.move, bot.timeouts)
+    }
+}
@@ -0,0 +1,215 @@
+package main
+
+/*
+    This file contains the co-op mode.
+
+    In the co-op mode 2 to 4 PacMen are in the same maze at the same time. They share the food, but each player has
+    their own points and lives. Each player moves their PacMan with their own keys or a gamepad.
+
+    Player 1: Arrow keys
+    Player 2: W A S D
+    Player 3: I J K L
+    Player 4: Number pad 8 4 5 6
+    Gamepads are given to the players in the order they are connected (first gamepad is for player 1)
+
+    Mazes can have the start of each player with 1 to 4. Players without a start in the maze start where player 1 starts.
+*/
+import (
+    "image/color"
+    "math"
+)
+
+// Let's define the keys of each player, in the order UP, RIGHT, DOWN, LEFT
+var playerKeys = [][]Key{
+    {KeyUp, KeyRight, KeyDown, KeyLeft},
+    {KeyW, KeyD, KeyS, KeyA},
+    {KeyI, KeyL, KeyK, KeyJ},
+    {KeyKP8, KeyKP6, KeyKP5, KeyKP4},
+}
+
+// Let's define the color of each player. The first player is not painted, so PacMan keeps the colors of the image
+var playerColors = []color.RGBA{
+    {},
+    {R: 120, G: 200, B: 255, A: 255},
+    {R: 255, G: 140, B: 200, A: 255},
+    {R: 140, G: 255, B: 140, A: 255},
+}
+
+// Let's have a variable to define how far a gamepad stick has to be pushed to move PacMan (0 to 1)
+var gamepadDeadZone = 0.5
+
+// Variable to hold the number of PacMen in the co-op mode (set with the -coop flag)
+var coopPlayers = 2
+
+/*
+    Function: startCoop
+    Start the co-op mode with the given number of PacMen
+    Input: number of PacMen (2 to 4)
+*/
+func startCoop(count int) {
+    count = int(math.Max(2, math.Min(float64(count), float64(len(playerKeys)))))
+
+    // let's give each player their own lives and points, and put all the PacMen in the maze
+    gameInfo.stats = []PlayerStats{}
+    for i := 0; i < count; i++ {
+        gameInfo.stats = append(gameInfo.stats, newPlayerStats())
+    }
+    locateGameObjects()
+
+    // all the PacMen play at the same time, so it's a single turn
+    startPlayers(1)
+}
+
+/*
+    Function: getPlayerInput
+    Get the direction a player wants to move, from the keys or the gamepad of the player
+    Outputs U=UP, R=RIGHT, D=DOWN, L=LEFT or I=IDLE (when no direction is pressed)
+    Input: player (position in gameInfo.stats)
+*/
+func getPlayerInput(player int) byte {
+    // in a rollback game, the direction comes with the inputs of the frame being run (see rollback.go)
+    if rollbackSession != nil {
+        return rollbackSession.getInput(player)
+    }
+
+    // player 1 can be played by an external program (see bot.go)
+    if player == 0 && gameBot != nil && !gameBot.isStopped {
+        return gameBot.move
+    }
+
+    // player 1 can play in the terminal, PacMan keeps going in the last direction pressed (see tui.go)
+    if player == 0 && isTUI {
+        return tuiMove
+    }
+
+    // a player who joined a networked game sends their direction over the network (see net.go)
+    if isRemotePlayer(player) {
+        return getRemoteInput(player)
+    }
+    return getKeyInput(player)
+}
+
+/*
+    Function: getKeyInput
+    Get the direction a player presses on their keys or their gamepad
+    Outputs U=UP, R=RIGHT, D=DOWN, L=LEFT or I=IDLE (when no direction is pressed)
+    Input: player (position in gameInfo.stats)
+*/
+func getKeyInput(player int) byte {
+    up, right, down, left := false, false, false, false
+
+    if player < len(playerKeys) {
+        keys := playerKeys[player]
+        up = isKeyPressed(keys[0])
+        right = isKeyPressed(keys[1])
+        down = isKeyPressed(keys[2])
+        left = isKeyPressed(keys[3])
+    }
+
+    // the gamepad of the player, if it's connected. Axis 0 is left/right and axis 1 is up/down
+    gamepads := gamepadIDs()
+    if player < len(gamepads) && gamepadAxisNum(gamepads[player]) >= 2 {
+        axisX := gamepadAxis(gamepads[player], 0)
+        axisY := gamepadAxis(gamepads[player], 1)
+        up = up || axisY < -gamepadDeadZone
+        down = down || axisY > gamepadDeadZone
+        left = left || axisX < -gamepadDeadZone
+        right = right || axisX > gamepadDeadZone
+    }
+
+    // only a single direction is used at a time, in this order
+    switch {
+    case up:
+        return 'U'
+    case down:
+        return 'D'
+    case left:
+        return 'L'
+    case right:
+        return 'R'
+    }
+    return 'I'
+}
+
+/*
+    Function: getAlivePacmanCount
+    Get the number of PacMen which still have lives
+*/
+func getAlivePacmanCount() int {
+    count := 0
+    for _, pacman := range pacmen {
+        if isPacmanAlive(pacman) {
+            count++
+        }
+    }
+    return count
+}
+
+/*
+    Function: getEnemyTarget
+    Get the PacMan an enemy chases, which is the nearest PacMan that still has lives. Returns nil if there's no such PacMan
+    Input: reference to a enemy game object
+*/
+func getEnemyTarget(enemy *Sprite) *Sprite {
+    var target *Sprite
+    targetDistance := math.Inf(1)
+    for _, pacman := range pacmen {
+        if !isPacmanAlive(pacman) {
+            continue
+        }
+        distance := math.Abs(pacman.x-enemy.x)+math.Abs(pacman.y-enemy.y)
+        if distance < targetDistance {
+            target = pacman
+            targetDistance = distance
+        }
+    }
+    return target
+}
+
+/*
+    Function: isNearPacman
+    Check if a position is near any PacMan that still has lives
+    Inputs: screen position (x, y) and the distance which counts as near
+*/
+func isNearPacman(x float64, y float64, distance float64) bool {
+    for _, pacman := range pacmen {
+        if isPacmanAlive(pacman) && math.Abs(x-pacman.x)+math.Abs(y-pacman.y) < distance {
+            return true
+        }
+    }
+    return false
+}
+
+/*
+    Function: getChaseDirection
+    Get the movable direction from the given maze point which gets closest to a target maze point
+    Turning back is used only if there's no other way (dead end)
+    Outputs a byte indicating direction: U=UP, R=RIGHT , D=DOWN, L=LEFT
+    Inputs: maze point, current direction and the target maze point
+*/
+func getChaseDirection(col int, row int, currentDirection byte, targetCol int, targetRow int) byte {
+    opposite := map[byte]byte{'U': 'D', 'D': 'U', 'L': 'R', 'R': 'L'}
+    steps := map[byte][]int{'U': {0, -1}, 'R': {1, 0}, 'D': {0, 1}, 'L': {-1, 0}}
+
+    bestDirection := byte(0)
+    bestDistance := math.Inf(1)
+    for _, direction := range []byte{'U', 'L', 'D', 'R'} {
+        nextCol := col+steps[direction][0]
+        nextRow := row+steps[direction][1]
+        if !isValidPoint(nextCol, nextRow) || gameInfo.maze[nextRow][nextCol] == '0' || direction == opposite[currentDirection] {
+            continue
+        }
+
+        distance := math.Hypot(float64(targetCol-nextCol), float64(targetRow-nextRow))
+        if distance < bestDistance {
+            bestDirection = direction
+            bestDistance = distance
+        }
+    }
+
+    // it's a dead end, so the only way is to turn back (or any movable direction)
+    if bestDirection == 0 {
+        return getMovableDirection(col, row, currentDirection)
+    }
+    return bestDirection
+}
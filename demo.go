@@ -0,0 +1,121 @@
+package main
+
+/*
+    This file contains the demo (attract mode) and the assist of player 1.
+
+    When nobody presses a key on the start screen for a while, the autopilot (see autopilot.go) plays a game as a demo,
+    without sounds and without counting for the high score. Pressing any key, or the end of the game, stops the demo
+    and brings back the start screen.
+
+    The assist is turned on and off with Tab while playing. When it's on, the autopilot moves PacMan of player 1 while
+    no direction key is pressed. Pressing a direction key moves PacMan as usual.
+*/
+import (
+)
+
+// Let's define how long (frames) the start screen waits for a key before the demo starts
+var demoIdleTime = 600
+
+// Variable to know if the autopilot is playing a demo
+var isDemo = false
+
+// Variable to hold the number of frames nobody pressed a key on the start screen
+var idleTimer = 0
+
+// Variable to know if the assist of player 1 is on
+var isAssistOn = false
+
+/*
+    Function: updateDemo
+    Start the demo when the start screen has waited long enough, and stop it when a key is pressed or the game ends
+    It's called on each frame by the update function of a game played on this computer
+*/
+func updateDemo() {
+    if isDemo {
+        if isAnyKeyJustPressed() || gameInfo.isGameOver || gameInfo.isLevelComplete {
+            stopDemo()
+        }
+        return
+    }
+
+    // networked games, headless games and bots are waiting for a real game, so there's no demo for them
+    isLocal := netHost == nil && netClient == nil && lobby == nil && rollbackSession == nil && gameBot == nil && !isHeadless
+    if !isLocal || !isNewGame || gameInfo.isStarted || isAnyKeyPressed() {
+        idleTimer = 0
+        return
+    }
+    idleTimer++
+    if idleTimer >= demoIdleTime {
+        startDemo()
+    }
+}
+
+/*
+    Function: startDemo
+    Start a one player game played by the autopilot
+*/
+func startDemo() {
+    isDemo = true
+    idleTimer = 0
+    startPlayers(1)
+    isNewGame = false
+}
+
+/*
+    Function: stopDemo
+    Stop the demo and go back to the start screen
+*/
+func stopDemo() {
+    isDemo = false
+    newGame()
+}
+
+/*
+    Function: isAnyKeyPressed
+    Check if any key is pressed
+*/
+func isAnyKeyPressed() bool {
+    for key := Key(0); key <= KeyMax; key++ {
+        if isKeyPressed(key) {
+            return true
+        }
+    }
+    return false
+}
+
+/*
+    Function: isAnyKeyJustPressed
+    Check if any key is pressed now, but it wasn't pressed on the previous frame
+*/
+func isAnyKeyJustPressed() bool {
+    for key := Key(0); key <= KeyMax; key++ {
+        if isKeyJustPressed(key) {
+            return true
+        }
+    }
+    return false
+}
+
+/*
+    Function: isAssisted
+    Check if the autopilot helps a player. Only player 1 has the assist, and not in a rollback game
+    (the other game doesn't know about the assist, so the games would be out of sync)
+    Input: player
+*/
+func isAssisted(player int) bool {
+    return isAssistOn && player == 0 && rollbackSession == nil
+}
+
+/*
+    Function: drawDemo
+    Let the players know the demo is playing, or the assist is on
+    Input: screen
+*/
+func drawDemo(screen *Image) {
+    clr := getTextColor()
+    if isDemo {
+        drawTextCentered(screen, "DEMO - Press any key", screenSizeX/2, playfieldY+blockSize, clr)
+    } else if isAssistOn && gameInfo.isStarted {
+        drawText(screen, "ASSIST", playfieldX+blockSize, playfieldY+2, clr)
+    }
+}
@@ -0,0 +1,292 @@
+package main
+
+/*
+    This file contains the adaptive difficulty (-adaptive flag), which changes the enemies to how well the player plays.
+
+    The difficulty is a number from -1 (easiest) to 1 (hardest). 0 is the level as it's made (the settings of LEVELS and
+    the maze). The difficulty changes the enemy speed, how often enemies chase PacMan, how long enemies stay frightened
+    and the number of enemies, between the easiest and the hardest bounds of each setting.
+    - from the second life PacMan loses on a level, each lost life makes the game a step easier
+    - a level cleared without losing a life, within the par time of the maze (or adaptiveConfig.ClearTime) and with
+      only a few near misses (an enemy came close to PacMan and went away) makes the game a step harder
+    The enemy speed and chasing change from the next life, the number of enemies from the next level. Every change is
+    logged with its reason.
+
+    The bounds can be changed with -adaptiveconfig difficulty.json (which also turns on the adaptive difficulty), a JSON
+    object with the settings to change. Speed, chasing and frightened time are multiplied, enemies are added. Ex:
+    {"enemySpeed": {"easiest": 0.5, "hardest": 1.5}, "enemies": {"easiest": -3, "hardest": 2}, "step": 0.5}
+
+    Only the one player mode is adapted, so the players of the other modes play the same game.
+*/
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "log"
+    "math"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure which matches the adaptive difficulty config file. Fields are exported so the json package can fill them
+type DifficultyConfig struct {
+    EnemySpeed DifficultyBounds `json:"enemySpeed"` // the enemy speed is multiplied by this
+    EnemyAggression DifficultyBounds `json:"enemyAggression"` // how often enemies chase PacMan is multiplied by this (up to always)
+    FrightenedTime DifficultyBounds `json:"frightenedTime"` // the frightened time is multiplied by this
+    Enemies DifficultyBounds `json:"enemies"` // this many enemies are added (or removed, at least 1 enemy stays)
+    Step float64 `json:"step"` // how much the difficulty changes at once
+    DeathsToEase int `json:"deathsToEase"` // lives lost on a level before the game gets easier
+    NearMisses int `json:"nearMisses"` // near misses on a level which keep the game from getting harder
+    ClearTime int `json:"clearTime"` // seconds to clear a level in to make the game harder, when the maze has no par time
+}
+
+// Structure which keeps the value of a setting at the easiest and at the hardest difficulty
+type DifficultyBounds struct {
+    Easiest float64 `json:"easiest"`
+    Hardest float64 `json:"hardest"`
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the default bounds and rules of the adaptive difficulty
+var adaptiveConfig = DifficultyConfig{
+    EnemySpeed: DifficultyBounds{Easiest: 0.6, Hardest: 1.3},
+    EnemyAggression: DifficultyBounds{Easiest: 0.3, Hardest: 1.5},
+    FrightenedTime: DifficultyBounds{Easiest: 1.5, Hardest: 0.6},
+    Enemies: DifficultyBounds{Easiest: -2, Hardest: 1},
+    Step: 0.25,
+    DeathsToEase: 2,
+    NearMisses: 6,
+    ClearTime: 150,
+}
+
+// Let's define how close (pixels) an enemy comes to PacMan for a near miss, and how far it goes away after it
+var nearMissDistance = 1.5*float64(blockSize)
+var nearMissAwayDistance = 3*float64(blockSize)
+
+// Variable to know if the adaptive difficulty is turned on
+var isAdaptive = false
+
+// Variable to hold the difficulty, from -1 (easiest) to 1 (hardest)
+var difficulty = 0.0
+
+// Variables to hold how the player does on the current level: lives lost, frames played and near misses
+var levelDeaths = 0
+var levelFrames = 0
+var levelNearMisses = 0
+
+// Variable to hold the enemies which are near PacMan now. A near miss is counted when one of them goes away
+var nearEnemies = map[*Sprite]bool{}
+
+/*
+    ##########################################
+    ## Functions of the adaptive difficulty ##
+    ##########################################
+*/
+
+/*
+    Function: loadDifficultyConfig
+    Change the bounds and rules of the adaptive difficulty with the ones of a config file
+    Input: path to the config file
+*/
+func loadDifficultyConfig(fileName string) error {
+    data, err := ioutil.ReadFile(fileName)
+    if err != nil {
+        return err
+    }
+
+    // the config starts from the defaults, so unmarshal only changes the settings which are given
+    config := adaptiveConfig
+    if err := json.Unmarshal(data, &config); err != nil {
+        return fmt.Errorf("%s: %v", fileName, err)
+    }
+    if config.EnemySpeed.Easiest <= 0 || config.EnemySpeed.Hardest <= 0 || config.FrightenedTime.Easiest < 0 || config.FrightenedTime.Hardest < 0 ||
+        config.EnemyAggression.Easiest < 0 || config.EnemyAggression.Hardest < 0 {
+        return fmt.Errorf("%s: enemy speed should be more than 0, enemy aggression and frightened time can't be less than 0", fileName)
+    }
+    if config.Step <= 0 || config.Step > 1 {
+        return fmt.Errorf("%s: step should be more than 0 and up to 1", fileName)
+    }
+    adaptiveConfig = config
+    return nil
+}
+
+/*
+    Function: isAdapting
+    Check if the difficulty is adapted in this game. Only the one player mode is adapted, and not the demo or a test
+    play of the editor
+*/
+func isAdapting() bool {
+    return isAdaptive && !isDemo && !isVersus && numPlayers == 1 && len(pacmen) == 1 && mazeEditor == nil && rollbackSession == nil
+}
+
+/*
+    Function: getDifficultyValue
+    Get the value of a setting at the current difficulty, between the neutral value (difficulty 0) and a bound
+    Inputs: bounds of the setting and the neutral value (1 for the settings which are multiplied, 0 for the added ones)
+*/
+func (bounds DifficultyBounds) getDifficultyValue(neutral float64) float64 {
+    if !isAdapting() || difficulty == 0 {
+        return neutral
+    }
+    if difficulty < 0 {
+        return neutral+(bounds.Easiest-neutral)*-difficulty
+    }
+    return neutral+(bounds.Hardest-neutral)*difficulty
+}
+
+/*
+    Function: adaptEnemySpeed
+    Get the speed of an enemy at the current difficulty
+    Input: speed of the level
+*/
+func adaptEnemySpeed(speed float64) float64 {
+    // enemies faster than half a block would jump over PacMan (see parseMazeSpeed)
+    return math.Min(speed*adaptiveConfig.EnemySpeed.getDifficultyValue(1), float64(blockSize)/2)
+}
+
+/*
+    Function: adaptAggression
+    Get how often an enemy chases PacMan at the current difficulty
+    Input: aggression of the level
+*/
+func adaptAggression(aggression float64) float64 {
+    return math.Min(aggression*adaptiveConfig.EnemyAggression.getDifficultyValue(1), 1)
+}
+
+/*
+    Function: adaptFrightenedTime
+    Get how long enemies stay frightened at the current difficulty
+    Input: frightened time of the level (frames)
+*/
+func adaptFrightenedTime(frames int) int {
+    return int(math.Round(float64(frames)*adaptiveConfig.FrightenedTime.getDifficultyValue(1)))
+}
+
+/*
+    Function: adaptEnemies
+    Get the enemies of a level at the current difficulty. Added enemies chase like the last enemy of the level
+    Input: how often each enemy of the level chases PacMan (see getEnemyAggressions)
+*/
+func adaptEnemies(aggressions []float64) []float64 {
+    count := len(aggressions)+int(math.Round(adaptiveConfig.Enemies.getDifficultyValue(0)))
+    if count < 1 {
+        count = 1
+    }
+    adapted := []float64{}
+    for i := 0; i < count && len(aggressions) > 0; i++ {
+        adapted = append(adapted, adaptAggression(getEnemyAggression(aggressions, i)))
+    }
+    return adapted
+}
+
+/*
+    Function: getEnemyAggression
+    Get how often an enemy of a level chases PacMan. Enemies after the last one of the level chase like the last one
+    Inputs: how often each enemy of the level chases PacMan and the position of the enemy
+*/
+func getEnemyAggression(aggressions []float64, enemy int) float64 {
+    if enemy >= len(aggressions) {
+        return aggressions[len(aggressions)-1]
+    }
+    return aggressions[enemy]
+}
+
+/*
+    Function: resetLevelPerformance
+    Start counting how the player does on a new level. It's called when a level starts
+*/
+func resetLevelPerformance() {
+    levelDeaths = 0
+    levelFrames = 0
+    levelNearMisses = 0
+    nearEnemies = map[*Sprite]bool{}
+}
+
+/*
+    Function: changeDifficulty
+    Make the game easier or harder by some steps, within -1 and 1, and log the change with the settings it gives
+    Inputs: steps (less than 0 is easier) and the reason
+*/
+func changeDifficulty(steps float64, reason string) {
+    previous := difficulty
+    difficulty = math.Max(-1, math.Min(1, difficulty+steps*adaptiveConfig.Step))
+    if difficulty == previous {
+        return
+    }
+    log.Printf("difficulty %+.2f -> %+.2f (%s): enemy speed x%.2f, enemy aggression x%.2f, frightened time x%.2f, enemies %+d",
+        previous, difficulty, reason,
+        adaptiveConfig.EnemySpeed.getDifficultyValue(1),
+        adaptiveConfig.EnemyAggression.getDifficultyValue(1),
+        adaptiveConfig.FrightenedTime.getDifficultyValue(1),
+        int(math.Round(adaptiveConfig.Enemies.getDifficultyValue(0))))
+}
+
+/*
+    Function: onDifficultyEvent
+    Change the difficulty when PacMan loses a life or clears a level. It listens to the game events (see events.go)
+    Input: name of the event
+*/
+func onDifficultyEvent(event string) {
+    if !isAdapting() {
+        return
+    }
+
+    switch event {
+    case eventDeath:
+        levelDeaths++
+        nearEnemies = map[*Sprite]bool{}
+        if levelDeaths < adaptiveConfig.DeathsToEase {
+            return
+        }
+        changeDifficulty(-1, fmt.Sprintf("%d lives lost on level %d", levelDeaths, gameInfo.level))
+
+        // the enemies of the level go on at the new difficulty from the next life
+        aggressions := getEnemyAggressions(gameInfo.level)
+        for i, enemy := range enemies {
+            enemy.speed = adaptEnemySpeed(getEnemySpeed(gameInfo.level))
+            if len(aggressions) > 0 {
+                enemy.aggression = adaptAggression(getEnemyAggression(aggressions, i))
+            }
+        }
+
+    case eventLevelClear:
+        clearTime := adaptiveConfig.ClearTime
+        if parTime := getLevelMaze(gameInfo.level).parTime; parTime > 0 {
+            clearTime = parTime
+        }
+        seconds := levelFrames/60
+        if levelDeaths == 0 && seconds <= clearTime && levelNearMisses < adaptiveConfig.NearMisses {
+            changeDifficulty(1, fmt.Sprintf("level %d cleared in %s without losing a life, near misses: %d", gameInfo.level, formatParTime(seconds), levelNearMisses))
+        }
+    }
+}
+
+/*
+    Function: updateDifficulty
+    Count the frames played on the level and the near misses. It's called on each frame while PacMan and the enemies move
+*/
+func updateDifficulty() {
+    if !isAdapting() {
+        return
+    }
+
+    levelFrames++
+    pacman := pacmen[0]
+    for _, enemy := range enemies {
+        distance := math.Hypot(enemy.x-pacman.x, enemy.y-pacman.y)
+        if distance < nearMissDistance && !enemy.isFrightened {
+            nearEnemies[enemy] = true
+        } else if distance > nearMissAwayDistance && nearEnemies[enemy] {
+            delete(nearEnemies, enemy)
+            levelNearMisses++
+        }
+    }
+}
@@ -0,0 +1,393 @@
+package main
+
+/*
+    This file contains the discovery of networked games on the local network and the lobby screen.
+
+    A host (-host flag) announces its game once a second with a UDP broadcast on the discovery port. The announcement is
+    JSON with the name of the game, the mode, the level pack, the free player slots and the TCP port to join.
+    The lobby (-lobby flag) listens on the discovery port and lists the games it hears about. A game which is not heard
+    about for a few seconds is removed from the list. Selecting a game joins it like -join (see net.go).
+
+    Announcements have the protocol version of the host. Games of a different version can't be joined (the host would
+    reject the player), so they are shown in the lobby but can't be selected to join. New fields can be added to the
+    announcement later, older lobbies skip the fields they don't know.
+*/
+import (
+    "encoding/json"
+    "fmt"
+    "image/color"
+    "log"
+    "net"
+    "os"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure which is sent by a host to announce its game. Fields are exported, so encoding/json can write them
+type GameAnnouncement struct {
+    Game string `json:"game"` // always discoveryGame, so other broadcasts on the port are skipped
+    Version int `json:"version"` // protocol version of the host (netProtocolVersion)
+    Name string `json:"name"` // name of the game
+    Mode string `json:"mode"` // mode the host is playing (Ex: co-op)
+    Pack string `json:"pack"` // level pack of the host
+    Port int `json:"port"` // TCP port to join the game
+    FreeSlots int `json:"freeSlots"` // number of players who can still join
+}
+
+// Structure which keeps a game heard about in the lobby
+type DiscoveredGame struct {
+    announcement GameAnnouncement // holds the last announcement of the game
+    address string // holds the address to join the game (IP of the host and the port of the announcement)
+    lastSeen time.Time // holds the time of the last announcement
+}
+
+// Structure which keeps the lobby screen
+type Lobby struct {
+    conn *net.UDPConn // holds the UDP socket which listens on the discovery port
+    mutex sync.Mutex // announcements are read in another goroutine, so games is guarded by a mutex
+    games map[string]*DiscoveredGame // holds the games heard about, by address
+    selected int // holds the position of the selected game in the list
+    message string // holds the reason the last join failed
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the name in the announcements, so only announcements of this game are used
+var discoveryGame = "ThePacMan"
+
+// Let's define the UDP port of the announcements
+var discoveryPort = 7776
+
+// Let's define how often a host announces its game (frames) and how long a game stays in the lobby without an announcement
+var announceFrames = 60
+var discoveryTimeout = 3*time.Second
+
+// Let's define the colors of the games in the lobby: games which can be joined and games which can't be joined
+var lobbyGameColor = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+var lobbyDisabledColor = color.RGBA{R: 110, G: 110, B: 110, A: 255}
+
+// Let's define the most games shown in the lobby at once
+var lobbyMaxGames = 12
+
+// Variable to hold the level pack of the game, which is shown in the announcements
+var levelPackName = classicPackName
+
+// Variable to hold the lobby (nil if the lobby isn't open)
+var lobby *Lobby
+
+/*
+    ##################################
+    ## Functions to announce a game ##
+    ##################################
+*/
+
+/*
+    Function: getDefaultGameName
+    Get the name of a hosted game when no name is given. Ex: PacMan on mycomputer
+*/
+func getDefaultGameName() string {
+    hostName, err := os.Hostname()
+    if err != nil {
+        return "PacMan"
+    }
+    return "PacMan on "+hostName
+}
+
+/*
+    Function: startAnnouncing
+    Start announcing the game of the host. The announcement is sent by announce, from the update function
+    Inputs: address to send the announcements to (Ex: 255.255.255.255 to broadcast) and the name of the game
+*/
+func (host *NetHost) startAnnouncing(address string, name string) error {
+    if _, _, err := net.SplitHostPort(address); err != nil {
+        address = net.JoinHostPort(address, strconv.Itoa(discoveryPort))
+    }
+    announceAddress, err := net.ResolveUDPAddr("udp4", address)
+    if err != nil {
+        return err
+    }
+    conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+    if err != nil {
+        return err
+    }
+
+    host.announceConn = conn
+    host.announceAddress = announceAddress
+    host.gameName = name
+    return nil
+}
+
+/*
+    Function: announce
+    Send the announcement of the game once every announceFrames frames. It's called on each frame by the update function
+*/
+func (host *NetHost) announce() {
+    if host.announceConn == nil {
+        return
+    }
+    host.announceTimer--
+    if host.announceTimer > 0 {
+        return
+    }
+    host.announceTimer = announceFrames
+
+    announcement, err := json.Marshal(host.getAnnouncement())
+    if err != nil {
+        log.Println(err)
+        return
+    }
+
+    // a lost announcement doesn't matter, the next one is sent in a second
+    host.announceConn.WriteToUDP(announcement, host.announceAddress)
+}
+
+/*
+    Function: getAnnouncement
+    Get the announcement of the game from the state of the game and the players who joined
+*/
+func (host *NetHost) getAnnouncement() GameAnnouncement {
+    // player 1 is on the host, and the versus mode has only two players
+    freeSlots := len(playerKeys)-1-getRemotePlayerCount()
+    if isVersus {
+        freeSlots = 1-getRemotePlayerCount()
+    }
+    if freeSlots < 0 {
+        freeSlots = 0
+    }
+
+    return GameAnnouncement{
+        Game: discoveryGame,
+        Version: int(netProtocolVersion),
+        Name: host.gameName,
+        Mode: getGameModeName(),
+        Pack: levelPackName,
+        Port: host.listener.Addr().(*net.TCPAddr).Port,
+        FreeSlots: freeSlots,
+    }
+}
+
+/*
+    Function: getGameModeName
+    Get the name of the mode the game is playing
+*/
+func getGameModeName() string {
+    switch {
+    case isNewGame && !gameInfo.isStarted:
+        return "start screen"
+    case isVersus:
+        return "versus"
+    case len(gameInfo.stats) > 1:
+        return "co-op"
+    case numPlayers > 1:
+        return "2 players"
+    }
+    return "1 player"
+}
+
+/*
+    ############################
+    ## Functions of the lobby ##
+    ############################
+*/
+
+/*
+    Function: startLobby
+    Open the lobby and start listening for announcements on the discovery port
+*/
+func startLobby() (*Lobby, error) {
+    conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: discoveryPort})
+    if err != nil {
+        return nil, err
+    }
+
+    lobby := &Lobby{
+        conn: conn,
+        games: map[string]*DiscoveredGame{},
+    }
+    go lobby.readAnnouncements()
+    return lobby, nil
+}
+
+/*
+    Function: readAnnouncements
+    Read the announcements of the hosts until the lobby is closed. Packets which are not announcements of this game are skipped
+*/
+func (lobby *Lobby) readAnnouncements() {
+    buffer := make([]byte, 2048)
+    for {
+        size, sender, err := lobby.conn.ReadFromUDP(buffer)
+        if err != nil {
+            return
+        }
+
+        announcement := GameAnnouncement{}
+        if err := json.Unmarshal(buffer[:size], &announcement); err != nil || announcement.Game != discoveryGame {
+            continue
+        }
+        address := net.JoinHostPort(sender.IP.String(), strconv.Itoa(announcement.Port))
+
+        lobby.mutex.Lock()
+        lobby.games[address] = &DiscoveredGame{
+            announcement: announcement,
+            address: address,
+            lastSeen: time.Now(),
+        }
+        lobby.mutex.Unlock()
+    }
+}
+
+/*
+    Function: getGames
+    Get the games heard about recently, sorted by name. Games which were not announced for a while are removed
+*/
+func (lobby *Lobby) getGames() []DiscoveredGame {
+    lobby.mutex.Lock()
+    defer lobby.mutex.Unlock()
+
+    games := []DiscoveredGame{}
+    for address, game := range lobby.games {
+        if time.Since(game.lastSeen) > discoveryTimeout {
+            delete(lobby.games, address)
+            continue
+        }
+        games = append(games, *game)
+    }
+    sort.Slice(games, func(i, j int) bool {
+        if games[i].announcement.Name != games[j].announcement.Name {
+            return games[i].announcement.Name < games[j].announcement.Name
+        }
+        return games[i].address < games[j].address
+    })
+    return games
+}
+
+/*
+    Function: close
+    Stop listening for announcements
+*/
+func (lobby *Lobby) close() {
+    lobby.conn.Close()
+}
+
+/*
+    Function: isJoinable
+    Check if a game can be joined: it has the same protocol version and a free player slot
+*/
+func (game DiscoveredGame) isJoinable() bool {
+    return game.announcement.Version == int(netProtocolVersion) && game.announcement.FreeSlots > 0
+}
+
+/*
+    Function: getLobbyLabel
+    Get the line of a game in the lobby. Ex: PacMan on mycomputer  co-op  classic  2 free
+*/
+func (game DiscoveredGame) getLobbyLabel() string {
+    name := game.announcement.Name
+    if len(name) > 20 {
+        name = name[:20]
+    }
+
+    status := strconv.Itoa(game.announcement.FreeSlots)+" free"
+    if game.announcement.Version != int(netProtocolVersion) {
+        status = "version "+strconv.Itoa(game.announcement.Version)
+    } else if game.announcement.FreeSlots <= 0 {
+        status = "full"
+    }
+    return fmt.Sprintf("%s  %s  %s  %s", name, game.announcement.Mode, game.announcement.Pack, status)
+}
+
+/*
+    Function: updateLobby
+    The update function of the lobby (instead of update in main.go)
+    Up and down select a game and Enter joins it. After joining, the game is played like a game joined with -join
+*/
+func updateLobby(screen *Image) error {
+    if netClient != nil {
+        return updateClient(screen)
+    }
+
+    games := lobby.getGames()
+    if isKeyJustPressed(KeyUp) && lobby.selected > 0 {
+        lobby.selected--
+    }
+    if isKeyJustPressed(KeyDown) {
+        lobby.selected++
+    }
+    if lobby.selected >= len(games) {
+        lobby.selected = len(games)-1
+    }
+    if lobby.selected < 0 {
+        lobby.selected = 0
+    }
+
+    // let's join the selected game. Games of a different version or without a free slot can't be selected to join
+    if isKeyJustPressed(KeyEnter) && lobby.selected < len(games) && games[lobby.selected].isJoinable() {
+        game := games[lobby.selected]
+        client, err := joinGame(game.address)
+        if err != nil {
+            lobby.message = err.Error()
+        } else {
+            netClient = client
+            lobby.close()
+            log.Println("joined", game.announcement.Name, "at", game.address, "as player", client.number+1)
+        }
+    }
+
+    if isDrawingSkipped() {
+        return nil
+    }
+    drawLobby(screen, games)
+    return nil
+}
+
+/*
+    Function: drawLobby
+    Draw the lobby screen with the list of games. The selected game has an arrow, games which can't be joined are gray
+    Inputs: screen and the games
+*/
+func drawLobby(screen *Image, games []DiscoveredGame) {
+    background, _ := getPaletteColor("background")
+    screen.Fill(background)
+
+    clr := getTextColor()
+    _, textHeight := measureText("0")
+    lineHeight := textHeight+textHeight/2
+    y := blockSize*2
+    drawTextCentered(screen, "GAMES ON THE LOCAL NETWORK", screenSizeX/2, y, clr)
+    y = y+lineHeight*2
+
+    if len(games) == 0 {
+        drawTextCentered(screen, "Looking for games...", screenSizeX/2, y, clr)
+    }
+    for i, game := range games {
+        if i == lobbyMaxGames {
+            break
+        }
+        gameClr := lobbyGameColor
+        if !game.isJoinable() {
+            gameClr = lobbyDisabledColor
+        }
+        if i == lobby.selected {
+            drawText(screen, ">", blockSize, y, clr)
+        }
+        drawText(screen, game.getLobbyLabel(), blockSize*2, y, gameClr)
+        y = y+lineHeight
+    }
+
+    bottomY := screenSizeY-blockSize*2-textHeight
+    drawTextCentered(screen, "Up/Down: SELECT  Enter: JOIN", screenSizeX/2, bottomY, clr)
+    if lobby.message != "" {
+        drawTextCentered(screen, lobby.message, screenSizeX/2, bottomY-lineHeight, clr)
+    }
+}
@@ -0,0 +1,137 @@
+//go:build !nowindow
+// +build !nowindow
+
+package main
+
+/*
+    This file contains the sound backend which plays the sounds with the audio package of ebiten (see audio.go).
+    It's left out of the game built with the nowindow tag (see nowindow.go), which has no sound.
+*/
+import (
+    "github.com/hajimehoshi/ebiten/audio"
+    "github.com/hajimehoshi/ebiten/audio/wav"
+    "io/ioutil"
+    "log"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure of the sound backend which plays the sounds with ebiten audio
+type EbitenAudioBackend struct {
+    context *audio.Context // holds the audio context of ebiten
+    effects map[string]*audio.Player // holds the player of each sound effect
+    siren *audio.Player // holds the player of the siren
+    sirenStream *SirenStream // holds the stream which generates the sound of the siren
+}
+
+/*
+    ##########################
+    ## Ebiten audio backend ##
+    ##########################
+*/
+
+/*
+    Function: newEbitenAudioBackend
+    Create the ebiten audio backend and load all the sound effects
+    Returns an error if the audio can not be used (Ex: there's no audio device)
+*/
+func newEbitenAudioBackend() (*EbitenAudioBackend, error) {
+    context, err := audio.NewContext(sampleRate)
+    if err != nil {
+        return nil, err
+    }
+
+    backend := &EbitenAudioBackend{
+        context: context,
+        effects: map[string]*audio.Player{},
+        sirenStream: &SirenStream{},
+    }
+
+    // let's create the sound effects from the sound definition file of the theme (see synth.go)
+    definitions, err := loadSoundDefinitions(getAsset("sounds"))
+    if err != nil {
+        return nil, err
+    }
+
+    // let's load each sound effect. A theme can give a wav file for an effect, otherwise the effect is synthesized
+    for _, name := range eventEffects {
+        pcm := samplesToPCM(synthesize(definitions[name], sampleRate))
+        if file := getAsset("sound."+name); file != "" {
+            pcm, err = loadWavFile(context, file)
+            if err != nil {
+                return nil, err
+            }
+        }
+
+        player, err := audio.NewPlayerFromBytes(context, pcm)
+        if err != nil {
+            return nil, err
+        }
+        backend.effects[name] = player
+    }
+
+    backend.siren, err = audio.NewPlayer(context, backend.sirenStream)
+    if err != nil {
+        return nil, err
+    }
+    return backend, nil
+}
+
+/*
+    Function: loadWavFile
+    Read a wav file and get its PCM data (16 bit stereo in the sample rate of the audio context)
+    Inputs: audio context and path to the wav file
+*/
+func loadWavFile(context *audio.Context, file string) ([]byte, error) {
+    content, err := readGameFile(file)
+    if err != nil {
+        return nil, err
+    }
+
+    stream, err := wav.Decode(context, audio.BytesReadSeekCloser(content))
+    if err != nil {
+        return nil, err
+    }
+    return ioutil.ReadAll(stream)
+}
+
+func (backend *EbitenAudioBackend) playEffect(name string) {
+    player, ok := backend.effects[name]
+    if !ok {
+        return
+    }
+
+    // let's play the effect from the beginning, even if it's still playing
+    if err := player.Rewind(); err != nil {
+        log.Println(err)
+        return
+    }
+    if err := player.Play(); err != nil {
+        log.Println(err)
+    }
+}
+
+func (backend *EbitenAudioBackend) setSiren(playing bool, pitch float64) {
+    backend.sirenStream.setPitch(pitch)
+
+    if playing && !backend.siren.IsPlaying() {
+        if err := backend.siren.Play(); err != nil {
+            log.Println(err)
+        }
+    } else if !playing && backend.siren.IsPlaying() {
+        if err := backend.siren.Pause(); err != nil {
+            log.Println(err)
+        }
+    }
+}
+
+func (backend *EbitenAudioBackend) setVolume(volume float64) {
+    for _, player := range backend.effects {
+        player.SetVolume(volume)
+    }
+    // the siren is a background sound, so let's keep it quieter than the effects
+    backend.siren.SetVolume(volume/3.0)
+}
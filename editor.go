@@ -0,0 +1,673 @@
+package main
+
+/*
+    This file contains the maze editor, which is opened with -edit maze.txt instead of the game.
+
+    The editor shows the grid of the maze and the tiles are painted on it with the mouse. The brush is picked from the
+    palette at the top (click it, or press the number key of the brush): walls, food, power pellets, empty paths, the
+    starts of the players and the spawn points of the enemies. The right mouse button paints an empty path.
+    The starts of the players are only once in a maze, so painting a start removes it from where it was.
+
+    Keys of the editor
+    M          : turn mirror painting on or off. Each tile is also painted on the other side of the maze (for symmetric mazes)
+    Ctrl+Z     : undo the last change (a whole mouse stroke is a single change)
+    Ctrl+Y     : redo the last change which was undone
+    Arrow keys : resize the maze. Right and Down add a column or a row of walls, Left and Up remove the last column or row
+    Ctrl+S     : save the maze into the file, in the text format of readMazeFile (the header of the file is kept, see maze.go)
+    T          : test-play the maze as level 1. Escape (or Space after the game is over) comes back to the editor
+
+    The maze is checked after each change. Tiles with a problem (Ex: food PacMan can't get to) are highlighted in red, and
+    the problem of the tile under the mouse (or the first problem) is shown at the bottom. A maze with problems can be
+    saved, but it can't be test-played.
+*/
+import (
+    "fmt"
+    "image/color"
+    "io/ioutil"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+
+// Structure of a brush of the palette
+type EditorBrush struct {
+    tile byte // holds the character of the tile in the maze file
+    name string // holds the name of the brush shown at the bottom
+}
+
+// Structure which keeps the state of the editor
+type Editor struct {
+    fileName string // holds the maze file which is edited
+    header Maze // holds the header of the maze file (name, author, speeds...), which is written back when saving
+    maze []string // holds the rows of the maze
+    brush int // holds the brush picked from the palette
+    isMirrored bool // holds whether mirror painting is on
+    undo [][]string // holds the mazes before each change, the last one is undone first
+    redo [][]string // holds the mazes which were undone
+    isStroking bool // holds whether a mouse button is held (a stroke is painted)
+    stroke []string // holds the maze before the stroke, until the stroke changes something
+    problems []MazeProblem // holds the problems found in the maze
+    isPlaying bool // holds whether the maze is test-played
+    tiles map[byte]*Sprite // holds the images of the tiles which have one
+    message string // holds a message shown for a while (Ex: the maze is saved)
+    messageTimer int
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the brushes of the palette, in the order of the number keys (1 is the first brush)
+var editorBrushes = []EditorBrush{
+    {'0', "WALL"},
+    {'.', "FOOD"},
+    {'o', "POWER PELLET"},
+    {' ', "EMPTY"},
+    {'P', "PLAYER 1 START"},
+    {'2', "PLAYER 2 START"},
+    {'3', "PLAYER 3 START"},
+    {'4', "PLAYER 4 START"},
+    {'E', "ENEMY SPAWN"},
+}
+
+// Let's define the size of a new maze, when the file to edit doesn't exist yet
+var editorNewCols = 28
+var editorNewRows = 23
+
+// Let's define the smallest maze the editor can resize to
+var editorMinSize = 3
+
+// Let's define how many changes can be undone
+var editorUndoLimit = 100
+
+// Let's define the time (frames) a message of the editor is shown
+var editorMessageTime = 120
+
+// Let's define the colors of the problems, the cursor and the selected brush
+var editorProblemColor = color.RGBA{R: 255, A: 110}
+var editorCursorColor = color.RGBA{R: 255, G: 255, B: 255, A: 70}
+var editorSelectedColor = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+// Variable to hold the editor, when the game is started with -edit
+var mazeEditor *Editor
+
+/*
+    ######################
+    ## Editing the maze ##
+    ######################
+*/
+
+/*
+    Function: newEditor
+    Open a maze file in the editor. If the file doesn't exist, the editor starts with a new maze with walls around it
+    A Tiled map can be opened too (see tiled.go), it's saved as a text maze file
+    Short rows are filled with walls, so all the rows are as long as the longest row
+    Input: maze file
+*/
+func newEditor(fileName string) *Editor {
+    editor := &Editor{fileName: fileName}
+    if _, err := os.Stat(fileName); err == nil {
+        // a maze with problems can be opened, they're shown to fix them
+        header, err := openMazeFile(fileName)
+        if err != nil {
+            log.Fatal(err)
+        }
+        editor.header = header
+        editor.maze = editor.header.rows
+    } else {
+        editor.maze = getNewMaze(editorNewCols, editorNewRows)
+    }
+    if len(editor.maze) == 0 {
+        editor.maze = getNewMaze(editorNewCols, editorNewRows)
+    }
+
+    cols := 0
+    for _, line := range editor.maze {
+        if len(line) > cols {
+            cols = len(line)
+        }
+    }
+    for row, line := range editor.maze {
+        editor.maze[row] = line+strings.Repeat("0", cols-len(line))
+    }
+
+    // the tiles are drawn with the images of the theme
+    editor.tiles = map[byte]*Sprite{}
+    for tile, asset := range map[byte]string{'.': "food", 'o': "pellet", 'P': "pacman", 'E': "enemy"} {
+        sprite := createSprite(getAsset(asset), blockSize, blockSize, 0, 0)
+        editor.tiles[tile] = &sprite
+    }
+
+    // a Tiled map or a PNG maze is saved as a text maze file next to it (Ex: maze.tmx is saved as maze.txt)
+    if isTiledMapFile(fileName) || isPixelMazeFile(fileName) {
+        editor.fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName))+".txt"
+    }
+
+    editor.problems = getMazeProblems(editor.getMaze())
+    return editor
+}
+
+/*
+    Function: getNewMaze
+    Get a maze of empty paths with walls around it
+    Inputs: number of columns and rows
+*/
+func getNewMaze(cols int, rows int) []string {
+    maze := make([]string, rows)
+    for row := range maze {
+        if row == 0 || row == rows-1 {
+            maze[row] = strings.Repeat("0", cols)
+        } else {
+            maze[row] = "0"+strings.Repeat(" ", cols-2)+"0"
+        }
+    }
+    return maze
+}
+
+/*
+    Function: paint
+    Paint a tile on a maze point, and on the mirrored maze point when mirror painting is on
+    The starts of the players are only once in the maze, so they are not mirrored and their old place becomes an empty path
+    Returns true if the maze has changed
+    Inputs: maze point and the tile
+*/
+func (editor *Editor) paint(col int, row int, tile byte) bool {
+    isStart := tile == 'P' || (tile >= '1' && tile <= '4')
+    changed := false
+    if isStart && editor.maze[row][col] != tile {
+        for r, line := range editor.maze {
+            for c := range line {
+                if line[c] == tile || (tile == 'P' && line[c] == '1') {
+                    editor.setTile(c, r, ' ')
+                }
+            }
+        }
+    }
+    if editor.maze[row][col] != tile {
+        editor.setTile(col, row, tile)
+        changed = true
+    }
+
+    mirrorCol := len(editor.maze[row])-1-col
+    if editor.isMirrored && !isStart && editor.maze[row][mirrorCol] != tile {
+        editor.setTile(mirrorCol, row, tile)
+        changed = true
+    }
+    return changed
+}
+
+/*
+    Function: setTile
+    Change the character of a maze point
+    Inputs: maze point and the tile
+*/
+func (editor *Editor) setTile(col int, row int, tile byte) {
+    line := []byte(editor.maze[row])
+    line[col] = tile
+    editor.maze[row] = string(line)
+}
+
+/*
+    Function: resize
+    Add or remove columns and rows at the right and bottom sides of the maze. New maze points are walls
+    Inputs: change of the number of columns and rows
+*/
+func (editor *Editor) resize(cols int, rows int) {
+    newCols := len(editor.maze[0])+cols
+    newRows := len(editor.maze)+rows
+    if newCols < editorMinSize || newRows < editorMinSize {
+        return
+    }
+    editor.saveUndo(editor.maze)
+
+    maze := make([]string, newRows)
+    for row := range maze {
+        line := strings.Repeat("0", newCols)
+        if row < len(editor.maze) {
+            line = editor.maze[row]
+        }
+        if len(line) < newCols {
+            line = line+strings.Repeat("0", newCols-len(line))
+        }
+        maze[row] = line[:newCols]
+    }
+    editor.maze = maze
+    editor.onChange()
+    editor.setScreenSize()
+}
+
+/*
+    Function: saveUndo
+    Remember the maze before a change, so the change can be undone. Changes which were undone can't be redone anymore
+    Input: maze before the change
+*/
+func (editor *Editor) saveUndo(maze []string) {
+    editor.undo = append(editor.undo, copyMaze(maze))
+    if len(editor.undo) > editorUndoLimit {
+        editor.undo = editor.undo[1:]
+    }
+    editor.redo = nil
+}
+
+/*
+    Function: undoChange
+    Bring back the maze before the last change
+*/
+func (editor *Editor) undoChange() {
+    if len(editor.undo) == 0 {
+        return
+    }
+    editor.redo = append(editor.redo, editor.maze)
+    editor.maze = editor.undo[len(editor.undo)-1]
+    editor.undo = editor.undo[:len(editor.undo)-1]
+    editor.onChange()
+    editor.setScreenSize()
+}
+
+/*
+    Function: redoChange
+    Make the last change which was undone again
+*/
+func (editor *Editor) redoChange() {
+    if len(editor.redo) == 0 {
+        return
+    }
+    editor.undo = append(editor.undo, editor.maze)
+    editor.maze = editor.redo[len(editor.redo)-1]
+    editor.redo = editor.redo[:len(editor.redo)-1]
+    editor.onChange()
+    editor.setScreenSize()
+}
+
+/*
+    Function: onChange
+    Check the maze again after a change
+*/
+func (editor *Editor) onChange() {
+    editor.problems = getMazeProblems(editor.getMaze())
+}
+
+/*
+    Function: save
+    Write the maze into its file, with the header it had and a row on each line like readMazeFile reads it
+*/
+func (editor *Editor) save() {
+    err := ioutil.WriteFile(editor.fileName, []byte(formatMaze(editor.getMaze())), 0644)
+    if err != nil {
+        log.Println(err)
+        editor.showMessage("THE MAZE CAN'T BE SAVED")
+        return
+    }
+    editor.showMessage("SAVED "+editor.fileName)
+}
+
+/*
+    Function: showMessage
+    Show a message at the bottom for a while
+    Input: message
+*/
+func (editor *Editor) showMessage(message string) {
+    editor.message = message
+    editor.messageTimer = editorMessageTime
+}
+
+/*
+    Function: setScreenSize
+    Set the size of the screen to fit the maze of the editor with the palette at the top and the bars of the HUD.
+    The screen is the same while test-playing, so the game is drawn like in a level
+*/
+func (editor *Editor) setScreenSize() {
+    playfieldX = 0
+    playfieldY = hudTopHeight
+    // the screen is at least as wide as the palette
+    screenSizeX = len(editor.maze[0])*blockSize
+    if paletteWidth := len(editorBrushes)*blockSize*2+blockSize*5; screenSizeX < paletteWidth {
+        screenSizeX = paletteWidth
+    }
+    screenSizeY = hudTopHeight+len(editor.maze)*blockSize+hudBottomHeight
+    resizeWindow(screenSizeX, screenSizeY)
+}
+
+/*
+    #######################
+    ## Checking the maze ##
+    #######################
+*/
+
+/*
+    Function: getProblemAt
+    Get the problem of a maze point. The problem of the whole maze is given if the maze point has none
+    Returns an empty string if the maze has no problems
+    Input: maze point (-1 if the mouse is not on the maze)
+*/
+func (editor *Editor) getProblemAt(col int, row int) string {
+    for _, problem := range editor.problems {
+        if problem.col == col && problem.row == row {
+            return problem.message
+        }
+    }
+    if len(editor.problems) > 0 {
+        return editor.problems[0].message
+    }
+    return ""
+}
+
+/*
+    ##################
+    ## Test-playing ##
+    ##################
+*/
+
+/*
+    Function: startTestPlay
+    Play the maze of the editor as level 1 of a one player game
+*/
+func (editor *Editor) startTestPlay() {
+    if len(editor.problems) > 0 {
+        editor.showMessage("FIX THE PROBLEMS TO TEST-PLAY")
+        return
+    }
+    editor.isPlaying = true
+    newGame()
+    startPlayers(1)
+    isNewGame = false
+}
+
+/*
+    Function: stopTestPlay
+    Stop playing the maze and go back to the editor
+*/
+func (editor *Editor) stopTestPlay() {
+    editor.isPlaying = false
+    updateSiren(false)
+    currentTheme = selectedTheme
+}
+
+/*
+    Function: isEditorPlaying
+    Check if the game which is played is a maze tested in the editor
+*/
+func isEditorPlaying() bool {
+    return mazeEditor != nil && mazeEditor.isPlaying
+}
+
+/*
+    Function: getMaze
+    Get the maze of the editor with the header of its file
+*/
+func (editor *Editor) getMaze() Maze {
+    maze := editor.header
+    maze.rows = copyMaze(editor.maze)
+    return maze
+}
+
+/*
+    #################
+    ## Editor Loop ##
+    #################
+*/
+
+/*
+    Function: updateEditor
+    Handle the mouse and the keys of the editor and draw it. While test-playing, the game runs instead
+    It's given to runWindow instead of the update function when the game is started with -edit
+    Input: screen
+*/
+func updateEditor(screen *Image) error {
+    editor := mazeEditor
+    if editor.isPlaying {
+        // Escape, or Space when the game is over or the level is complete, brings back the editor
+        isOver := gameInfo.isGameOver || gameInfo.isLevelComplete
+        if isKeyJustPressed(KeyEscape) || (isOver && isKeyJustPressed(KeySpace)) {
+            editor.stopTestPlay()
+        } else {
+            return update(screen)
+        }
+    }
+
+    if editor.messageTimer > 0 {
+        editor.messageTimer--
+    }
+    col, row := editor.getCursorPoint()
+    editor.updateMouse(col, row)
+    editor.updateKeys()
+
+    if isDrawingSkipped() {
+        return nil
+    }
+    editor.draw(screen, col, row)
+    return nil
+}
+
+/*
+    Function: getCursorPoint
+    Get the maze point under the mouse. Returns -1, -1 if the mouse is not on the maze
+*/
+func (editor *Editor) getCursorPoint() (int, int) {
+    x, y := cursorPosition()
+    x = x-playfieldX
+    y = y-playfieldY
+    if x < 0 || y < 0 {
+        return -1, -1
+    }
+    col := x/blockSize
+    row := y/blockSize
+    if row >= len(editor.maze) || col >= len(editor.maze[row]) {
+        return -1, -1
+    }
+    return col, row
+}
+
+/*
+    Function: updateMouse
+    Pick a brush when the palette is clicked, and paint the maze while a mouse button is held on it
+    A mouse stroke (from pressing a button to releasing it) is undone as a single change
+    Input: maze point under the mouse
+*/
+func (editor *Editor) updateMouse(col int, row int) {
+    if isMouseButtonJustPressed(MouseButtonLeft) {
+        if brush := editor.getPaletteBrush(); brush >= 0 {
+            editor.brush = brush
+        }
+    }
+
+    isLeft := isMouseButtonPressed(MouseButtonLeft)
+    isRight := isMouseButtonPressed(MouseButtonRight)
+    if !isLeft && !isRight {
+        editor.isStroking = false
+        editor.stroke = nil
+        return
+    }
+    if !editor.isStroking {
+        editor.isStroking = true
+        editor.stroke = copyMaze(editor.maze)
+    }
+    if col < 0 {
+        return
+    }
+
+    // the right mouse button paints an empty path
+    tile := editorBrushes[editor.brush].tile
+    if isRight {
+        tile = ' '
+    }
+    if editor.paint(col, row, tile) {
+        // the maze before the stroke is remembered at the first change of the stroke
+        if editor.stroke != nil {
+            editor.saveUndo(editor.stroke)
+            editor.stroke = nil
+        }
+        editor.onChange()
+    }
+}
+
+/*
+    Function: getPaletteBrush
+    Get the brush of the palette under the mouse. Returns -1 if the mouse is not on the palette
+*/
+func (editor *Editor) getPaletteBrush() int {
+    x, y := cursorPosition()
+    if y < 0 || y >= hudTopHeight || x < blockSize/2 {
+        return -1
+    }
+    brush := (x-blockSize/2)/(blockSize*2)
+    if brush >= len(editorBrushes) {
+        return -1
+    }
+    return brush
+}
+
+/*
+    Function: updateKeys
+    Handle the keys of the editor (see the top of this file)
+*/
+func (editor *Editor) updateKeys() {
+    isControl := isKeyPressed(KeyControl)
+    for i := range editorBrushes {
+        if isKeyJustPressed(Key1+Key(i)) {
+            editor.brush = i
+        }
+    }
+
+    switch {
+    case isKeyJustPressed(KeyM):
+        editor.isMirrored = !editor.isMirrored
+    case isControl && isKeyJustPressed(KeyZ):
+        editor.undoChange()
+    case isControl && isKeyJustPressed(KeyY):
+        editor.redoChange()
+    case isControl && isKeyJustPressed(KeyS):
+        editor.save()
+    case isKeyJustPressed(KeyT):
+        editor.startTestPlay()
+    case isKeyJustPressed(KeyRight):
+        editor.resize(1, 0)
+    case isKeyJustPressed(KeyLeft):
+        editor.resize(-1, 0)
+    case isKeyJustPressed(KeyDown):
+        editor.resize(0, 1)
+    case isKeyJustPressed(KeyUp):
+        editor.resize(0, -1)
+    }
+}
+
+/*
+    Function: draw
+    Draw the palette, the maze with its problems highlighted, the cursor and the status of the editor
+    Inputs: screen and the maze point under the mouse
+*/
+func (editor *Editor) draw(screen *Image, col int, row int) {
+    background, _ := getPaletteColor("background")
+    screen.Fill(background)
+    clr := getTextColor()
+
+    // the palette, with a frame around the selected brush
+    for i, brush := range editorBrushes {
+        x := blockSize/2+i*blockSize*2
+        y := (hudTopHeight-blockSize)/2
+        if i == editor.brush {
+            fillRect(screen, float64(x-2), float64(y-2), float64(blockSize+4), float64(blockSize+4), editorSelectedColor)
+            fillRect(screen, float64(x-1), float64(y-1), float64(blockSize+2), float64(blockSize+2), background)
+        }
+        editor.drawTile(screen, brush.tile, x, y)
+    }
+    if editor.isMirrored {
+        drawTextRight(screen, "MIRROR", screenSizeX-blockSize/2, (hudTopHeight-blockSize)/2, clr)
+    }
+
+    // the maze, with a red mark on the maze points which have a problem
+    for r, line := range editor.maze {
+        for c := 0; c < len(line); c++ {
+            editor.drawTile(screen, line[c], playfieldX+c*blockSize, playfieldY+r*blockSize)
+        }
+    }
+    for _, problem := range editor.problems {
+        if problem.col >= 0 {
+            fillRect(screen, float64(playfieldX+problem.col*blockSize), float64(playfieldY+problem.row*blockSize), float64(blockSize), float64(blockSize), editorProblemColor)
+        }
+    }
+
+    // the cursor, and where the mirrored tile goes
+    if col >= 0 {
+        fillRect(screen, float64(playfieldX+col*blockSize), float64(playfieldY+row*blockSize), float64(blockSize), float64(blockSize), editorCursorColor)
+        if editor.isMirrored {
+            mirrorCol := len(editor.maze[row])-1-col
+            fillRect(screen, float64(playfieldX+mirrorCol*blockSize), float64(playfieldY+row*blockSize), float64(blockSize), float64(blockSize), editorCursorColor)
+        }
+    }
+
+    // the bottom bar: the brush and the size of the maze, then a message or the problem under the mouse
+    _, textHeight := measureText("0")
+    statusY := screenSizeY-hudBottomHeight+(hudBottomHeight-textHeight*2)/2
+    status := fmt.Sprintf("%s  %dx%d  T: TEST  CTRL+S: SAVE", editorBrushes[editor.brush].name, len(editor.maze[0]), len(editor.maze))
+    drawText(screen, status, blockSize/2, statusY, clr)
+    message := editor.getProblemAt(col, row)
+    if editor.messageTimer > 0 {
+        message = editor.message
+    } else if message != "" {
+        message = fmt.Sprintf("%d PROBLEMS: %s", len(editor.problems), message)
+    }
+    drawText(screen, message, blockSize/2, statusY+textHeight, clr)
+}
+
+/*
+    Function: drawTile
+    Draw a tile of the maze at a position of the screen. Walls are filled with the wall color, the starts of the players
+    are PacMen in the color of the player and unknown tiles are shown as their character
+    Inputs: screen, tile and the position
+*/
+func (editor *Editor) drawTile(screen *Image, tile byte, x int, y int) {
+    switch tile {
+    case '0':
+        // the wall color of the maze file is used, unless the theme has a wall color (like in the game)
+        wallColor := getWallColor(1)
+        if _, ok := getPaletteColor("wall"); !ok && editor.header.wallColor.A != 0 {
+            wallColor = editor.header.wallColor
+        }
+        fillRect(screen, float64(x), float64(y), float64(blockSize), float64(blockSize), wallColor)
+    case ' ':
+    case 'P', '1', '2', '3', '4':
+        player := 0
+        if tile != 'P' {
+            player = int(tile-'1')
+        }
+        sprite := *editor.tiles['P']
+        sprite.tint = playerColors[player%len(playerColors)]
+        sprite.x = float64(x-playfieldX)
+        sprite.y = float64(y-playfieldY)
+        drawSprite(screen, &sprite)
+    default:
+        sprite, ok := editor.tiles[tile]
+        if !ok {
+            drawText(screen, string(tile), x+blockSize/4, y, getTextColor())
+            return
+        }
+        drawSprite(screen, &Sprite{img: sprite.img, x: float64(x-playfieldX), y: float64(y-playfieldY), visibility: true})
+    }
+}
+
+/*
+    Function: runEditor
+    Open a maze file in the editor and run it in a window
+    Input: maze file
+*/
+func runEditor(fileName string) {
+    currentTheme = selectedTheme
+    gameFont = loadFont(getAsset("font"))
+    mazeEditor = newEditor(fileName)
+    mazeEditor.setScreenSize()
+
+    err := runWindow(updateEditor, screenSizeX, screenSizeY, 1.5, "Simple PacMan Maze Editor")
+    if err != nil {
+        log.Fatal(err)
+    }
+}
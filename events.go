@@ -0,0 +1,43 @@
+package main
+
+/*
+    This file contains the game events.
+
+    When something happens in the game (PacMan eats food, loses a life, ...) the game emits an event.
+    Other parts of the game (like sounds) listen to the events, so the game logic doesn't need to know about them.
+*/
+
+// Let's define the names of the game events
+const (
+    eventFoodEaten = "foodEaten" // PacMan ate a food
+    eventPelletEaten = "pelletEaten" // PacMan ate a power pellet
+    eventFruitEaten = "fruitEaten" // PacMan ate the fruit
+    eventEnemyEaten = "enemyEaten" // PacMan ate a frightened enemy
+    eventDeath = "death" // an enemy caught PacMan
+    eventExtraLife = "extraLife" // PacMan got an extra life
+    eventLevelClear = "levelClear" // PacMan ate all the food of the level
+    eventGameOver = "gameOver" // PacMan has no lives left
+)
+
+// Variable to hold the functions which are called when an event is emitted
+var eventListeners []func(event string)
+
+/*
+    Function: addEventListener
+    Add a function to be called on every game event
+    Input: function which gets the name of the event
+*/
+func addEventListener(listener func(event string)) {
+    eventListeners = append(eventListeners, listener)
+}
+
+/*
+    Function: emitEvent
+    Let all the listeners know that an event happened in the game
+    Input: name of the event
+*/
+func emitEvent(event string) {
+    for _, listener := range eventListeners {
+        listener(event)
+    }
+}
@@ -0,0 +1,124 @@
+package main
+
+/*
+    This file contains the reading of the files of the game (mazes, images, fonts, sounds and themes).
+
+    Files are read through file systems (fs.FS), so they can come from different places. A file is looked for in:
+    1. the level pack which is played (a zip file, see pack.go), so a pack can also replace the default files
+    2. the disk, relative to the folder the game is started in
+    3. the default files embedded in the game, so the game also starts from another folder
+    Absolute paths (and paths going out of the folder with ..) are always read from the disk.
+*/
+import (
+    "embed"
+    "errors"
+    "image"
+    _ "image/png"
+    "io/fs"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+)
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's embed the default files of the game: the images, the mazes of the levels, the sounds and the themes
+//go:embed assets maze01.txt maze02.txt sounds.json themes
+var embeddedFiles embed.FS
+
+// Variable to hold the files of the level pack which is played (nil when the classic levels are played)
+var packFiles fs.FS
+
+/*
+    #############################
+    ## Functions to read files ##
+    #############################
+*/
+
+/*
+    Function: getFileSystems
+    Get the file systems a file is looked for in, in order: the level pack, the disk and the embedded files
+*/
+func getFileSystems() []fs.FS {
+    fileSystems := []fs.FS{}
+    if packFiles != nil {
+        fileSystems = append(fileSystems, packFiles)
+    }
+    return append(fileSystems, os.DirFS("."), embeddedFiles)
+}
+
+/*
+    Function: openGameFile
+    Open a file of the game from the first file system which has it (see getFileSystems)
+    Input: path to the file
+*/
+func openGameFile(fileName string) (fs.File, error) {
+    // file systems take paths with / inside the folder, other paths are read from the disk
+    path := filepath.ToSlash(filepath.Clean(fileName))
+    if !fs.ValidPath(path) {
+        return os.Open(fileName)
+    }
+
+    for _, fileSystem := range getFileSystems() {
+        file, err := fileSystem.Open(path)
+        if err == nil {
+            return file, nil
+        }
+        // a file which is there but can't be read is an error, it's not looked for in the next file system
+        if !errors.Is(err, fs.ErrNotExist) {
+            return nil, err
+        }
+    }
+    return nil, &fs.PathError{Op: "open", Path: fileName, Err: fs.ErrNotExist}
+}
+
+/*
+    Function: readGameFile
+    Read the whole content of a file of the game (see openGameFile)
+    Input: path to the file
+*/
+func readGameFile(fileName string) ([]byte, error) {
+    file, err := openGameFile(fileName)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    return ioutil.ReadAll(file)
+}
+
+/*
+    Function: statGameFile
+    Get the information of a file of the game (Ex: to know if it's a folder), see openGameFile
+    Input: path to the file
+*/
+func statGameFile(fileName string) (fs.FileInfo, error) {
+    file, err := openGameFile(fileName)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    return file.Stat()
+}
+
+/*
+    Function: loadGameImage
+    Load an image file of the game as an ebiten image (see openGameFile)
+    Input: path to the image file
+*/
+func loadGameImage(fileName string) (*Image, error) {
+    file, err := openGameFile(fileName)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    img, _, err := image.Decode(file)
+    if err != nil {
+        return nil, err
+    }
+    return newImageFromImage(img)
+}
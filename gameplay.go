@@ -0,0 +1,204 @@
+package main
+
+/*
+    This file contains the rules of the game play: the lives and the points of the players, the frightened enemies,
+    the fruit and the time everyone waits before moving.
+*/
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+// Let's define the number of lives PacMan has when a new game starts
+var startingLives = 3
+
+// Let's define the points PacMan gets for eating things
+var foodPoints = 10
+var pelletPoints = 50
+var enemyPoints = 200
+var fruitPoints = 100 // this is multiplied by the level
+
+// Let's define the points needed for each extra life
+var extraLifePoints = 3000
+
+// Let's define the number of food eaten when the fruit shows up, and how long (frames) it stays
+var fruitAtFood = []int{70, 170}
+var fruitTime = 600
+
+// Let's define how long (frames) PacMan and enemies wait before moving at the start and after losing a life
+var freezeTime = 90
+
+
+/*
+    ############################
+    ## Rules of the game play ##
+    ############################
+*/
+/*
+    Function: addPoints
+    Add points to a player and give an extra life when the player reaches enough points
+    Inputs: player (position in gameInfo.stats) and the points to add
+*/
+func addPoints(player int, points int) {
+    stats := &gameInfo.stats[player]
+    stats.points = stats.points+points
+
+    if stats.points >= stats.nextExtraLife {
+        stats.lives = stats.lives+1
+        stats.nextExtraLife = stats.nextExtraLife+extraLifePoints
+        emitEvent(eventExtraLife)
+    }
+    updateHighScore()
+}
+
+/*
+    Function: isPacmanAlive
+    Check if a PacMan is still in the game (the player has lives left)
+    Input: reference to a PacMan game object
+*/
+func isPacmanAlive(pacman *Sprite) bool {
+    return gameInfo.stats[pacman.player].lives > 0
+}
+
+/*
+    Function: frightenEnemies
+    Make all the enemies frightened for a while (after PacMan eats a power pellet)
+*/
+func frightenEnemies() {
+    gameInfo.frightenedTimer = adaptFrightenedTime(LEVELS[gameInfo.level].frightenedTime)
+    for _, enemy := range enemies {
+        enemy.isFrightened = true
+        enemy.img = enemy.faces['F']
+    }
+}
+
+/*
+    Function: calmEnemy
+    Make an enemy not frightened anymore
+    Input: reference to a enemy game object
+*/
+func calmEnemy(enemy *Sprite) {
+    enemy.isFrightened = false
+    enemy.img = enemy.faces['N']
+}
+
+/*
+    Function: eatEnemy
+    PacMan eats a frightened enemy. The enemy comes back at a new place
+    Inputs: reference to a enemy game object and the PacMan who ate it
+*/
+func eatEnemy(enemy *Sprite, pacman *Sprite) {
+    addPoints(pacman.player, enemyPoints)
+    calmEnemy(enemy)
+    placeEnemy(enemy)
+    emitEvent(eventEnemyEaten)
+}
+
+/*
+    Function: loseLife
+    PacMan is caught by an enemy. PacMan loses a life and everyone goes back to start, or the game is over if no lives left
+    Input: reference to the PacMan game object which is caught
+*/
+func loseLife(pacman *Sprite) {
+    gameInfo.stats[pacman.player].lives = gameInfo.stats[pacman.player].lives-1
+    emitEvent(eventDeath)
+
+    // in the co-op mode, a PacMan without lives leaves the maze and the others go on
+    if !isPacmanAlive(pacman) {
+        pacman.visibility = false
+    }
+
+    if getAlivePacmanCount() == 0 {
+        // in the two player mode, the game goes on while the other player has lives
+        if changePlayer() {
+            return
+        }
+        gameInfo.isGameOver = true
+        saveHighScore()
+        emitEvent(eventGameOver)
+        return
+    }
+
+    // in the two player mode, the other player gets the turn
+    if numPlayers > 1 && changePlayer() {
+        return
+    }
+
+    // let's put the PacMen back to the start
+    for _, pacman := range pacmen {
+        pacman.x = pacman.startX
+        pacman.y = pacman.startY
+        pacman.direction = 'I'
+        pacman.img = pacman.faces['I']
+    }
+
+    // let's place the enemies again and calm them down
+    gameInfo.frightenedTimer = 0
+    for _, enemy := range enemies {
+        calmEnemy(enemy)
+        placeEnemy(enemy)
+    }
+
+    // let's wait a bit before everyone starts moving again
+    gameInfo.freezeTimer = freezeTime
+}
+
+/*
+    Function: updateTimers
+    Count down the timers of the game on each frame
+*/
+func updateTimers() {
+    // when the frightened time is over, let's calm down the enemies
+    if gameInfo.frightenedTimer > 0 {
+        gameInfo.frightenedTimer = gameInfo.frightenedTimer-1
+        if gameInfo.frightenedTimer == 0 {
+            for _, enemy := range enemies {
+                calmEnemy(enemy)
+            }
+        }
+    }
+
+    // when the fruit time is over, let's hide the fruit
+    if gameInfo.fruitTimer > 0 {
+        gameInfo.fruitTimer = gameInfo.fruitTimer-1
+        if gameInfo.fruitTimer == 0 {
+            fruit.visibility = false
+        }
+    }
+}
+
+/*
+    Function: placeEnemy
+    Place an enemy on a random spawn point of the maze, or on a random food (a random point on a movable path) if the maze
+    has no spawn points, and give it a direction to move
+    The enemy is not placed too close to PacMan, so PacMan is not caught as soon as the enemy shows up
+    Input: reference to a enemy game object
+*/
+func placeEnemy(enemy *Sprite) {
+    // the places the enemy can be placed at
+    places := enemySpawns
+    if len(places) == 0 {
+        for _, dot := range food {
+            places = append(places, []float64{dot.x, dot.y})
+        }
+    }
+
+    // get random place. The game has its own random numbers (see random.go)
+    randomPlace := places[randomInt(len(places))]
+
+    // let's try a few times to find a place which is far enough from the PacMen
+    for i := 0; i < 10 && isNearPacman(randomPlace[0], randomPlace[1], float64(blockSize*5)); i++ {
+        randomPlace = places[randomInt(len(places))]
+    }
+
+    // Let's mark the location of the enemy at the random place. This way we can place enemies at random points in a movable path
+    enemy.x = randomPlace[0]
+    enemy.y = randomPlace[1]
+
+    // Let's also give an initial direction for the enemy to move
+    // For this we need to get the grid point which this enemy is getting placed
+    colPlace, rowPlace := getMazePointFromPosition(randomPlace[0], randomPlace[1])
+    // Now get a possible movable direction at that grid cell
+    enemy.direction = getMovableDirection(colPlace, rowPlace, enemy.direction)
+}
@@ -0,0 +1,272 @@
+package main
+
+/*
+    This file contains the code which draws the HUD (Heads Up Display) of the game.
+
+    The HUD has two bars outside the maze (playfield)
+    Top bar   : score, high score and level
+    Bottom bar: lives left, collected fruit and the timers of active power-ups
+*/
+import (
+    "image/color"
+    "io/ioutil"
+    "log"
+    "math"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// Let's have variables to define the height of the top and bottom bars of the HUD
+var hudTopHeight = blockSize*2
+var hudBottomHeight = blockSize*2
+
+// Let's have a variable to define the file which keeps the high score between games
+var highScoreFile = "highscore.txt"
+
+// Variable to hold the highest score reached
+var highScore int
+
+// Let's have a variable to define the color of the power-up timer bars
+var powerUpBarColor = color.RGBA{R: 33, G: 33, B: 222, A: 255}
+
+/*
+    Function: loadHighScore
+    Read the high score from the high score file. If the file is not there yet, the high score is 0
+*/
+func loadHighScore() {
+    content, err := ioutil.ReadFile(highScoreFile)
+    if os.IsNotExist(err) {
+        return
+    }
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    highScore, err = strconv.Atoi(strings.TrimSpace(string(content)))
+    if err != nil {
+        log.Fatal(err)
+    }
+}
+
+/*
+    Function: saveHighScore
+    Write the high score to the high score file
+*/
+func saveHighScore() {
+    err := ioutil.WriteFile(highScoreFile, []byte(strconv.Itoa(highScore)+"\n"), 0644)
+    if err != nil {
+        log.Println(err)
+    }
+}
+
+/*
+    Function: updateHighScore
+    Update the high score if the points of any player of the current game are higher
+*/
+func updateHighScore() {
+    // the points of the demo and of a maze tested in the editor don't count
+    if isDemo || isEditorPlaying() {
+        return
+    }
+    for _, stats := range gameInfo.stats {
+        if stats.points > highScore {
+            highScore = stats.points
+        }
+    }
+}
+
+/*
+    Function: drawImageAt
+    Draw an image on the screen at the given screen position (without moving it into the playfield)
+    Inputs: screen, image and the position
+*/
+func drawImageAt(screen *Image, img *Image, x float64, y float64) {
+    opts := &DrawImageOptions{}
+    opts.GeoM.Translate(x, y)
+    screen.DrawImage(img, opts)
+}
+
+/*
+    Function: drawHUD
+    Draw the top and the bottom bars of the HUD
+    Input: screen
+*/
+func drawHUD(screen *Image) {
+    clr := getTextColor()
+    _, textHeight := measureText("0")
+
+    // Top bar: labels on the first line, values on the second line
+    labelY := (hudTopHeight-textHeight*2)/2
+    valueY := labelY+textHeight
+    left := blockSize
+    right := screenSizeX-blockSize
+
+    // in the co-op mode, the score and the lives of each player are shown next to each other
+    if len(gameInfo.stats) > 1 {
+        drawCoopHUD(screen, labelY, valueY)
+        return
+    }
+
+    // in the versus mode, the round and the match points of the two players are shown
+    if isVersus {
+        drawVersusHUD(screen, labelY, valueY)
+    } else {
+        // in the two player mode, the score of the player who is playing is shown
+        scoreLabel := "SCORE"
+        if numPlayers > 1 {
+            scoreLabel = getPlayerLabel()
+        }
+        drawText(screen, scoreLabel, left, labelY, clr)
+        drawText(screen, strconv.Itoa(gameInfo.stats[0].points), left, valueY, clr)
+
+        drawTextCentered(screen, "HIGH SCORE", screenSizeX/2, labelY, clr)
+        drawTextCentered(screen, strconv.Itoa(highScore), screenSizeX/2, valueY, clr)
+
+        drawTextRight(screen, "LEVEL", right, labelY, clr)
+        drawTextRight(screen, strconv.Itoa(gameInfo.level), right, valueY, clr)
+    }
+
+    // Bottom bar
+    bottomY := float64(screenSizeY-hudBottomHeight)+float64(hudBottomHeight-blockSize)/2.0
+
+    // lives left. The life which is being played is not shown (like the original Pac-Man)
+    if lifeImg := pacmen[0].faces['L']; lifeImg != nil {
+        for i := 0; i < gameInfo.stats[0].lives-1; i++ {
+            drawImageAt(screen, lifeImg, float64(left+i*(blockSize+2)), bottomY)
+        }
+    }
+
+    // collected fruit, from right to left
+    if fruit.img != nil {
+        for i := 0; i < gameInfo.fruits; i++ {
+            drawImageAt(screen, fruit.img, float64(right-(i+1)*(blockSize+2)), bottomY)
+        }
+    }
+
+    drawPowerUps(screen, screenSizeX/2, bottomY)
+}
+
+/*
+    Function: drawCoopHUD
+    Draw the HUD of the co-op mode
+    Top bar has the score of each player (in the color of the player), the high score and the level
+    Bottom bar has the lives of each player under their score and the power-up timers under the high score
+    Inputs: screen and the positions of the labels and the values of the top bar
+*/
+func drawCoopHUD(screen *Image, labelY int, valueY int) {
+    clr := getTextColor()
+
+    // the high score and the level take the first column, each player gets one of the other columns
+    columnWidth := screenSizeX/(len(gameInfo.stats)+1)
+    drawText(screen, "HI "+strconv.Itoa(highScore), blockSize, labelY, clr)
+    drawText(screen, "LEVEL "+strconv.Itoa(gameInfo.level), blockSize, valueY, clr)
+
+    bottomY := float64(screenSizeY-hudBottomHeight)+float64(hudBottomHeight-blockSize)/2.0
+    _, textHeight := measureText("0")
+    for i, stats := range gameInfo.stats {
+        x := columnWidth*(i+1)
+        playerClr := clr
+        if tint := playerColors[i%len(playerColors)]; tint.A != 0 {
+            playerClr = tint
+        }
+        drawText(screen, "P"+strconv.Itoa(i+1), x, labelY, playerClr)
+        drawText(screen, strconv.Itoa(stats.points), x, valueY, clr)
+
+        // a single life icon in the color of the player and the number of lives left
+        if i < len(pacmen) {
+            if lifeImg := pacmen[i].faces['L']; lifeImg != nil {
+                opts := &DrawImageOptions{}
+                opts.GeoM.Translate(float64(x), bottomY)
+                if pacmen[i].tint.A != 0 {
+                    opts.ColorM.Scale(float64(pacmen[i].tint.R)/255.0, float64(pacmen[i].tint.G)/255.0, float64(pacmen[i].tint.B)/255.0, 1)
+                }
+                screen.DrawImage(lifeImg, opts)
+            }
+        }
+        drawText(screen, "x"+strconv.Itoa(stats.lives), x+blockSize+2, int(bottomY)+(blockSize-textHeight)/2, clr)
+    }
+
+    drawPowerUps(screen, blockSize+columnWidth/2, bottomY)
+}
+
+/*
+    Function: drawVersusHUD
+    Draw the top bar of the versus mode
+    Each player has the role in this round and the match points (dots and catches), and the round is in the middle
+    Inputs: screen and the positions of the labels and the values of the top bar
+*/
+func drawVersusHUD(screen *Image, labelY int, valueY int) {
+    clr := getTextColor()
+    left := blockSize
+    right := screenSizeX-blockSize
+
+    drawText(screen, getVersusRole(0), left, labelY, clr)
+    drawText(screen, getVersusPointsLabel(0), left, valueY, clr)
+
+    drawTextCentered(screen, "ROUND", screenSizeX/2, labelY, clr)
+    drawTextCentered(screen, strconv.Itoa(versusRound)+"/"+strconv.Itoa(versusRounds), screenSizeX/2, valueY, clr)
+
+    drawTextRight(screen, getVersusRole(1), right, labelY, clr)
+    drawTextRight(screen, getVersusPointsLabel(1), right, valueY, clr)
+}
+
+/*
+    Function: getVersusPointsLabel
+    Get the match points of a player with the dots and catches they are made of. Ex: 230 (180+1C)
+    Input: player (0 or 1)
+*/
+func getVersusPointsLabel(player int) string {
+    score := versusScores[player]
+    return strconv.Itoa(getVersusPoints(player))+" ("+strconv.Itoa(score.dots)+"+"+strconv.Itoa(score.catches)+"C)"
+}
+
+/*
+    Function: drawPowerUps
+    Draw the timers of the active power-ups, as a label and a bar which gets shorter as time runs out
+    They are placed next to each other around the given center of the bottom bar
+    Inputs: screen, center (x) of the timers and the position of the bottom bar
+*/
+func drawPowerUps(screen *Image, centerX int, bottomY float64) {
+    clr := getTextColor()
+    _, textHeight := measureText("0")
+    powerUps := getActivePowerUps()
+    barWidth := blockSize*4
+    totalWidth := 0
+    for _, powerUp := range powerUps {
+        labelWidth, _ := measureText(powerUp.name)
+        totalWidth = totalWidth+labelWidth+4+barWidth+blockSize
+    }
+    x := centerX-totalWidth/2
+    for _, powerUp := range powerUps {
+        labelWidth, _ := measureText(powerUp.name)
+        drawText(screen, powerUp.name, x, int(bottomY)+(blockSize-textHeight)/2, clr)
+        x = x+labelWidth+4
+        fillRect(screen, float64(x), bottomY+float64(blockSize)/4.0, float64(barWidth)*powerUp.left, float64(blockSize)/2.0, powerUpBarColor)
+        x = x+barWidth+blockSize
+    }
+}
+
+// Structure to hold an active power-up to show on the HUD
+type PowerUpTimer struct {
+    name string // holds the name shown next to the timer
+    left float64 // holds the amount of time left, from 1 (just started) to 0 (finished)
+}
+
+/*
+    Function: getActivePowerUps
+    Get the power-ups which are active at the moment
+*/
+func getActivePowerUps() []PowerUpTimer {
+    powerUps := []PowerUpTimer{}
+    if gameInfo.frightenedTimer > 0 {
+        // the frightened time is changed by the adaptive difficulty (see frightenEnemies). A timer from another
+        // difficulty (Ex: a continued game) can be longer, so the bar is never longer than full
+        frightenedTime := adaptFrightenedTime(LEVELS[gameInfo.level].frightenedTime)
+        powerUps = append(powerUps, PowerUpTimer{
+            name: "POWER",
+            left: math.Min(float64(gameInfo.frightenedTimer)/float64(frightenedTime), 1),
+        })
+    }
+    return powerUps
+}
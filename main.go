@@ -2,18 +2,14 @@ package main
 
 /*
 Let's import the required packages
-Except "github.com/hajimehoshi/ebiten", all the other packages are default packages that comes with Go Language
 */
 import (
     "bufio"
-    "github.com/hajimehoshi/ebiten"
-    "github.com/hajimehoshi/ebiten/ebitenutil"
+    "flag"
+    "fmt"
+    "image/color"
 	"log"
 	"math"
-	"math/rand"
-    "os"
-    "strconv"
-    "time"
 )
 
 /*
@@ -30,6 +26,18 @@ type GameInfo struct {
     isGameOver bool // when the game is over (enemy eat PacMan), this flag is set to true
     isLevelComplete bool // when the level is completed (PacMan eat all food), this flag is set to true
     maze []string // holds the maze file as string array, each string is a row. each character in the string is a column
+    stats []PlayerStats // holds the lives and points of each PacMan in the maze (more than one only in the co-op mode)
+    fruits int // holds the number of fruits collected in the whole game
+    frightenedTimer int // holds the number of frames left until enemies stop being frightened (after a power pellet)
+    fruitTimer int // holds the number of frames left until the fruit disappears from the maze
+    freezeTimer int // holds the number of frames PacMan and enemies wait before moving (after starting or losing a life)
+}
+
+// Structure which keeps the lives and points of a player. These are kept from level to level
+type PlayerStats struct {
+    lives int // holds the number of lives PacMan has left (including the one being played)
+    points int // holds the points of the whole game (food, power pellets, enemies and fruit eaten)
+    nextExtraLife int // holds the points PacMan should reach to get the next extra life
 }
 
 // Structure which keeps information about a level
@@ -38,17 +46,27 @@ type LevelInfo struct {
     enemySpeed float64 // holds the speed of an enemy on a level
     numEnemies int // holds the number of elements which should loaded into a level
     mazeFile string // holds the path to the file containing the maze on a level
+    wallColor color.RGBA // holds the color of the maze walls on a level (default wall color is used if not given)
+    theme string // holds the theme of a level (the theme given with -theme flag is used if not given)
+    frightenedTime int // holds the number of frames enemies stay frightened after PacMan eats a power pellet
+    enemyAggression float64 // holds how often enemies chase the nearest PacMan at a junction, from 0 (never, random moves) to 1 (always)
 }
 
 // Structure to hold information about a single game object
 type Sprite struct {
-    img *ebiten.Image // holds the image displayed as the game object
-    faces map[byte]*ebiten.Image // holds the images of different faces/animations of this sprite. This is a map structure
+    img *Image // holds the image displayed as the game object
+    faces map[byte]*Image // holds the images of different faces/animations of this sprite. This is a map structure
 	visibility bool // holds if the game object is visible or not
 	x float64 // holds the x position of the game object in the screen
 	y float64 // holds the y position of the game object in the screen
 	speed float64 // holds the speed of moving game objects (used for PacMan and enemies)
 	direction byte // holds the current moving direction of moving game objects (U=UP, R=RIGHT, D=DOWN, L=LEFT)
+	startX float64 // holds the x position where the game object starts (PacMan goes back here after losing a life)
+	startY float64 // holds the y position where the game object starts
+	isFrightened bool // when the enemy is frightened by a power pellet (PacMan can eat it), this flag is set to true
+	player int // holds the player of a PacMan, which is the position of the player's lives and points in gameInfo.stats
+	aggression float64 // holds how often an enemy chases the nearest PacMan at a junction (see LevelInfo)
+	tint color.RGBA // holds a color to paint the image with when drawing (used to tell PacMen apart in the co-op mode)
 }
 
 /*
@@ -58,9 +76,14 @@ type Sprite struct {
 */
 
 // Let's have variables to hold the Size of screen window of the game
+// These are set when the game starts to fit the largest maze and the HUD (see setScreenSize)
 var screenSizeX = 420
 var screenSizeY = 360
 
+// Let's have variables to hold the position of the maze (playfield) on the screen. The maze is drawn below the top bar of the HUD
+var playfieldX = 0
+var playfieldY = 0
+
 // Let's have a variable to define the size of a single game block (cell)
 var blockSize = 15
 
@@ -71,27 +94,39 @@ var LEVELS = map[int]LevelInfo {
         enemySpeed: 2,
         numEnemies: 4,
         mazeFile: "maze01.txt",
+        wallColor: color.RGBA{R: 33, G: 33, B: 222, A: 255},
+        frightenedTime: 360,
+        enemyAggression: 0.3,
     },
     2: LevelInfo{
         pacmanSpeed: 2,
         enemySpeed: 3,
         numEnemies: 5,
         mazeFile: "maze02.txt",
+        wallColor: color.RGBA{R: 222, G: 151, B: 81, A: 255},
+        frightenedTime: 240,
+        enemyAggression: 0.5,
     },
 }
 
+// Variable to know if the start screen is for a new game (the number of players can be chosen) or for the next level
+var isNewGame = true
+
 // Variable to hold Game Info
 var gameInfo GameInfo
 
-// Variable to hold the main game object, THE PACMAN!!!
-var pacman Sprite
+// Variable to hold the main game objects, THE PACMEN!!! There is one PacMan, unless it's the co-op mode (see coop.go)
+var pacmen []*Sprite
 
-// Variable to hold maze wall square pieces
+// Variable to hold maze wall pieces
 var mazeWall []*Sprite
 
 // Variable to hold food
 var food []*Sprite
 
+// Variable to hold the places where enemies are spawned (E in the maze). Enemies are spawned at random food if the maze has none
+var enemySpawns [][]float64
+
 // Variable to keep references to the still objects (wall and food) in order to fast access them. Note that this is a multi-dimensional array having same shape as the maze
 var mazeSprites [][]*Sprite
 
@@ -100,6 +135,9 @@ var enemies []*Sprite
 
 // Note that all the arrays above are initialized with * (pointers) to keep only the reference. Otherwise a copy of the object will be created when accessing elements inside them
 
+// Variable to hold the fruit, which shows up at PacMan's starting point for a while
+var fruit Sprite
+
 
 /*
     Variables to hold popups
@@ -110,6 +148,10 @@ var levelComplete Sprite
 var win Sprite
 var startLogo Sprite
 
+// Variable to know if the game is shown in a window. Images are only created for a window, the game runs without them
+// headless, in the terminal and in the modes which don't open a window
+var hasWindow = false
+
 /*
     ###############################################
     ## Functions to read files (maze and assets) ##
@@ -118,20 +160,72 @@ var startLogo Sprite
 
 /*
     Function: readMazeFile
-    Read file which containing the maze information
-    Inputs: path to the file
-    Outputs an array containing the rows of the maze, each row as a string
+    Read file which containing the maze information. The game can't go on without its maze, so an error stops the game
+    Inputs: path to the file (a text maze file, a Tiled map or a PNG pixel maze)
+    Outputs the maze with the information of its header and the rows of the grid, each row as a string (see maze.go)
+*/
+func readMazeFile(fileName string) Maze {
+    maze, err := loadMazeFile(fileName)
+    if err != nil {
+        log.Fatal(err)
+    }
+    return maze
+}
+
+/*
+    Function: loadMazeFile
+    Read file which containing the maze information, like readMazeFile, and give back the error if it can't be read
+    or it can't be played (see checkMaze). Ex: to check the mazes of a level pack before playing it
+    Inputs: path to the file (a text maze file, a Tiled map or a PNG pixel maze)
+*/
+func loadMazeFile(fileName string) (Maze, error) {
+    maze, err := openMazeFile(fileName)
+    if err != nil {
+        return maze, err
+    }
+    if err := checkMaze(maze); err != nil {
+        return maze, fmt.Errorf("%s: %v", fileName, err)
+    }
+    return maze, nil
+}
+
+/*
+    Function: openMazeFile
+    Read file which containing the maze information, without checking it can be played
+    (Ex: the editor opens a maze with problems to fix them)
+    Inputs: path to the file (a text maze file, a Tiled map or a PNG pixel maze)
 */
-func readMazeFile(fileName string) []string {
-    // create an empty array to hold the maze
-    maze := []string{}
+func openMazeFile(fileName string) (Maze, error) {
+    // a Tiled map is changed to a maze by the importer (see tiled.go). The things of the map it ignores are logged
+    if isTiledMapFile(fileName) {
+        maze, warnings, err := readTiledMapFile(fileName)
+        for _, warning := range warnings {
+            log.Println(fileName+":", warning)
+        }
+        if err != nil {
+            return maze, fmt.Errorf("%s: %v", fileName, err)
+        }
+        return maze, nil
+    }
+
+    // a PNG image is a pixel maze, its colors are changed to tiles with the palette (see pixelmaze.go)
+    if isPixelMazeFile(fileName) {
+        maze, err := readPixelMazeFile(fileName)
+        if err != nil {
+            return maze, fmt.Errorf("%s: %v", fileName, err)
+        }
+        return maze, nil
+    }
+
+    // create an empty array to hold the lines of the file
+    lines := []string{}
 
-    // Open the file and load bytes into a variable
-    file, err := os.Open(fileName)
+    // Open the file and load bytes into a variable. The file can be in the level pack, on the disk or embedded (see files.go)
+    file, err := openGameFile(fileName)
 
-    // if error occurred while loading the file log it
+    // if error occurred while loading the file give it back
     if err != nil {
-        log.Fatal(err)
+        return Maze{}, err
     }
     // close the file once this method has completely executed
     defer file.Close()
@@ -144,11 +238,19 @@ func readMazeFile(fileName string) []string {
         // get the row as a string
         line := scanner.Text()
 
-        // push each string line to the maze array
-    	maze = append(maze, line)
+        // push each string line to the lines array
+    	lines = append(lines, line)
+    }
+    if err := scanner.Err(); err != nil {
+        return Maze{}, fmt.Errorf("%s: %v", fileName, err)
     }
 
-    return maze
+    // let's read the header and the grid from the lines
+    maze, err := parseMaze(lines)
+    if err != nil {
+        return maze, fmt.Errorf("%s: %v", fileName, err)
+    }
+    return maze, nil
 }
 
 /*
@@ -157,11 +259,21 @@ func readMazeFile(fileName string) []string {
     Inputs: width and height, initial position (x and y)
 */
 func createSprite(imgFile string, width int, height int, x float64, y float64) Sprite {
+    // without a window nothing is drawn, so the sprite has no image
+    if !hasWindow {
+        return Sprite{visibility: true, x: x, y: y, speed: 1}
+    }
+
     // create an empty image with given width and height
-    img, _ := ebiten.NewImage(width, height, ebiten.FilterDefault)
+    img, _ := newImage(width, height)
 
-    // load pacman image from a file
-    imgFromFile, _, err := ebitenutil.NewImageFromFile(imgFile, ebiten.FilterDefault)
+    // load pacman image from a file (from the level pack, the disk or the embedded files, see files.go)
+    imgFromFile, err := loadGameImage(imgFile)
+
+    // log if there's any errors occurred while loading the image
+    if err != nil {
+        log.Fatal(err)
+    }
 
     /*
         Let's resize get the size to resize the image according the given height and width
@@ -172,17 +284,12 @@ func createSprite(imgFile string, width int, height int, x float64, y float64) S
     scaleY := float64(height)/float64(originalHeight)
 
     // Let's set the resizing to ebiten drawing image options
-    opts := &ebiten.DrawImageOptions{}
+    opts := &DrawImageOptions{}
     opts.GeoM.Scale(scaleX, scaleY)
 
     // add loaded image to the empty image with resize options
     img.DrawImage(imgFromFile, opts)
 
-    // log if there's any errors occurred while loading the image
-	if err != nil {
-		log.Fatal(err)
-	}
-
     // return a new Sprite object
 	return Sprite{
 	    img: img,
@@ -255,6 +362,27 @@ func isValidPoint(col int, row int) bool {
     return true
 }
 
+/*
+    Function: isMovableDirection
+    Check if a game object can move from the given maze point in the given direction (there's no wall)
+    Inputs: maze point and the direction: U=UP, R=RIGHT , D=DOWN, L=LEFT
+*/
+func isMovableDirection(col int, row int, direction byte) bool {
+    switch direction {
+    case 'U':
+        row = row-1
+    case 'R':
+        col = col+1
+    case 'D':
+        row = row+1
+    case 'L':
+        col = col-1
+    default:
+        return false
+    }
+    return isValidPoint(col, row) && gameInfo.maze[row][col] != '0'
+}
+
 /*
     Function: getMovableDirection
     Get a movable direction from the given maze point
@@ -293,9 +421,8 @@ func getMovableDirection(col int, row int, currentDirection byte) byte {
         directions['L']=true
     }
 
-    // Let's get a random direction out of all the possible directions. This is the standard way of generating a random integer in Go.
-    rand.Seed(time.Now().UnixNano())
-    direction := possibilities[rand.Intn(len(possibilities))]
+    // Let's get a random direction out of all the possible directions. The game has its own random numbers (see random.go)
+    direction := possibilities[randomInt(len(possibilities))]
 
     // if the direction we get is UP but sprite is moving DOWN and still possible to move DOWN, move it DOWN!
     if direction == 'U' && currentDirection == 'D' && directions['D'] {
@@ -326,15 +453,31 @@ func getMovableDirection(col int, row int, currentDirection byte) byte {
     Render any sprite (Game object) on the screen.
     Inputs: screen and the sprite to render has to be given as arguments
 */
-func drawSprite(screen *ebiten.Image, sprite *Sprite) {
+func drawSprite(screen *Image, sprite *Sprite) {
     if sprite.visibility {
-        opts := &ebiten.DrawImageOptions{}
-        opts.GeoM.Translate(sprite.x, sprite.y)
+        opts := &DrawImageOptions{}
+        // sprite positions are inside the maze, so let's move them to where the maze is drawn on the screen
+        opts.GeoM.Translate(sprite.x+float64(playfieldX), sprite.y+float64(playfieldY))
         // opts.GeoM.Scale(sprite.x, sprite.y)
+
+        // if the sprite has a tint, let's paint the image with that color
+        if sprite.tint.A != 0 {
+            opts.ColorM.Scale(float64(sprite.tint.R)/255.0, float64(sprite.tint.G)/255.0, float64(sprite.tint.B)/255.0, 1)
+        }
         screen.DrawImage(sprite.img, opts)
     }
 }
 
+/*
+    Function: drawPrompt
+    Render a text under a popup, centered to the popup
+    Inputs: screen, the popup sprite and the text
+*/
+func drawPrompt(screen *Image, popup *Sprite, prompt string) {
+    w, h := popup.img.Size()
+    drawTextCentered(screen, prompt, playfieldX+int(popup.x)+w/2, playfieldY+int(popup.y)+h, getTextColor())
+}
+
 /*
     ############################################
     ## Defining behaviours of movable objects ##
@@ -344,13 +487,21 @@ func drawSprite(screen *ebiten.Image, sprite *Sprite) {
 /*
     Function: movePacman
     Move the PacMan on keypress, otherwise keep him idle
+    Input: reference to a PacMan game object
 */
-func movePacman() {
+func movePacman(pacman *Sprite) {
     /*
-        Using ebiten.IsKeyPressed we can check if the given key is pressed at the time of calling this function
-        In below, we are using if else because, we want to make sure that only a single key is functional at a given time.
-        If none of the below conditions satisfied, pacman will be idle in the current position
+        getPlayerInput checks the keys (or the gamepad) of the player of this PacMan at the time of calling this function
+        It gives only a single direction, because we want to make sure that only a single key is functional at a given time.
+        If no direction key is pressed, pacman will be idle in the current position
+        When the player of a networked game is disconnected, the autopilot moves the PacMan instead (see autopilot.go)
+        The autopilot also plays the demo, and helps player 1 when the assist is on and no direction is pressed (see demo.go)
     */
+    controller := getPacmanController(pacman)
+    input := getPlayerInput(controller)
+    if isAIPlayer(controller) || (input == 'I' && isAssisted(controller)) {
+        input = getAutopilotInput(pacman)
+    }
     x := pacman.x
     y := pacman.y
     direction := pacman.direction
@@ -359,22 +510,22 @@ func movePacman() {
     col, row := getMazePointFromPosition(x, y)
     alignedX, alignedY := getPositionFromMazePoint(col, row)
 
-    if ebiten.IsKeyPressed(ebiten.KeyUp) {
+    if input == 'U' {
         // When the "up arrow key" is pressed, let's move the pacman towards north direction from the current position
         y = y-pacman.speed
         x = alignedX
         direction = 'U'
-    } else if ebiten.IsKeyPressed(ebiten.KeyDown) {
+    } else if input == 'D' {
         // When the "down arrow key" is pressed, let's move the pacman towards south direction from the current position
         y = y+pacman.speed
         x = alignedX
         direction = 'D'
-    } else if ebiten.IsKeyPressed(ebiten.KeyLeft) {
+    } else if input == 'L' {
         // When the "left arrow key" is pressed, let's move the pacman towards west direction from the current position
         x = x-pacman.speed
         y = alignedY
         direction = 'L'
-    } else if ebiten.IsKeyPressed(ebiten.KeyRight) {
+    } else if input == 'R' {
         // When the "right arrow key" is pressed, let's move the pacman towards east direction from the current position
         x = x+pacman.speed
         y = alignedY
@@ -402,14 +553,25 @@ func movePacman() {
 /*
     Function: eatFood
     Let PacMan eat food if he's on or passing a food sprite
+    Input: reference to a PacMan game object
 */
-func eatFood() {
+func eatFood(pacman *Sprite) {
     // Let's get the current position of the pacman to map to the maze point
     col, row := getMazePointFromPosition(pacman.x, pacman.y)
 
-    // check the symbol at that point in the maze matching food symbol (i.e. dot)
-    if isValidPoint(col, row) && gameInfo.maze[row][col] == '.' {
-        // player is on a food, remove the . from maze
+    // check the symbol at that point in the maze matching food symbol (i.e. dot) or power pellet symbol (i.e. o)
+    if isValidPoint(col, row) && (gameInfo.maze[row][col] == '.' || gameInfo.maze[row][col] == 'o') {
+        if gameInfo.maze[row][col] == 'o' {
+            // it's a power pellet, let's make the enemies frightened so PacMan can eat them
+            addPoints(pacman.player, pelletPoints)
+            frightenEnemies()
+            emitEvent(eventPelletEaten)
+        } else {
+            addPoints(pacman.player, foodPoints)
+            emitEvent(eventFoodEaten)
+        }
+
+        // player is on a food, remove the food from maze
         gameInfo.maze[row] = gameInfo.maze[row][:col] + " " + gameInfo.maze[row][col+1:]
 
         // make the food invisible from the screen
@@ -417,11 +579,30 @@ func eatFood() {
 
         // increase the player score by 1
         gameInfo.score = gameInfo.score+1
+
+        // let's show the fruit when PacMan has eaten enough food (score starts from 1)
+        for _, foodCount := range fruitAtFood {
+            if gameInfo.score-1 == foodCount {
+                fruit.visibility = true
+                gameInfo.fruitTimer = fruitTime
+            }
+        }
+    }
+
+    // let's check if PacMan is on the fruit
+    colFruit, rowFruit := getMazePointFromPosition(fruit.x, fruit.y)
+    if fruit.visibility && col == colFruit && row == rowFruit {
+        fruit.visibility = false
+        gameInfo.fruitTimer = 0
+        gameInfo.fruits = gameInfo.fruits+1
+        addPoints(pacman.player, fruitPoints*gameInfo.level)
+        emitEvent(eventFruitEaten)
     }
 
     // let's check if user has eat all food. if all food has been eaten, let's complete the level
-    if gameInfo.score >= gameInfo.maxScore {
+    if gameInfo.score >= gameInfo.maxScore && !gameInfo.isLevelComplete {
         gameInfo.isLevelComplete = true
+        emitEvent(eventLevelClear)
     }
 }
 
@@ -437,12 +618,22 @@ func moveEnemy(sprite *Sprite) {
     // current maze point of the enemy
     col, row := getMazePointFromPosition(x, y)
 
-    // current maze point of the pacman
-    colPac, rowPac := getMazePointFromPosition(pacman.x, pacman.y)
-
-    // Let's check if ENEMIE HIT the PACMAN!. If so make game over
-    if col == colPac && row == rowPac{
-        gameInfo.isGameOver = true
+    // Let's check if ENEMIE HIT any of the PACMEN!
+    for _, pacman := range pacmen {
+        // current maze point of the pacman
+        colPac, rowPac := getMazePointFromPosition(pacman.x, pacman.y)
+
+        if isPacmanAlive(pacman) && col == colPac && row == rowPac{
+            if sprite.isFrightened {
+                // the enemy is frightened, so PacMan eats the enemy
+                eatEnemy(sprite, pacman)
+            } else {
+                // PacMan loses a life. If there are no lives left it's game over
+                countVersusCatch(sprite)
+                loseLife(pacman)
+            }
+            return
+        }
     }
 
     // Let's get the aligned position to keep enemy on center of the path
@@ -464,8 +655,17 @@ func moveEnemy(sprite *Sprite) {
     */
     reasonableMoveAmount := math.Floor(float64(blockSize)/2.0)-1.0 // This equation has been taken on trial and error basis. if the block size is 15, reasonable amount is 6.
     if math.Abs(x-alignedX) > reasonableMoveAmount || math.Abs(y-alignedY) > reasonableMoveAmount {
-        // get a movable direction
-        direction = getMovableDirection(col, row, sprite.direction)
+        // the enemy steered by a player (versus mode) turns where the player wants, unless the player is disconnected.
+        // Other enemies chase the nearest PacMan sometimes (how often depends on the level), otherwise get a random movable direction
+        target := getEnemyTarget(sprite)
+        if sprite == versusEnemy && !isAIPlayer(getVersusEnemyPlayer()) {
+            direction = getVersusEnemyDirection(col, row, sprite.direction)
+        } else if target != nil && !sprite.isFrightened && randomFloat() < sprite.aggression {
+            colTarget, rowTarget := getMazePointFromPosition(target.x, target.y)
+            direction = getChaseDirection(col, row, sprite.direction, colTarget, rowTarget)
+        } else {
+            direction = getMovableDirection(col, row, sprite.direction)
+        }
     }
     sprite.direction = direction
 
@@ -487,6 +687,9 @@ func moveEnemy(sprite *Sprite) {
 }
 
 
+
+
+
 /*
     ########################################
     ## Functions to initialize properties ##
@@ -500,8 +703,10 @@ func moveEnemy(sprite *Sprite) {
 
     Each character meaning in the maze:
     P - location of the player
+    1 to 4 - location of the players 1 to 4 in the co-op mode (P is the same as 1)
     0 - location of a wall piece
     . - Location of a food piece (PacMan can move only through dots)
+    o - Location of a power pellet (PacMan can eat enemies for a while after eating it)
     E - Enemy which eats the PacMan
 
 */
@@ -509,11 +714,25 @@ func locateGameObjects() {
     // initialize the variable to store pieces of wall with an empty array
     mazeWall = []*Sprite{}
 
+    // initialize the variable to store the PacMen with an empty array
+    pacmen = []*Sprite{}
+
+    // initialize the variable to store the start position of each player (start positions in the maze are P or 1 to 4)
+    starts := map[int][]float64{}
+
+    // the maximum score is counted from the food in the maze
+    gameInfo.maxScore = 1
+
     // initialize the variable to store enemies with an empty array
     enemies = []*Sprite{}
 
     // initialize the variable to store enemies with an empty array
     food = []*Sprite{}
+    enemySpawns = [][]float64{}
+
+    // initialize the wall tiles, so the tiles are created again with the wall color and the wallTiles image of this level
+    wallTiles = map[int]*Image{}
+    wallTilesImage = nil
 
     // initialize multi dimensional array to hold still element references
     mazeSprites = make([][]*Sprite, len(gameInfo.maze))
@@ -530,36 +749,39 @@ func locateGameObjects() {
 		    // Let's check each character and place corresponding objects to that places
 			switch char {
             case '0':
-                // create a Wall block for 0 point in maze and mark position to the corresponding grid cell
-                wall := createSprite("assets/wall.png", blockSize, blockSize, x, y)
+                // create a Wall piece for 0 point in maze and mark position to the corresponding grid cell
+                // the piece is picked by looking at the neighbouring walls (see walls.go)
+                wall := createWallSprite(col, row, x, y)
                 // let's store the wall block Sprite reference in the mazeWall array
                 mazeWall = append(mazeWall, &wall)
 
                 // let's store the wall block Sprite reference in the maze grid matrix as well to quickly get the object
                 mazeSprites[row][col] = &wall
-			case 'P':
-			    // create the PacMan and mark position to the corresponding grid cell
-				pacman = createSprite("assets/pacman.png", blockSize, blockSize, x, y)
-
-				// now let's load the other faces of pacman
-				UP_SPRITE := createSprite("assets/pacmanU.png", blockSize, blockSize, x, y)
-				RIGHT_SPRITE := createSprite("assets/pacmanR.png", blockSize, blockSize, x, y)
-				DOWN_SPRITE := createSprite("assets/pacmanD.png", blockSize, blockSize, x, y)
-				LEFT_SPRITE := createSprite("assets/pacmanL.png", blockSize, blockSize, x, y)
-				IDLE_SPRITE := createSprite("assets/pacmanI.png", blockSize, blockSize, x, y)
-
-                pacman.faces = map[byte]*ebiten.Image{
-                    'U': UP_SPRITE.img,
-                    'R': RIGHT_SPRITE.img,
-                    'D': DOWN_SPRITE.img,
-                    'L': LEFT_SPRITE.img,
-                    'I': IDLE_SPRITE.img,
+			case 'P', '1', '2', '3', '4':
+			    // let's remember where the player starts. P is the start of the first player, the same as 1
+			    player := 0
+			    if char != 'P' {
+			        player = int(char-'1')
+			    }
+			    starts[player] = []float64{x, y}
+
+                // the fruit shows up at the place the first player starts. It's hidden until PacMan eats enough food
+                if player == 0 {
+                    fruit = createSprite(getAsset("fruit"), blockSize, blockSize, x, y)
+                    fruit.visibility = false
                 }
 
 				// Since PacMan is moving always, we don't need to add it to the maze grid matrix
-            case '.':
-                // create the food and mark position to the corresponding grid cell
-                dot := createSprite("assets/food.png", blockSize, blockSize, x, y)
+            case 'E':
+                // let's remember where enemies are spawned. Enemies move on it like on an empty path
+                enemySpawns = append(enemySpawns, []float64{x, y})
+            case '.', 'o':
+                // create the food (or the power pellet) and mark position to the corresponding grid cell
+                foodAsset := "food"
+                if char == 'o' {
+                    foodAsset = "pellet"
+                }
+                dot := createSprite(getAsset(foodAsset), blockSize, blockSize, x, y)
 
                 // let's store the food block Sprite reference in the mazeWall array
                 food = append(food, &dot)
@@ -573,36 +795,104 @@ func locateGameObjects() {
 		}
 	}
 
-	// Now, let's place enemies on random places (random places where there's a path (food))
-	for i := 0; i < LEVELS[gameInfo.level].numEnemies; i++ {
-	    // get random food. This is the standard way of generating a random integer in Go.
-	    rand.Seed(time.Now().UnixNano())
-	    randomFood := food[rand.Intn(len(food))]
-
-        // Let's create and enemy and mark its location at the random food. This way we can place enemies at random points in a movable path
-	    enemy := createSprite("assets/enemy.png", blockSize, blockSize, randomFood.x, randomFood.y)
+	// The maze can give its own enemies, speeds and the place of the fruit, otherwise the level is used (see maze.go)
+	maze := getLevelMaze(gameInfo.level)
+	if maze.fruit != nil {
+	    fruit.x, fruit.y = getPositionFromMazePoint(maze.fruit[0], maze.fruit[1])
+	}
 
-        // Let's also give an initial direction for the enemy to move
-        // For this we need to get the grid point which this enemy is getting placed
-	    colFood, rowFood := getMazePointFromPosition(randomFood.x, randomFood.y)
-	    // Now get a possible movable direction at that grid cell
-        enemy.direction = getMovableDirection(colFood, rowFood, enemy.direction)
+	// Now, let's create a PacMan for each player at the start of the player
+	for player := range gameInfo.stats {
+	    // if the maze doesn't have a start for this player, the player starts at the start of the first player
+	    start, ok := starts[player]
+	    if !ok {
+	        start = starts[0]
+	    }
+	    pacman := createPacman(player, start[0], start[1])
+	    pacman.speed = getPacmanSpeed(gameInfo.level)
+	    pacmen = append(pacmen, &pacman)
+	}
 
+	// Now, let's place enemies on random places (random spawn points, or random places where there's a path (food))
+	// The adaptive difficulty can change the number of enemies, how often they chase PacMan and their speed (see difficulty.go)
+	for _, aggression := range adaptEnemies(getEnemyAggressions(gameInfo.level)) {
         // Let's add enemy to the list of enemies. We don't need to add to maze grid matrix as enemy is moving.
-        enemies = append(enemies, &enemy)
+        enemies = append(enemies, createEnemy(aggression))
 	}
 
+	// Popups are placed on the center of the maze (playfield)
+	centerX := float64(len(gameInfo.maze[0])*blockSize)/2.0
+	centerY := float64(len(gameInfo.maze)*blockSize)/2.0
+
 	// Let's load game over image as Sprite
-	gameOver = createSprite("assets/gameover.png", blockSize*10, blockSize*7, float64(screenSizeX)/2.0-float64(blockSize*10)/2.0, float64(screenSizeY)/2.0-float64(blockSize*7)/2.0)
+	gameOver = createSprite(getAsset("gameOver"), blockSize*10, blockSize*7, centerX-float64(blockSize*10)/2.0, centerY-float64(blockSize*7)/2.0)
 
 	// Let's load level complete image as Sprite
-    levelComplete = createSprite("assets/levelcomplete.png", blockSize*12, blockSize*12, float64(screenSizeX)/2.0-float64(blockSize*12)/2, float64(screenSizeY)/2.0-float64(blockSize*12)/2)
+    levelComplete = createSprite(getAsset("levelComplete"), blockSize*12, blockSize*12, centerX-float64(blockSize*12)/2, centerY-float64(blockSize*12)/2)
 
     // Let's load Win image as Sprite
-    win = createSprite("assets/win.png", blockSize*12, blockSize*12, float64(screenSizeX)/2.0-float64(blockSize*12)/2, float64(screenSizeY)/2.0-float64(blockSize*12)/2)
+    win = createSprite(getAsset("win"), blockSize*12, blockSize*12, centerX-float64(blockSize*12)/2, centerY-float64(blockSize*12)/2)
 
     // Let's load Start logo image as Sprite
-    startLogo = createSprite("assets/start.png", blockSize*14, blockSize*5, float64(screenSizeX)/2.0-float64(blockSize*14)/2, float64(screenSizeY)/2.0-float64(blockSize*5)/2)
+    startLogo = createSprite(getAsset("start"), blockSize*14, blockSize*5, centerX-float64(blockSize*14)/2, centerY-float64(blockSize*5)/2)
+}
+
+/*
+    Function: createPacman
+    Create a PacMan with all of his faces
+    Inputs: player of the PacMan and the start position (x, y)
+*/
+func createPacman(player int, x float64, y float64) Sprite {
+    // create the PacMan and mark position to the corresponding grid cell
+    pacman := createSprite(getAsset("pacman"), blockSize, blockSize, x, y)
+
+    // now let's load the other faces of pacman
+    UP_SPRITE := createSprite(getAsset("pacmanU"), blockSize, blockSize, x, y)
+    RIGHT_SPRITE := createSprite(getAsset("pacmanR"), blockSize, blockSize, x, y)
+    DOWN_SPRITE := createSprite(getAsset("pacmanD"), blockSize, blockSize, x, y)
+    LEFT_SPRITE := createSprite(getAsset("pacmanL"), blockSize, blockSize, x, y)
+    IDLE_SPRITE := createSprite(getAsset("pacmanI"), blockSize, blockSize, x, y)
+
+    pacman.faces = map[byte]*Image{
+        'U': UP_SPRITE.img,
+        'R': RIGHT_SPRITE.img,
+        'D': DOWN_SPRITE.img,
+        'L': LEFT_SPRITE.img,
+        'I': IDLE_SPRITE.img,
+    }
+
+    // let's remember where PacMan starts, to bring him back here after losing a life
+    pacman.startX = x
+    pacman.startY = y
+
+    // let's give each player a different color, so the PacMen can be told apart
+    pacman.player = player
+    pacman.tint = playerColors[player%len(playerColors)]
+
+    // a player without lives (co-op mode) is not in the maze
+    pacman.visibility = gameInfo.stats[player].lives > 0
+    return pacman
+}
+
+/*
+    Function: createEnemy
+    Create an enemy with its faces, and place it at a random spawn point or food (see placeEnemy)
+    Input: how often the enemy chases PacMan at a junction, from 0 (never) to 1 (always)
+*/
+func createEnemy(aggression float64) *Sprite {
+    enemy := createSprite(getAsset("enemy"), blockSize, blockSize, 0, 0)
+    enemy.aggression = aggression
+    enemy.speed = adaptEnemySpeed(getEnemySpeed(gameInfo.level))
+
+    // let's load the faces of the enemy, normal and frightened (after PacMan eats a power pellet)
+    FRIGHTENED_SPRITE := createSprite(getAsset("enemyFrightened"), blockSize, blockSize, 0, 0)
+    enemy.faces = map[byte]*Image{
+        'N': enemy.img,
+        'F': FRIGHTENED_SPRITE.img,
+    }
+
+    placeEnemy(&enemy)
+    return &enemy
 }
 
 /*
@@ -611,18 +901,104 @@ func locateGameObjects() {
     Input: level
 */
 func initLevel(level int) {
-    // initialize the game info
+    // use the theme of this level
+    currentTheme = getLevelTheme(level)
+
+    // initialize the game info. lives, points and fruits are kept from the previous level
     gameInfo = GameInfo {
         level: level,
         score: 1,
         maxScore: 1, // this will be set after loading all the food sprites. for now let's keep it as 1
-        maze: readMazeFile(LEVELS[level].mazeFile),
+        maze: copyMaze(getLevelMaze(level).rows), // the maze file of the level, or the maze tested in the editor (see maze.go)
+        stats: gameInfo.stats,
+        fruits: gameInfo.fruits,
+        freezeTimer: freezeTime,
     }
 
+    // load the font of the theme
+    gameFont = loadFont(getAsset("font"))
+
+    // the adaptive difficulty counts the lives lost and the time on each level from the start (see difficulty.go)
+    resetLevelPerformance()
+
     // load game objects from assets and locate them in corresponding places
     locateGameObjects()
 }
 
+/*
+    Function: newGame
+    Start a new game from level 1 with all the lives and no points
+    The number of players is chosen on the start screen
+*/
+func newGame() {
+    isNewGame = true
+    isVersus = false
+    versusEnemy = nil
+    numPlayers = 1
+    gameInfo.stats = []PlayerStats{newPlayerStats()}
+    gameInfo.fruits = 0
+    initLevel(1)
+}
+
+/*
+    Function: startNextLevel
+    Go on after a level is complete. When all the levels are completed, the other player gets the turn in the two
+    player mode, and a new game is started only when no other player can go on
+*/
+func startNextLevel() {
+    if gameInfo.level+1 <= len(LEVELS) {
+        // hide level complete and load next level
+        gameInfo.isLevelComplete = false
+        initLevel(gameInfo.level+1)
+        return
+    }
+
+    // all the levels are completed, let's keep the high score. The saved game of this player stays complete, so the
+    // player doesn't get the turn again
+    saveHighScore()
+    if changePlayer() {
+        return
+    }
+    newGame()
+}
+
+/*
+    Function: newPlayerStats
+    Get the lives and points of a player at the start of a new game
+*/
+func newPlayerStats() PlayerStats {
+    return PlayerStats{
+        lives: startingLives,
+        points: 0,
+        nextExtraLife: extraLifePoints,
+    }
+}
+
+/*
+    Function: setScreenSize
+    Set the size of the screen to fit the largest maze of all the levels and the bars of the HUD
+*/
+func setScreenSize() {
+    maxCols := 0
+    maxRows := 0
+    for level := range LEVELS {
+        maze := getLevelMaze(level).rows
+        if len(maze) > maxRows {
+            maxRows = len(maze)
+        }
+        for _, line := range maze {
+            if len(line) > maxCols {
+                maxCols = len(line)
+            }
+        }
+    }
+
+    playfieldX = 0
+    playfieldY = hudTopHeight
+    screenSizeX = maxCols*blockSize
+    screenSizeY = hudTopHeight+maxRows*blockSize+hudBottomHeight
+}
+
 
 
 /*
@@ -632,14 +1008,240 @@ func initLevel(level int) {
 */
 
 // code inside update function is called every 60 times per second
-func update(screen *ebiten.Image) error {
+func update(screen *Image) error {
+    // when a bot plays player 1, let's get its move for this frame
+    if gameBot != nil {
+        gameBot.update()
+    }
+
+    // let's start the demo when nobody plays for a while on the start screen, or stop it when a key is pressed
+    updateDemo()
+
+    // Let's run the game for this frame (move PacMan and enemies, handle the keys of the screens)
+    updateGame()
+
+    // when hosting a networked game, let's send the new state of the game to the players who joined, and announce the game
+    if netHost != nil {
+        netHost.broadcast()
+        netHost.announce()
+    }
+
+    // when the game is streamed, let's send the new state of the game to the spectators
+    if spectatorServer != nil {
+        spectatorServer.publish()
+    }
+
+    // play the siren only while PacMan and enemies are moving (the demo has no sounds)
+    updateSiren(gameInfo.isStarted && !gameInfo.isLevelComplete && !gameInfo.isGameOver && gameInfo.freezeTimer == 0 && !isDemo)
+
+    // When M is pressed, turn the sound on or off
+    if isKeyJustPressed(KeyM) {
+        toggleMute()
+    }
+
+    // When Tab is pressed, turn the assist of player 1 on or off
+    if isKeyJustPressed(KeyTab) {
+        isAssistOn = !isAssistOn
+    }
+
+    // When F9 is pressed, write a snapshot of the game into a file (see snapshot.go)
+    if isKeyJustPressed(KeyF9) {
+        writeSnapshotFile()
+    }
+
+    // When F5 is pressed, save the game (see savegame.go)
+    updateSaveGame()
+
     // Let's skip rendering the frame is the game play gets slow. (This is increases the performance)
-	if ebiten.IsDrawingSkipped() {
+	if isDrawingSkipped() {
 	    // stop the function here
 		return nil
 	}
 
+	// Let's draw the frame
+	drawGame(screen)
+	return nil
+}
+
+/*
+    Function: isSpacePressed
+    Check if space is pressed to go on from a screen. In a rollback game, space comes with the inputs of the frame (see rollback.go)
+    A bot can also press space (see bot.go), and space can be pressed in the terminal (see tui.go)
+*/
+func isSpacePressed() bool {
+    if gameBot != nil && gameBot.isSpace {
+        return true
+    }
+    if isTUI {
+        return isTUIKeyPressed(' ')
+    }
+    if isHeadless {
+        return headlessSpace
+    }
+    if rollbackSession != nil {
+        return rollbackSession.isSpacePressed()
+    }
+    return isKeyPressed(KeySpace)
+}
+
+/*
+    Function: isSpaceJustPressed
+    Check if space is pressed now, but it wasn't pressed on the previous frame
+*/
+func isSpaceJustPressed() bool {
+    if gameBot != nil && gameBot.isSpace {
+        return true
+    }
+    if isTUI {
+        return isTUIKeyPressed(' ')
+    }
+    if isHeadless {
+        return headlessSpace
+    }
+    if rollbackSession != nil {
+        return rollbackSession.isSpacePressed()
+    }
+    return isKeyJustPressed(KeySpace)
+}
+
+/*
+    Function: isModeKeyPressed
+    Check if a key which chooses the game mode on the start screen is pressed
+    A rollback game is always the versus mode, so the keys are not used there (only space comes with the inputs of a frame)
+    A headless game has no keys, it always plays the one player mode. The terminal has only some of the keys (see tui.go)
+    Input: key
+*/
+func isModeKeyPressed(key Key) bool {
+    if isTUI {
+        char, ok := tuiModeKeys[key]
+        return ok && isTUIKeyPressed(char)
+    }
+    return rollbackSession == nil && !isHeadless && isKeyPressed(key)
+}
+
+/*
+    Function: updateGame
+    Run the game for a single frame. Nothing is drawn here, so the game can also run without a window (Ex: network host, see net.go)
+*/
+func updateGame() {
+    // while the pack browser is open, the game waits on the start screen (see pack.go)
+    if packBrowser != nil {
+        updatePackBrowser()
+        return
+    }
+
 	// Let's code what should happen on each frame (Game Starts from here)
+    if !gameInfo.isStarted {
+        // When space is pressed, load next level
+        if isSpacePressed() {
+            // hide start logo complete
+            gameInfo.isStarted = true
+            if isNewGame && rollbackSession != nil {
+                // a rollback game is always the versus mode, so the next match starts
+                startVersus()
+            } else if isNewGame {
+                startPlayers(1)
+            }
+            isNewGame = false
+        } else if isNewGame && hasSaveGame && canContinueGame() && isModeKeyPressed(KeyEnter) {
+            // continue the saved game (see savegame.go)
+            continueGame()
+        } else if isNewGame && isModeKeyPressed(Key2) {
+            // start the two player mode, players take turns
+            startPlayers(2)
+            isNewGame = false
+        } else if isNewGame && isModeKeyPressed(KeyC) {
+            // start the co-op mode, all the PacMen play at the same time. Players who joined over the network get a PacMan too
+            startCoop(int(math.Max(float64(coopPlayers), float64(getRemotePlayerCount()+1))))
+            isNewGame = false
+        } else if isNewGame && isModeKeyPressed(KeyV) {
+            // start the versus mode, the second player steers an enemy
+            startVersus()
+            isNewGame = false
+        } else if isNewGame && canBrowsePacks() && isModeKeyPressed(KeyL) {
+            // choose the level pack to play (see pack.go)
+            openPackBrowser()
+        }
+
+    } else if isVersus && isVersusRoundOver() {
+        // When space is pressed, start the next round in the versus mode. Space has to be pressed again, so a held key doesn't skip the screen
+        if isSpaceJustPressed() {
+            nextVersusRound()
+        }
+
+    } else if gameInfo.isLevelComplete {
+        // When space is pressed, load next level
+        if isSpacePressed() {
+            startNextLevel()
+        }
+
+    } else if gameInfo.isGameOver {
+        // When space is pressed, start from level 1
+        if isSpacePressed() {
+            // hide game over
+            gameInfo.isGameOver = false
+            // start a new game from level 1
+            newGame()
+        }
+
+    } else if gameInfo.freezeTimer > 0 {
+        // PacMan and enemies are waiting to start moving. Let's count down
+        gameInfo.freezeTimer = gameInfo.freezeTimer-1
+
+    } else {
+        // Main Game logic exist here
+
+        // in the versus mode, let's remember the direction the enemy player is pressing
+        if isVersus {
+            readVersusInput()
+        }
+
+        for _, pacman := range pacmen {
+            // a PacMan without lives (co-op mode) doesn't move
+            if !isPacmanAlive(pacman) {
+                continue
+            }
+
+            // Let's move the PacMan if user is pressing a direction key
+            movePacman(pacman)
+
+            // PacMan going into a tunnel comes out of the other end (see maze.go)
+            useTunnel(pacman)
+
+            // let PacMan eat food, if there's any food on the current location
+            eatFood(pacman)
+        }
+
+        // get each enemy from the list of enemies array and move each enemy
+        for _, enemy := range enemies {
+            // move enemy to a possible direction, and through a tunnel
+            moveEnemy(enemy)
+            useTunnel(enemy)
+        }
+
+        // count down the power pellet and fruit timers
+        updateTimers()
+
+        // let's watch how the player does, for the adaptive difficulty (see difficulty.go)
+        updateDifficulty()
+    }
+}
+
+/*
+    Function: drawGame
+    Draw the maze, the game objects, the popups and the HUD of the current frame
+    Input: screen
+*/
+func drawGame(screen *Image) {
+    // the pack browser is drawn instead of the start screen while it's open
+    if packBrowser != nil {
+        drawPackBrowser(screen)
+        return
+    }
+
+    // Let's fill the screen with the background color of the theme
+    background, _ := getPaletteColor("background")
+    screen.Fill(background)
 
     // Let's draw the Walls and food first
     // Go through all the sprites in mazeWall and add them to the screen
@@ -652,84 +1254,102 @@ func update(screen *ebiten.Image) error {
 	    drawSprite(screen, dot)
     }
 
+    clr := getTextColor()
+
     if !gameInfo.isStarted {
         // Show Start screen when game is not yet started
         drawSprite(screen, &startLogo)
 
-        // When space is pressed, load next level
-        if ebiten.IsKeyPressed(ebiten.KeySpace) {
-            // hide start logo complete
-            gameInfo.isStarted = true
+        if isNewGame {
+            drawPrompt(screen, &startLogo, getSpacePrompt("Space: START  2: 2 PLAYERS  C: CO-OP  V: VERSUS"))
+
+            // when there's a saved game, let's show how to continue it under the prompt, and how to choose a level pack under it
+            _, textHeight := measureText("0")
+            w, h := startLogo.img.Size()
+            y := playfieldY+int(startLogo.y)+h+textHeight*2
+            if hasSaveGame && canContinueGame() {
+                drawTextCentered(screen, "Enter: CONTINUE", playfieldX+int(startLogo.x)+w/2, y, clr)
+                y = y+textHeight*2
+            }
+            if canBrowsePacks() {
+                drawTextCentered(screen, "L: LEVEL PACKS ("+levelPackName+")", playfieldX+int(startLogo.x)+w/2, y, clr)
+            }
+        } else {
+            drawPrompt(screen, &startLogo, getSpacePrompt("Press Space to START"))
+        }
+
+        // when hosting a networked game, let's show how many players joined
+        if netHost != nil {
+            drawJoinedPlayers(screen)
         }
 
+    } else if isVersus && isVersusRoundOver() {
+        // Show the end of the round (or the winner of the match) in the versus mode
+        drawVersusRoundOver(screen)
+
     } else if gameInfo.isLevelComplete {
         // Show Level Complete / WIN Screen on level complete
-        nextLevel := gameInfo.level+1
-
-        // if the Next level is below number of levels, show level complete text
-        if nextLevel <= len(LEVELS) {
+        if gameInfo.level+1 <= len(LEVELS) {
+            // if the Next level is below number of levels, show level complete text
             drawSprite(screen, &levelComplete)
-            _, h := levelComplete.img.Size()
-            ebitenutil.DebugPrintAt(screen, "Press Space to START.....", int(levelComplete.x)+blockSize, int(levelComplete.y)+h)
+            drawPrompt(screen, &levelComplete, getSpacePrompt("Press Space to START"))
         } else {
             // if the Next level is above number of levels, that means user has completed all the levels. Let's show win screen
             drawSprite(screen, &win)
             // show Text under the win sprite
-            _, h := win.img.Size()
-            ebitenutil.DebugPrintAt(screen, "Press Space to START..", int(win.x)+2*blockSize, int(win.y)+h)
-
-            // let's make the next level as 1, to start over when space is pressed
-            nextLevel = 1
-        }
-
-        // When space is pressed, load next level
-        if ebiten.IsKeyPressed(ebiten.KeySpace) {
-            // hide level complete
-            gameInfo.isLevelComplete = false
-
-            // load next level
-            initLevel(nextLevel)
+            drawPrompt(screen, &win, getSpacePrompt("Press Space to START"))
         }
 
     } else if gameInfo.isGameOver {
         // Show Game Over Screen  on game over
         drawSprite(screen, &gameOver)
 
-        _, h := gameOver.img.Size()
-        ebitenutil.DebugPrintAt(screen, "Press Space to START", int(gameOver.x)+blockSize, int(gameOver.y)+h)
-
-        // When space is pressed, start from level 1
-        if ebiten.IsKeyPressed(ebiten.KeySpace) {
-            // hide game over
-            gameInfo.isGameOver = false
-            // load level 1
-            initLevel(1)
-        }
+        drawPrompt(screen, &gameOver, getSpacePrompt("Press Space to START"))
     } else {
-        // There are no any pause screens, Let's make the pacman and enemies visible and allow them to move
-
-        // Main Game logic exist here
-        // Let's move the PacMan if user is pressing a direction key
-        movePacman()
+        // There are no any pause screens, Let's make the pacman and enemies visible
+
+        if gameInfo.freezeTimer > 0 {
+            // PacMan and enemies are waiting to start moving. Let's show READY! under PacMan's start
+            readyX := playfieldX+int(pacmen[0].startX)+blockSize/2
+            readyY := playfieldY+int(pacmen[0].startY)
+            drawTextCentered(screen, "READY!", readyX, readyY+blockSize, clr)
+
+            // in the two player mode, let's also show whose turn it is
+            if numPlayers > 1 {
+                drawTextCentered(screen, getPlayerLabel(), readyX, readyY-blockSize, clr)
+            }
+
+            // in the versus mode, let's show who is PacMan in this round
+            if isVersus {
+                drawTextCentered(screen, getVersusRole(versusPacmanPlayer), readyX, readyY-blockSize, clr)
+            }
+
+            // let's show the name, the author and the par time of the maze, if the maze file gives them (see maze.go)
+            drawMazeInfo(screen)
+        }
 
-        // let PacMan eat food, if there's any food on the current location
-        eatFood()
+        // show the fruit, if it's there
+        drawSprite(screen, &fruit)
 
-        // get each enemy from the list of enemies array and move each enemy
+        // show enemies on the screen
         for _, enemy := range enemies {
-            // move enemy to a possible direction
-            moveEnemy(enemy)
-            // show enemy on the screen
     	    drawSprite(screen, enemy)
         }
 
-        // show the PACMAN on screen
-        drawSprite(screen, &pacman)
+        // show the PACMEN on screen
+        for _, pacman := range pacmen {
+            drawSprite(screen, pacman)
+        }
     }
 
-    // show the score and level on top left corner of the screen
-    ebitenutil.DebugPrint(screen, "  Level: "+strconv.Itoa(gameInfo.level)+"   Score: "+strconv.Itoa(gameInfo.score))
-	return nil
+    // show the score, high score, level, lives and fruits on the HUD
+    drawHUD(screen)
+
+    // let the players know when the demo is playing or the assist is on
+    drawDemo(screen)
+
+    // show the message about saving the game
+    drawSaveMessage(screen)
 }
 
 
@@ -740,12 +1360,253 @@ func update(screen *ebiten.Image) error {
 */
 // When we run this GO file, this method is getting executed first.
 func main() {
+    // Let's read the command line flags. Ex: ./SimplePacmanGame -theme retro
+    themeName := flag.String("theme", "", "name of a theme inside the themes folder, or path to a theme folder or theme.json file")
+    noSound := flag.Bool("nosound", false, "turn off sound (no audio device is used)")
+    flag.Float64Var(&soundVolume, "volume", soundVolume, "volume of the sounds, from 0 to 1")
+    flag.BoolVar(&isMuted, "mute", isMuted, "start with the sound muted (press M to turn it on or off)")
+    flag.IntVar(&coopPlayers, "coop", coopPlayers, "number of PacMen in the co-op mode, from 2 to 4")
+    flag.IntVar(&versusRounds, "rounds", versusRounds, "number of rounds in a versus match (players swap roles after each round)")
+    exportFolder := flag.String("exportsounds", "", "write the sound effects of the theme as wav files into this folder and exit")
+    hostAddress := flag.String("host", "", "host a networked game on this address (Ex: :7777), other players join it with -join")
+    joinAddress := flag.String("join", "", "join the networked game of a host at this address (Ex: 192.168.1.10:7777)")
+    gameName := flag.String("name", getDefaultGameName(), "name of the hosted game, shown in the lobby of the other players")
+    announceAddress := flag.String("announce", "255.255.255.255", "address the hosted game is announced to (Ex: 192.168.1.255), use an empty address to not announce it")
+    showLobby := flag.Bool("lobby", false, "show the games on the local network and join one of them")
+    streamAddress := flag.String("stream", "", "publish the game for spectators on this address (Ex: :7779), they watch it with -watch")
+    watchAddress := flag.String("watch", "", "watch the game streamed at this address as a spectator (Ex: 192.168.1.10:7779)")
+    flag.BoolVar(&isTUI, "tui", false, "play the game in the terminal with colored characters instead of a window (Ex: over SSH)")
+    printMaze := flag.String("printmaze", "", "print a maze file in the terminal with the colors of -tui and exit")
+    convertMaze := flag.String("convertmaze", "", "convert a maze file (text, PNG or Tiled map) to the format of the -to file (.png or text) and exit")
+    convertTo := flag.String("to", "", "maze file -convertmaze writes")
+    mazePalette := flag.String("mazepalette", "", "JSON file with the colors of the tiles of PNG mazes (Ex: {\"wall\": \"#0000ff\"})")
+    packFile := flag.String("pack", "", "play the levels of a level pack (a zip file with a pack.json manifest, see pack.go)")
+    editFile := flag.String("edit", "", "open a maze file in the maze editor (a new maze if the file doesn't exist), T test-plays it")
+    flag.BoolVar(&isHeadless, "headless", false, "run the game without a window, played by the autopilot (Ex: to stream it with -stream)")
+    botCommand := flag.String("bot", "", "let an external program play player 1 (Ex: \"python3 bot.py\"), it gets the game as JSON on stdin and writes its moves to stdout")
+    flag.DurationVar(&botTimeout, "bottimeout", botTimeout, "how long the game waits for the bot to answer a frame")
+    rollbackAddress := flag.String("rollback", "", "play a versus match with rollback over UDP on this local address (Ex: :7778), the other player is at -peer")
+    peerAddress := flag.String("peer", "", "address of the other player of the rollback game (Ex: 192.168.1.10:7778)")
+    rollbackPlayer := flag.Int("player", 1, "player of this game in the rollback game (1 or 2), player 1 starts the match")
+    snapshotFile := flag.String("snapshot", "", "start the game from a snapshot file (press F9 while playing to write one)")
+    snapshotCheck := flag.String("snapshotcheck", "", "run a snapshot file for -frames frames without a window, compare the world with -expect (or print it) and exit")
+    snapshotFrames := flag.Int("frames", 0, "number of frames -snapshotcheck runs")
+    expectedSnapshot := flag.String("expect", "", "snapshot file the world of -snapshotcheck should be the same as")
+    analyzeFile := flag.String("analyze", "", "print the corridor graph and the difficulty of a maze file, write a heatmap of the deaths and exit")
+    analyzeGames := flag.Int("games", 50, "number of games the autopilot plays on the maze of -analyze")
+    heatmapFile := flag.String("heatmap", "", "PNG file -analyze writes the heatmap of the deaths to (Ex: maze02-deaths.png, next to the maze if not given)")
+    flag.BoolVar(&isAdaptive, "adaptive", false, "change the enemies to how well the player plays (one player mode), every change is logged")
+    difficultyFile := flag.String("adaptiveconfig", "", "JSON file with the bounds of the adaptive difficulty, turns on -adaptive (see difficulty.go)")
+    autopilotGames := flag.Int("autopilottest", 0, "let the autopilot play this many seeded games without a window, print its win rate and exit")
+    flag.Parse()
+
+    // Let's load the selected theme. Levels without their own theme use this theme
+    selectedTheme = loadTheme(*themeName)
+
+    // The palette of the PNG mazes is loaded before any maze is read
+    if *mazePalette != "" {
+        if err := loadPixelMazePalette(*mazePalette); err != nil {
+            log.Fatal(err)
+        }
+    }
+
+    // The bounds of the adaptive difficulty are loaded before the game starts
+    if *difficultyFile != "" {
+        if err := loadDifficultyConfig(*difficultyFile); err != nil {
+            log.Fatal(err)
+        }
+        isAdaptive = true
+    }
+
+    // The level pack is loaded before anything else, so its mazes, images and sounds are used everywhere
+    if *packFile != "" {
+        if err := loadLevelPack(*packFile); err != nil {
+            log.Fatal(err)
+        }
+    }
+
+    // If sounds should be exported, let's write them and stop here without opening the game window
+    if *exportFolder != "" {
+        currentTheme = selectedTheme
+        if err := exportSounds(getAsset("sounds"), *exportFolder); err != nil {
+            log.Fatal(err)
+        }
+        return
+    }
+
+    // If a maze file should be converted, let's write it in the other format and stop here without opening the game window
+    if *convertMaze != "" {
+        if *convertTo == "" {
+            log.Fatal("-convertmaze needs the file to write with -to")
+        }
+        if err := convertMazeFile(*convertMaze, *convertTo); err != nil {
+            log.Fatal(err)
+        }
+        return
+    }
+
+    // If a maze file should be checked, let's print it and stop here without opening the game window
+    if *printMaze != "" {
+        printMazeFile(*printMaze)
+        return
+    }
+
+    // If a maze file should be edited, let's open the editor instead of the game
+    if *editFile != "" {
+        loadHighScore()
+        initSound(*noSound)
+        hasWindow = true
+        runEditor(*editFile)
+        return
+    }
+
+    // If the win rate of the autopilot should be reported, let's play the games and stop here without opening the game window
+    if *autopilotGames > 0 {
+        runAutopilotTest(*autopilotGames)
+        return
+    }
+
+    // If a maze should be analyzed, let's print the report and stop here without opening the game window
+    if *analyzeFile != "" {
+        runMazeAnalysis(*analyzeFile, *analyzeGames, *heatmapFile)
+        return
+    }
+
+    // Let's create the images of the game only if it's shown in a window
+    hasWindow = !isHeadless && !isTUI && *snapshotCheck == ""
+
+    // Let's make the screen big enough for the mazes and the HUD
+    setScreenSize()
+
+    // Let's read the high score of the previous games
+    loadHighScore()
+
+    // Let's check if there's a saved game to continue
+    checkSaveGame()
+
+    // Let's start the random numbers from the current time, so each game is different
+    seedRandomFromTime()
+
     // Let's initialize the game information use level 1
-    initLevel(1)
+    newGame()
+
+    // If a snapshot should be checked, let's run it and stop here without opening the game window
+    if *snapshotCheck != "" {
+        if err := runSnapshotCheck(*snapshotCheck, *snapshotFrames, *expectedSnapshot); err != nil {
+            log.Fatal("snapshot check failed: ", err)
+        }
+        return
+    }
+
+    // Let's start the game from a snapshot, if one is given
+    if *snapshotFile != "" {
+        if err := readSnapshotFile(*snapshotFile); err != nil {
+            log.Fatal(err)
+        }
+    }
+
+    // Let's load the sounds and play them on game events
+    initSound(*noSound || isHeadless || isTUI)
+
+    // Let's count the dots eaten by PacMan in the versus mode
+    addEventListener(countVersusDot)
+
+    // Let's change the difficulty to how the player does, when the adaptive difficulty is on
+    addEventListener(onDifficultyEvent)
+
+    // Let's start hosting the networked game, or join the networked game of a host
+    updateFunction := update
+    if *hostAddress != "" {
+        host, err := startHost(*hostAddress)
+        if err != nil {
+            log.Fatal(err)
+        }
+        netHost = host
+        log.Println("hosting a networked game on", host.listener.Addr())
+        if *announceAddress != "" {
+            if err := host.startAnnouncing(*announceAddress, *gameName); err != nil {
+                log.Println("the game is not announced:", err)
+            }
+        }
+    } else if *joinAddress != "" {
+        client, err := joinGame(*joinAddress)
+        if err != nil {
+            log.Fatal(err)
+        }
+        netClient = client
+        updateFunction = updateClient
+        log.Println("joined the networked game as player", client.number+1)
+    } else if *watchAddress != "" {
+        client, err := watchGame(*watchAddress)
+        if err != nil {
+            log.Fatal(err)
+        }
+        netClient = client
+        updateFunction = updateClient
+        log.Println("watching the game at", *watchAddress)
+    } else if *showLobby {
+        openedLobby, err := startLobby()
+        if err != nil {
+            log.Fatal(err)
+        }
+        lobby = openedLobby
+        updateFunction = updateLobby
+    } else if *rollbackAddress != "" {
+        if *rollbackPlayer != 1 && *rollbackPlayer != 2 {
+            log.Fatal("the player of a rollback game has to be 1 or 2")
+        }
+        session, err := startRollback(*rollbackAddress, *peerAddress, *rollbackPlayer-1)
+        if err != nil {
+            log.Fatal(err)
+        }
+        rollbackSession = session
+        updateFunction = updateRollback
+        log.Println("playing a rollback game as player", *rollbackPlayer, "with", session.peer)
+    }
+
+    // Let's start the program which plays player 1
+    if *botCommand != "" {
+        if netClient != nil || lobby != nil || rollbackSession != nil {
+            log.Fatal("a bot can't play a joined game, the lobby or a rollback game")
+        }
+        bot, err := startBot(*botCommand)
+        if err != nil {
+            log.Fatal(err)
+        }
+        gameBot = bot
+    }
+
+    // Let's publish the game for spectators
+    if *streamAddress != "" {
+        server, err := startStream(*streamAddress)
+        if err != nil {
+            log.Fatal(err)
+        }
+        spectatorServer = server
+        log.Println("streaming the game on", server.listener.Addr())
+    }
+
+    // A headless game runs here without a window until it's stopped. Only the host of a networked game can be headless
+    if isHeadless {
+        if netClient != nil || lobby != nil || rollbackSession != nil {
+            log.Fatal("a headless game can't join a game, open the lobby or play a rollback game")
+        }
+        runHeadless()
+        return
+    }
+
+    // A game in the terminal runs here without a window until Q is pressed
+    if isTUI {
+        if netClient != nil || lobby != nil || rollbackSession != nil || gameBot != nil {
+            log.Fatal("a game in the terminal can't join a game, open the lobby, play a rollback game or be played by a bot")
+        }
+        runTUI()
+        return
+    }
 
-    // ebiten.Run is a function given by the ebiten library.
+    // runWindow opens the game window with the ebiten library (see window.go).
     // Here, we give a method which should call always (60 times per second) and size of the screen, scale the window by 1.5 and name of the window as Simple PacMan Game
-	err := ebiten.Run(update, screenSizeX, screenSizeY, 1.5, "Simple PacMan Game")
+	err := runWindow(updateFunction, screenSizeX, screenSizeY, 1.5, "Simple PacMan Game")
 	// Note that the update method contain all the game logic
 
 	// If there's any error occured in ebiten library to fail loading the window, let's log it
@@ -0,0 +1,569 @@
+package main
+
+/*
+    This file contains the format of the maze files.
+
+    A maze file is the grid of the maze, a row on each line (0 wall, . food, o power pellet, space empty path,
+    P or 1 to 4 the starts of the players, E a spawn point of the enemies).
+    A maze file can start with a header of "key: value" lines, which ends with a line of three dashes. Files without a
+    header are only the grid, like before. All the keys are optional.
+
+    Ex: maze03.txt
+    name: The Cross
+    author: prabushitha
+    pacman speed: 1
+    enemy speed: 1
+    enemies: 0.2, 0.2, 0.6, 1
+    wall color: #21de21
+    theme: retro
+    par time: 1:30
+    fruit: 4,3
+    tunnel: 0,2 9,2
+    tile #: wall
+    tile -: empty
+    ---
+    ##########
+    #P.....-E#
+    ...
+
+    name, author : shown when the level starts
+    pacman speed : speed of PacMan in this maze (pixels a frame), instead of the speed of the level
+    enemy speed  : speed of the enemies in this maze, instead of the speed of the level
+    enemies      : the enemies of this maze, each one is how often it chases PacMan at a junction (0 never, 1 always).
+                   It's used instead of the number of enemies and the aggression of the level
+    wall color   : color of the walls (the wall color of the theme is still used first)
+    theme        : theme of this maze (a theme folder inside the themes folder, a folder or a theme.json file, like
+                   -theme). It's used when the level doesn't give a theme, instead of the theme given with -theme
+    par time     : time to finish the maze in, as seconds or minutes:seconds. It's shown when the level starts
+    fruit        : maze point (column,row) where the fruit shows up, instead of the start of player 1
+    tunnel       : the two ends (column,row) of a tunnel. PacMan and enemies going into an end come out of the other
+                   end, going in the same direction. A maze can have many tunnel lines
+    tile X       : what the character X means in the grid (wall, food, pellet, empty, spawn, player 1 to player 4,
+                   or a character of the grid). Ex: to read mazes drawn with other characters
+*/
+import (
+    "errors"
+    "fmt"
+    "image/color"
+    "math"
+    "strconv"
+    "strings"
+)
+
+// Structure which keeps a maze read from a maze file
+type Maze struct {
+    name string // holds the name of the maze
+    author string // holds who made the maze
+    pacmanSpeed float64 // holds the speed of PacMan (0 if the maze doesn't give it)
+    enemySpeed float64 // holds the speed of the enemies (0 if the maze doesn't give it)
+    enemies []float64 // holds how often each enemy chases PacMan (nil if the maze doesn't give the enemies)
+    wallColor color.RGBA // holds the color of the walls (transparent if the maze doesn't give it)
+    theme string // holds the theme of the maze (empty if the maze doesn't give it)
+    parTime int // holds the time to finish the maze in, in seconds (0 if the maze doesn't give it)
+    fruit []int // holds the maze point (column, row) where the fruit shows up (nil if it's the start of player 1)
+    tunnels [][2][2]int // holds the two ends (column, row) of each tunnel
+    rows []string // holds the grid, each string is a row. The tiles of the legend are already changed to the tiles of the game
+}
+
+// Structure of a problem found in a maze
+type MazeProblem struct {
+    col int // holds the maze point of the problem. Problems of the whole maze have -1
+    row int
+    message string // holds what's wrong
+}
+
+// Let's define the line which ends the header of a maze file
+var mazeHeaderEnd = "---"
+
+// Let's define the names of the tiles which can be used in the legend of a maze file
+var mazeTileNames = map[string]byte{
+    "wall": '0',
+    "food": '.',
+    "pellet": 'o',
+    "empty": ' ',
+    "spawn": 'E',
+    "player 1": 'P',
+    "player 2": '2',
+    "player 3": '3',
+    "player 4": '4',
+}
+
+// Variable to keep the already read maze files, so a maze file is read only once
+var loadedMazes = map[string]Maze{}
+
+/*
+    Function: parseMaze
+    Read the header and the grid of a maze file
+    Input: lines of the maze file
+*/
+func parseMaze(lines []string) (Maze, error) {
+    maze := Maze{}
+
+    // a maze file without the end of the header is only the grid
+    headerEnd := -1
+    for i, line := range lines {
+        if strings.TrimSpace(line) == mazeHeaderEnd {
+            headerEnd = i
+            break
+        }
+    }
+    if headerEnd < 0 {
+        maze.rows = lines
+        return maze, nil
+    }
+
+    legend := map[byte]byte{}
+    for i, line := range lines[:headerEnd] {
+        if strings.TrimSpace(line) == "" {
+            continue
+        }
+        if err := parseMazeHeaderLine(&maze, legend, line); err != nil {
+            return maze, fmt.Errorf("line %d: %v", i+1, err)
+        }
+    }
+
+    // let's change the characters of the legend to the tiles of the game
+    for _, line := range lines[headerEnd+1:] {
+        row := []byte(line)
+        for col, char := range row {
+            if tile, ok := legend[char]; ok {
+                row[col] = tile
+            }
+        }
+        maze.rows = append(maze.rows, string(row))
+    }
+    return maze, nil
+}
+
+/*
+    Function: checkMaze
+    Check that a maze can be played, with the same checks as the editor (see getMazeProblems)
+    Gives back the first problem found as an error
+    Input: maze
+*/
+func checkMaze(maze Maze) error {
+    problems := getMazeProblems(maze)
+    if len(problems) == 0 {
+        return nil
+    }
+    if problems[0].col < 0 {
+        return errors.New(problems[0].message)
+    }
+    return fmt.Errorf("%d,%d: %s", problems[0].col, problems[0].row, problems[0].message)
+}
+
+/*
+    Function: getMazeProblems
+    Find what's wrong in a maze: rows of different lengths, unknown tiles, missing or repeated starts, no food, the fruit
+    or tunnels which are not on paths, and food, starts or spawn points which can't be reached from the start of player 1
+    Input: maze
+*/
+func getMazeProblems(mazeFile Maze) []MazeProblem {
+    maze := mazeFile.rows
+    problems := []MazeProblem{}
+    if len(maze) == 0 {
+        return append(problems, MazeProblem{-1, -1, "THE MAZE IS EMPTY"})
+    }
+
+    starts := map[byte][2]int{}
+    foodCount := 0
+    for row, line := range maze {
+        if len(line) != len(maze[0]) {
+            problems = append(problems, MazeProblem{0, row, "THE ROW IS NOT AS LONG AS THE FIRST ROW"})
+        }
+        for col := 0; col < len(line); col++ {
+            tile := line[col]
+            switch tile {
+            case '0', ' ', 'E':
+            case '.', 'o':
+                foodCount++
+            case 'P', '1', '2', '3', '4':
+                // P is the start of player 1, the same as 1
+                if tile == '1' {
+                    tile = 'P'
+                }
+                if _, ok := starts[tile]; ok {
+                    problems = append(problems, MazeProblem{col, row, "THE START IS ALREADY IN THE MAZE"})
+                } else {
+                    starts[tile] = [2]int{col, row}
+                }
+            default:
+                problems = append(problems, MazeProblem{col, row, fmt.Sprintf("UNKNOWN TILE %q", tile)})
+            }
+        }
+    }
+    if foodCount == 0 {
+        problems = append(problems, MazeProblem{-1, -1, "THE MAZE HAS NO FOOD"})
+    }
+    if fruit := mazeFile.fruit; fruit != nil && !isMazePath(maze, fruit[0], fruit[1]) {
+        problems = append(problems, MazeProblem{-1, -1, "THE FRUIT IS NOT ON A PATH"})
+    }
+    for _, tunnel := range mazeFile.tunnels {
+        for _, end := range tunnel {
+            if !isMazePath(maze, end[0], end[1]) {
+                problems = append(problems, MazeProblem{-1, -1, fmt.Sprintf("THE TUNNEL END %d,%d IS NOT ON A PATH", end[0], end[1])})
+            }
+        }
+    }
+    start, ok := starts['P']
+    if !ok {
+        return append(problems, MazeProblem{-1, -1, "THE MAZE HAS NO START OF PLAYER 1 (P)"})
+    }
+
+    // everything PacMan eats, the other starts and the spawn points of the enemies should be reachable from the start
+    reached := getReachablePoints(mazeFile, start[0], start[1])
+    for row, line := range maze {
+        for col := 0; col < len(line); col++ {
+            if strings.IndexByte(".o234E", line[col]) >= 0 && !reached[[2]int{col, row}] {
+                problems = append(problems, MazeProblem{col, row, "PACMAN CAN'T GET HERE FROM THE START"})
+            }
+        }
+    }
+    return problems
+}
+
+/*
+    Function: getReachablePoints
+    Get the maze points which can be reached from a maze point without going through walls (breadth first search)
+    The other end of a tunnel is reached from an end of the tunnel
+    Inputs: maze and the maze point to start from
+*/
+func getReachablePoints(mazeFile Maze, col int, row int) map[[2]int]bool {
+    reached := map[[2]int]bool{{col, row}: true}
+    queue := [][2]int{{col, row}}
+    for len(queue) > 0 {
+        point := queue[0]
+        queue = queue[1:]
+        for _, next := range getMazeNeighbors(mazeFile, point[0], point[1]) {
+            if !reached[next] {
+                reached[next] = true
+                queue = append(queue, next)
+            }
+        }
+    }
+    return reached
+}
+
+/*
+    Function: isMazePath
+    Check if a maze point is inside the grid and it's not a wall
+    Inputs: rows of the maze and the maze point
+*/
+func isMazePath(rows []string, col int, row int) bool {
+    return row >= 0 && row < len(rows) && col >= 0 && col < len(rows[row]) && rows[row][col] != '0'
+}
+
+/*
+    Function: getMazeNeighbors
+    Get the paths next to a maze point: the paths up, right, down and left of it, and the other end of its tunnel
+    Inputs: maze and the maze point
+*/
+func getMazeNeighbors(maze Maze, col int, row int) [][2]int {
+    neighbors := [][2]int{}
+    for _, step := range autopilotSteps {
+        if isMazePath(maze.rows, col+step[0], row+step[1]) {
+            neighbors = append(neighbors, [2]int{col+step[0], row+step[1]})
+        }
+    }
+    for _, tunnel := range maze.tunnels {
+        for end := range tunnel {
+            if tunnel[end] == [2]int{col, row} && isMazePath(maze.rows, tunnel[1-end][0], tunnel[1-end][1]) {
+                neighbors = append(neighbors, tunnel[1-end])
+            }
+        }
+    }
+    return neighbors
+}
+
+/*
+    Function: parseMazeHeaderLine
+    Read a "key: value" line of the header of a maze file
+    Inputs: maze to fill, legend to fill (character of the grid to the tile of the game) and the line
+*/
+func parseMazeHeaderLine(maze *Maze, legend map[byte]byte, line string) error {
+    parts := strings.SplitN(line, ":", 2)
+    if len(parts) != 2 {
+        return fmt.Errorf("%q is not a key: value line", line)
+    }
+    key := strings.ToLower(strings.TrimSpace(parts[0]))
+    value := strings.TrimSpace(parts[1])
+
+    var err error
+    switch key {
+    case "name":
+        maze.name = value
+    case "author":
+        maze.author = value
+    case "pacman speed":
+        maze.pacmanSpeed, err = parseMazeSpeed(value)
+    case "enemy speed":
+        maze.enemySpeed, err = parseMazeSpeed(value)
+    case "enemies":
+        maze.enemies = []float64{}
+        for _, enemy := range strings.Split(value, ",") {
+            aggression, err := strconv.ParseFloat(strings.TrimSpace(enemy), 64)
+            if err != nil || aggression < 0 || aggression > 1 {
+                return fmt.Errorf("enemies: %q should be a number from 0 to 1", strings.TrimSpace(enemy))
+            }
+            maze.enemies = append(maze.enemies, aggression)
+        }
+    case "wall color":
+        maze.wallColor, err = parseHexColor(value)
+    case "theme":
+        maze.theme = value
+    case "par time":
+        maze.parTime, err = parseParTime(value)
+    case "fruit":
+        maze.fruit = make([]int, 2)
+        if _, scanErr := fmt.Sscanf(value, "%d,%d", &maze.fruit[0], &maze.fruit[1]); scanErr != nil {
+            err = fmt.Errorf("%q should be column,row", value)
+        }
+    case "tunnel":
+        tunnel := [2][2]int{}
+        if _, scanErr := fmt.Sscanf(value, "%d,%d %d,%d", &tunnel[0][0], &tunnel[0][1], &tunnel[1][0], &tunnel[1][1]); scanErr != nil {
+            err = fmt.Errorf("%q should be column,row column,row", value)
+        }
+        maze.tunnels = append(maze.tunnels, tunnel)
+    default:
+        // a tile of the legend is "tile X: name" (the character is taken as it is, so a space can't be used)
+        if !strings.HasPrefix(key, "tile ") || len(key) != len("tile X") {
+            return fmt.Errorf("unknown key %q", key)
+        }
+        tile, ok := mazeTileNames[strings.ToLower(value)]
+        if !ok && len(value) == 1 {
+            tile, ok = value[0], true
+        }
+        if !ok {
+            return fmt.Errorf("%s: unknown tile %q", key, value)
+        }
+        legend[strings.TrimSpace(parts[0])[len("tile ")]] = tile
+    }
+    if err != nil {
+        return fmt.Errorf("%s: %v", key, err)
+    }
+    return nil
+}
+
+/*
+    Function: parseMazeSpeed
+    Read a speed of the header of a maze file
+    Input: speed as a string
+*/
+func parseMazeSpeed(value string) (float64, error) {
+    speed, err := strconv.ParseFloat(value, 64)
+    if err != nil || speed <= 0 || speed > float64(blockSize/2) {
+        return 0, fmt.Errorf("%q should be a number above 0 and up to %d", value, blockSize/2)
+    }
+    return speed, nil
+}
+
+/*
+    Function: parseParTime
+    Read a par time written as seconds (Ex: 90) or minutes:seconds (Ex: 1:30, the seconds are less than 60)
+    Input: par time as a string
+*/
+func parseParTime(value string) (int, error) {
+    parts := strings.Split(value, ":")
+    numbers := []int{}
+    for _, part := range parts {
+        // only digits, so signs (Ex: -0) are not taken
+        number, err := strconv.Atoi(part)
+        if err != nil || strings.Trim(part, "0123456789") != "" {
+            return 0, fmt.Errorf("%q should be seconds or minutes:seconds", value)
+        }
+        numbers = append(numbers, number)
+    }
+    switch {
+    case len(numbers) == 1:
+        return numbers[0], nil
+    case len(numbers) != 2:
+        return 0, fmt.Errorf("%q should be seconds or minutes:seconds", value)
+    case numbers[1] >= 60:
+        return 0, fmt.Errorf("%q should have less than 60 seconds after the minutes", value)
+    }
+    return numbers[0]*60+numbers[1], nil
+}
+
+/*
+    Function: formatMaze
+    Write a maze in the format of the maze files. The header is written only if the maze has something for it
+    The grid is written with the tiles of the game, so the legend is not needed
+    Input: maze
+*/
+func formatMaze(maze Maze) string {
+    header := []string{}
+    if maze.name != "" {
+        header = append(header, "name: "+maze.name)
+    }
+    if maze.author != "" {
+        header = append(header, "author: "+maze.author)
+    }
+    if maze.pacmanSpeed > 0 {
+        header = append(header, "pacman speed: "+strconv.FormatFloat(maze.pacmanSpeed, 'g', -1, 64))
+    }
+    if maze.enemySpeed > 0 {
+        header = append(header, "enemy speed: "+strconv.FormatFloat(maze.enemySpeed, 'g', -1, 64))
+    }
+    if maze.enemies != nil {
+        enemies := []string{}
+        for _, aggression := range maze.enemies {
+            enemies = append(enemies, strconv.FormatFloat(aggression, 'g', -1, 64))
+        }
+        header = append(header, "enemies: "+strings.Join(enemies, ", "))
+    }
+    if maze.wallColor.A != 0 {
+        clr := maze.wallColor
+        header = append(header, fmt.Sprintf("wall color: #%02x%02x%02x", clr.R, clr.G, clr.B))
+    }
+    if maze.theme != "" {
+        header = append(header, "theme: "+maze.theme)
+    }
+    if maze.parTime > 0 {
+        header = append(header, "par time: "+formatParTime(maze.parTime))
+    }
+    if maze.fruit != nil {
+        header = append(header, fmt.Sprintf("fruit: %d,%d", maze.fruit[0], maze.fruit[1]))
+    }
+    for _, tunnel := range maze.tunnels {
+        header = append(header, fmt.Sprintf("tunnel: %d,%d %d,%d", tunnel[0][0], tunnel[0][1], tunnel[1][0], tunnel[1][1]))
+    }
+    if len(header) > 0 {
+        header = append(header, mazeHeaderEnd)
+    }
+    return strings.Join(append(header, maze.rows...), "\n")+"\n"
+}
+
+/*
+    Function: formatParTime
+    Write a par time as minutes:seconds
+    Input: par time in seconds
+*/
+func formatParTime(parTime int) string {
+    return fmt.Sprintf("%d:%02d", parTime/60, parTime%60)
+}
+
+/*
+    Function: copyMaze
+    Get a copy of the rows of a maze, so changing a row (Ex: eating food) doesn't change the maze it came from
+    Input: rows of the maze
+*/
+func copyMaze(maze []string) []string {
+    return append([]string{}, maze...)
+}
+
+/*
+    Function: getLevelMaze
+    Get the maze of a level. The maze file of each level is read only once. The maze tested in the editor is level 1
+    Input: level
+*/
+func getLevelMaze(level int) Maze {
+    if isEditorPlaying() {
+        return mazeEditor.getMaze()
+    }
+    fileName := LEVELS[level].mazeFile
+    if maze, ok := loadedMazes[fileName]; ok {
+        return maze
+    }
+    maze := readMazeFile(fileName)
+    loadedMazes[fileName] = maze
+    return maze
+}
+
+/*
+    Function: getEnemyAggressions
+    Get how often each enemy of a level chases PacMan. The enemies of the maze are used first, then the level
+    Input: level
+*/
+func getEnemyAggressions(level int) []float64 {
+    if enemies := getLevelMaze(level).enemies; enemies != nil {
+        return enemies
+    }
+    aggressions := make([]float64, LEVELS[level].numEnemies)
+    for i := range aggressions {
+        aggressions[i] = LEVELS[level].enemyAggression
+    }
+    return aggressions
+}
+
+/*
+    Function: getPacmanSpeed
+    Get the speed of PacMan on a level. The speed of the maze is used first, then the level
+    Input: level
+*/
+func getPacmanSpeed(level int) float64 {
+    if speed := getLevelMaze(level).pacmanSpeed; speed > 0 {
+        return speed
+    }
+    return LEVELS[level].pacmanSpeed
+}
+
+/*
+    Function: getEnemySpeed
+    Get the speed of the enemies on a level. The speed of the maze is used first, then the level
+    Input: level
+*/
+func getEnemySpeed(level int) float64 {
+    if speed := getLevelMaze(level).enemySpeed; speed > 0 {
+        return speed
+    }
+    return LEVELS[level].enemySpeed
+}
+
+/*
+    Function: getMazeInfo
+    Get the name, the author and the par time of a maze as a line of text. It's empty if the maze file doesn't give them
+    Input: maze
+*/
+func getMazeInfo(maze Maze) string {
+    info := []string{}
+    if maze.name != "" {
+        info = append(info, strings.ToUpper(maze.name))
+    }
+    if maze.author != "" {
+        info = append(info, "BY "+strings.ToUpper(maze.author))
+    }
+    if maze.parTime > 0 {
+        info = append(info, "PAR "+formatParTime(maze.parTime))
+    }
+    return strings.Join(info, "  ")
+}
+
+/*
+    Function: drawMazeInfo
+    Draw the name, the author and the par time of the maze of the current level at the top of the playfield
+    Input: screen
+*/
+func drawMazeInfo(screen *Image) {
+    if info := getMazeInfo(getLevelMaze(gameInfo.level)); info != "" {
+        drawTextCentered(screen, info, screenSizeX/2, playfieldY+blockSize, getTextColor())
+    }
+}
+
+/*
+    Function: useTunnel
+    Move a PacMan or an enemy which gets to the center of a tunnel end to the other end of the tunnel. It comes out a step
+    away from the center of the other end in the same direction, so it doesn't go back into the tunnel right away
+    Input: reference to a moving game object
+*/
+func useTunnel(sprite *Sprite) {
+    step, ok := autopilotSteps[sprite.direction]
+    if !ok {
+        return
+    }
+    col, row := getMazePointFromPosition(sprite.x, sprite.y)
+    centerX, centerY := getPositionFromMazePoint(col, row)
+    if math.Abs(sprite.x-centerX)+math.Abs(sprite.y-centerY) >= sprite.speed {
+        return
+    }
+    for _, tunnel := range getLevelMaze(gameInfo.level).tunnels {
+        for end, point := range tunnel {
+            if point != [2]int{col, row} {
+                continue
+            }
+            exitX, exitY := getPositionFromMazePoint(tunnel[1-end][0], tunnel[1-end][1])
+            sprite.x = exitX+float64(step[0])*sprite.speed
+            sprite.y = exitY+float64(step[1])*sprite.speed
+            return
+        }
+    }
+}
@@ -0,0 +1,40 @@
+package main
+
+/*
+    This file contains the tests of the maze files (see maze.go).
+
+    The broken mazes of testdata (testdata/bad-maze-*.txt) can't be played: no start, rows of different lengths, no
+    food, a tunnel into a wall, an unknown tile or food which can't be reached. They're rejected when they're loaded
+    instead of crashing the game, and the rl package rejects the same files (see rl/env_test.go).
+*/
+import (
+    "path/filepath"
+    "testing"
+)
+
+func TestLoadMazeFileRejectsBrokenMazes(t *testing.T) {
+    mazeFiles, err := filepath.Glob(filepath.Join("testdata", "bad-maze-*.txt"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(mazeFiles) == 0 {
+        t.Fatal("there are no broken mazes in testdata")
+    }
+    for _, mazeFile := range mazeFiles {
+        if _, err := loadMazeFile(mazeFile); err == nil {
+            t.Errorf("%s can't be played, it should be rejected", mazeFile)
+        }
+        // the editor still opens it, to fix it
+        if _, err := openMazeFile(mazeFile); err != nil {
+            t.Errorf("%s: the editor can't open it: %v", mazeFile, err)
+        }
+    }
+}
+
+func TestLoadMazeFileLevels(t *testing.T) {
+    for level, info := range LEVELS {
+        if _, err := loadMazeFile(info.mazeFile); err != nil {
+            t.Errorf("level %d: %v", level, err)
+        }
+    }
+}
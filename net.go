@@ -0,0 +1,938 @@
+package main
+
+/*
+    This file contains the networked game.
+
+    One game is the host (-host flag). It runs the game like a normal game and other players join it over TCP on the
+    local network (-join flag). Only the host runs the game (server authoritative). A player who joined sends the
+    direction they press, and gets the state of the game (snapshot) back on each frame to draw it.
+
+    Player 1 plays on the host. A player who joins gets the next free player number (2 to 4): their PacMan in the
+    co-op mode, or the second player (PacMan or the enemy, whichever role it has in the round) in the versus mode.
+    Players who joined use the arrow keys. If a player is disconnected, the autopilot (see autopilot.go) moves their
+    PacMan, or the game moves their enemy like the other enemies, until a player joins again.
+
+    Snapshots are delta compressed: only the bytes which changed from the previous snapshot are sent (see encodeDelta).
+    TCP delivers every message in order, so the previous snapshot is always there to apply the changes to.
+
+    Each message sent by the host starts with a type (1 byte) and the size of the data (4 bytes):
+    H - hello, sent when a player joins. The data is the protocol version and the player number
+    R - rejected, the game is full or the protocol version doesn't match. The data is the reason
+    F - full snapshot, the data is the snapshot (see encodeSnapshot)
+    D - delta snapshot, the data is the changes from the previous snapshot
+    A player who joins sends the protocol version (1 byte) first, and then a single byte each time their direction
+    changes: U, R, D, L or I
+*/
+import (
+    "bufio"
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "image/color"
+    "io"
+    "log"
+    "math"
+    "net"
+    "sync"
+    "time"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure which keeps a player who joined the host
+type RemotePlayer struct {
+    number int // holds the player number, which is the position of the player in gameInfo.stats (1 to 3)
+    conn net.Conn // holds the connection to the player
+    input byte // holds the direction the player is pressing
+    snapshots chan []byte // holds the latest snapshot waiting to be sent. If the player is slow, older snapshots are dropped
+    done chan struct{} // closed when the player is disconnected
+}
+
+// Structure which keeps the host of a networked game
+type NetHost struct {
+    listener net.Listener // holds the listener which accepts the players
+    mutex sync.Mutex // players join and leave in other goroutines, so the fields below are guarded by a mutex
+    players map[int]*RemotePlayer // holds the players who joined, by player number
+    tick uint32 // holds the number of snapshots sent
+    lastSnapshot []byte // holds the last snapshot sent to the players
+    fullBytes int // holds the number of bytes sent as full snapshots
+    deltaBytes int // holds the number of bytes sent as delta snapshots
+    snapshotBytes int // holds the number of bytes the delta snapshots would take as full snapshots
+    announceConn *net.UDPConn // holds the UDP socket which sends the announcements of the game (nil if not announced, see discovery.go)
+    announceAddress *net.UDPAddr // holds the address the announcements are sent to
+    announceTimer int // holds the number of frames until the next announcement
+    gameName string // holds the name of the game in the announcements
+}
+
+// Structure which keeps a player who joined a host
+type NetClient struct {
+    conn net.Conn // holds the connection to the host
+    number int // holds the player number given by the host
+    lastInput byte // holds the last direction sent to the host
+    mutex sync.Mutex // snapshots are read in another goroutine, so the fields below are guarded by a mutex
+    snapshot []byte // holds the latest snapshot from the host
+    err error // holds the error which closed the connection
+}
+
+// Structure which is at the start of a snapshot. Fields are exported and have fixed sizes, so encoding/binary can write them
+type NetHeader struct {
+    Tick uint32
+    Level int32
+    Score int32
+    MaxScore int32
+    Fruits int32
+    FrightenedTimer int32
+    FruitTimer int32
+    FreezeTimer int32
+    IsStarted bool
+    IsGameOver bool
+    IsLevelComplete bool
+    IsNewGame bool
+    NumPlayers int32
+    CurrentPlayer int32
+    HighScore int32
+    IsVersus bool
+    VersusRound int32
+    VersusRounds int32
+    VersusPacmanPlayer int32
+    VersusScores [2][2]int32 // dots and catches of each player
+    MazeRows int32
+    NumStats int32
+    NumEnemies int32
+}
+
+// Structure which keeps the lives and points of a player in a snapshot
+type NetStats struct {
+    Lives int32
+    Points int32
+}
+
+// Structure which keeps a game object in a snapshot
+type NetSprite struct {
+    X float32
+    Y float32
+    Direction byte
+    Visibility bool
+    IsFrightened bool
+}
+
+// Structure which keeps a decoded snapshot
+type NetSnapshot struct {
+    header NetHeader // holds the game info
+    maze []string // holds the maze with the food which is left
+    stats []NetStats // holds the lives and points of each player
+    pacmen []NetSprite // holds the PacMen
+    enemies []NetSprite // holds the enemies
+    fruit NetSprite // holds the fruit
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the version of the messages. A player with a different version can't join
+var netProtocolVersion byte = 1
+
+// Let's define the port used when the address doesn't have one
+var netDefaultPort = "7777"
+
+// Let's define the biggest message which is accepted, so a broken message doesn't use up the memory
+var netMaxMessage = 1 << 20
+
+// Let's define how many unchanged bytes can be between changed bytes to keep them in the same part of a delta snapshot
+var netDeltaGap = 4
+
+// Variable to hold the host of the networked game (nil if this game isn't the host)
+var netHost *NetHost
+
+// Variable to hold the connection to the host (nil if this game didn't join a host)
+var netClient *NetClient
+
+/*
+    ##########################################
+    ## Functions to read and write messages ##
+    ##########################################
+*/
+
+/*
+    Function: getNetAddress
+    Add the default port to an address if it doesn't have a port
+    Input: address (Ex: 192.168.1.10 or 192.168.1.10:7777)
+*/
+func getNetAddress(address string) string {
+    if _, _, err := net.SplitHostPort(address); err != nil {
+        return net.JoinHostPort(address, netDefaultPort)
+    }
+    return address
+}
+
+/*
+    Function: writeMessage
+    Write a message with its type and the size of the data
+    Inputs: writer, type of the message and the data
+*/
+func writeMessage(writer io.Writer, kind byte, data []byte) error {
+    message := make([]byte, 5, 5+len(data))
+    message[0] = kind
+    binary.BigEndian.PutUint32(message[1:], uint32(len(data)))
+    _, err := writer.Write(append(message, data...))
+    return err
+}
+
+/*
+    Function: readMessage
+    Read a message written by writeMessage
+    Input: reader
+    Outputs the type of the message and the data
+*/
+func readMessage(reader io.Reader) (byte, []byte, error) {
+    header := make([]byte, 5)
+    if _, err := io.ReadFull(reader, header); err != nil {
+        return 0, nil, err
+    }
+
+    size := binary.BigEndian.Uint32(header[1:])
+    if int(size) > netMaxMessage {
+        return 0, nil, fmt.Errorf("message of %d bytes is too big", size)
+    }
+    data := make([]byte, size)
+    if _, err := io.ReadFull(reader, data); err != nil {
+        return 0, nil, err
+    }
+    return header[0], data, nil
+}
+
+/*
+    ##################################
+    ## Functions to build snapshots ##
+    ##################################
+*/
+
+/*
+    Function: encodeSnapshot
+    Write the state of the game into bytes. Everything is written with a fixed size, so the same game objects are always
+    at the same place in the bytes and the changes from the previous snapshot are small
+    Input: number of the snapshot
+*/
+func encodeSnapshot(tick uint32) []byte {
+    header := NetHeader{
+        Tick: tick,
+        Level: int32(gameInfo.level),
+        Score: int32(gameInfo.score),
+        MaxScore: int32(gameInfo.maxScore),
+        Fruits: int32(gameInfo.fruits),
+        FrightenedTimer: int32(gameInfo.frightenedTimer),
+        FruitTimer: int32(gameInfo.fruitTimer),
+        FreezeTimer: int32(gameInfo.freezeTimer),
+        IsStarted: gameInfo.isStarted,
+        IsGameOver: gameInfo.isGameOver,
+        IsLevelComplete: gameInfo.isLevelComplete,
+        IsNewGame: isNewGame,
+        NumPlayers: int32(numPlayers),
+        CurrentPlayer: int32(currentPlayer),
+        HighScore: int32(highScore),
+        IsVersus: isVersus,
+        VersusRound: int32(versusRound),
+        VersusRounds: int32(versusRounds),
+        VersusPacmanPlayer: int32(versusPacmanPlayer),
+        MazeRows: int32(len(gameInfo.maze)),
+        NumStats: int32(len(gameInfo.stats)),
+        NumEnemies: int32(len(enemies)),
+    }
+    for i, score := range versusScores {
+        header.VersusScores[i] = [2]int32{int32(score.dots), int32(score.catches)}
+    }
+
+    // writing into a bytes.Buffer doesn't fail, so the errors of binary.Write are not checked
+    buffer := &bytes.Buffer{}
+    binary.Write(buffer, binary.BigEndian, header)
+    for _, line := range gameInfo.maze {
+        binary.Write(buffer, binary.BigEndian, int32(len(line)))
+        buffer.WriteString(line)
+    }
+    for _, stats := range gameInfo.stats {
+        binary.Write(buffer, binary.BigEndian, NetStats{Lives: int32(stats.lives), Points: int32(stats.points)})
+    }
+    for _, pacman := range pacmen {
+        binary.Write(buffer, binary.BigEndian, getNetSprite(pacman))
+    }
+    for _, enemy := range enemies {
+        binary.Write(buffer, binary.BigEndian, getNetSprite(enemy))
+    }
+    binary.Write(buffer, binary.BigEndian, getNetSprite(&fruit))
+    return buffer.Bytes()
+}
+
+/*
+    Function: getNetSprite
+    Get the part of a game object which is sent in a snapshot
+    Input: reference to a game object
+*/
+func getNetSprite(sprite *Sprite) NetSprite {
+    return NetSprite{
+        X: float32(sprite.x),
+        Y: float32(sprite.y),
+        Direction: sprite.direction,
+        Visibility: sprite.visibility,
+        IsFrightened: sprite.isFrightened,
+    }
+}
+
+/*
+    Function: decodeSnapshot
+    Read a snapshot written by encodeSnapshot
+    Input: bytes of the snapshot
+*/
+func decodeSnapshot(data []byte) (NetSnapshot, error) {
+    snapshot := NetSnapshot{}
+    reader := bytes.NewReader(data)
+    if err := binary.Read(reader, binary.BigEndian, &snapshot.header); err != nil {
+        return snapshot, err
+    }
+
+    header := snapshot.header
+    if header.MazeRows <= 0 || int(header.MazeRows) > len(data) || header.NumStats <= 0 || int(header.NumStats) > len(data) || header.NumEnemies < 0 || int(header.NumEnemies) > len(data) {
+        return snapshot, errors.New("snapshot has wrong sizes")
+    }
+
+    for i := 0; i < int(header.MazeRows); i++ {
+        var length int32
+        if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+            return snapshot, err
+        }
+        if length < 0 || int(length) > reader.Len() {
+            return snapshot, errors.New("snapshot has a wrong maze row")
+        }
+        line := make([]byte, length)
+        reader.Read(line)
+        snapshot.maze = append(snapshot.maze, string(line))
+    }
+
+    snapshot.stats = make([]NetStats, header.NumStats)
+    snapshot.pacmen = make([]NetSprite, header.NumStats)
+    snapshot.enemies = make([]NetSprite, header.NumEnemies)
+    for _, part := range []interface{}{snapshot.stats, snapshot.pacmen, snapshot.enemies, &snapshot.fruit} {
+        if err := binary.Read(reader, binary.BigEndian, part); err != nil {
+            return snapshot, err
+        }
+    }
+    return snapshot, nil
+}
+
+/*
+    Function: encodeDelta
+    Get the changes from the previous snapshot to the current snapshot (both have the same size)
+    The changes are a list of parts. Each part has the number of bytes which didn't change (uvarint), the number of
+    changed bytes (uvarint) and the changed bytes. Changed bytes with only a few unchanged bytes between them are kept
+    in the same part, because a new part would take more bytes than the gap
+    Inputs: previous snapshot and the current snapshot
+*/
+func encodeDelta(previous []byte, current []byte) []byte {
+    delta := []byte{}
+    number := make([]byte, binary.MaxVarintLen64)
+    position := 0
+    for position < len(current) {
+        // skip the bytes which didn't change
+        start := position
+        for start < len(current) && current[start] == previous[start] {
+            start++
+        }
+        if start == len(current) {
+            break
+        }
+
+        // find the end of the changed bytes
+        end := start+1
+        for end < len(current) {
+            if current[end] != previous[end] {
+                end++
+                continue
+            }
+            gap := end
+            for gap < len(current) && gap-end < netDeltaGap && current[gap] == previous[gap] {
+                gap++
+            }
+            if gap == len(current) || gap-end >= netDeltaGap {
+                break
+            }
+            end = gap
+        }
+
+        delta = append(delta, number[:binary.PutUvarint(number, uint64(start-position))]...)
+        delta = append(delta, number[:binary.PutUvarint(number, uint64(end-start))]...)
+        delta = append(delta, current[start:end]...)
+        position = end
+    }
+    return delta
+}
+
+/*
+    Function: applyDelta
+    Apply the changes made by encodeDelta to the previous snapshot
+    Inputs: previous snapshot and the changes
+    Outputs the current snapshot
+*/
+func applyDelta(previous []byte, delta []byte) ([]byte, error) {
+    current := append([]byte{}, previous...)
+    reader := bytes.NewReader(delta)
+    position := 0
+    for reader.Len() > 0 {
+        skip, err := binary.ReadUvarint(reader)
+        if err != nil {
+            return nil, err
+        }
+        count, err := binary.ReadUvarint(reader)
+        if err != nil {
+            return nil, err
+        }
+        if skip > uint64(len(current)) || count > uint64(len(current)) || position+int(skip)+int(count) > len(current) || int(count) > reader.Len() {
+            return nil, errors.New("delta snapshot doesn't fit the previous snapshot")
+        }
+        position = position+int(skip)
+        reader.Read(current[position:position+int(count)])
+        position = position+int(count)
+    }
+    return current, nil
+}
+
+/*
+    ###################################
+    ## Functions of the host (-host) ##
+    ###################################
+*/
+
+/*
+    Function: startHost
+    Start accepting players on the given address
+    Input: address to listen on (Ex: :7777)
+*/
+func startHost(address string) (*NetHost, error) {
+    listener, err := net.Listen("tcp", getNetAddress(address))
+    if err != nil {
+        return nil, err
+    }
+
+    host := &NetHost{
+        listener: listener,
+        players: map[int]*RemotePlayer{},
+    }
+    go host.acceptPlayers()
+    return host, nil
+}
+
+/*
+    Function: acceptPlayers
+    Accept players until the listener is closed
+*/
+func (host *NetHost) acceptPlayers() {
+    for {
+        conn, err := host.listener.Accept()
+        if err != nil {
+            return
+        }
+        go host.addPlayer(conn)
+    }
+}
+
+/*
+    Function: addPlayer
+    Check the protocol version of a new player and give it a free player number
+    Input: connection to the player
+*/
+func (host *NetHost) addPlayer(conn net.Conn) {
+    version := make([]byte, 1)
+    conn.SetReadDeadline(time.Now().Add(5*time.Second))
+    if _, err := io.ReadFull(conn, version); err != nil {
+        conn.Close()
+        return
+    }
+    conn.SetReadDeadline(time.Time{})
+    if version[0] != netProtocolVersion {
+        writeMessage(conn, 'R', []byte(fmt.Sprintf("the host has version %d, this game has version %d", netProtocolVersion, version[0])))
+        conn.Close()
+        return
+    }
+
+    // let's find a free player number. Player 1 (number 0) is on the host
+    host.mutex.Lock()
+    number := 0
+    for i := 1; i < len(playerKeys); i++ {
+        if _, ok := host.players[i]; !ok {
+            number = i
+            break
+        }
+    }
+    if number == 0 {
+        host.mutex.Unlock()
+        writeMessage(conn, 'R', []byte("the game is full"))
+        conn.Close()
+        return
+    }
+
+    player := &RemotePlayer{
+        number: number,
+        conn: conn,
+        input: 'I',
+        snapshots: make(chan []byte, 1),
+        done: make(chan struct{}),
+    }
+    host.players[number] = player
+    host.mutex.Unlock()
+
+    log.Println("player", number+1, "joined from", conn.RemoteAddr())
+    if err := writeMessage(conn, 'H', []byte{netProtocolVersion, byte(number)}); err != nil {
+        host.removePlayer(player)
+        return
+    }
+    go host.sendSnapshots(player)
+    host.readInputs(player)
+}
+
+/*
+    Function: removePlayer
+    Close the connection to a player and give the player to the autopilot
+    Input: reference to the player
+*/
+func (host *NetHost) removePlayer(player *RemotePlayer) {
+    host.mutex.Lock()
+    defer host.mutex.Unlock()
+    if host.players[player.number] != player {
+        return
+    }
+    delete(host.players, player.number)
+    close(player.done)
+    player.conn.Close()
+    log.Println("player", player.number+1, "disconnected, the autopilot takes over")
+}
+
+/*
+    Function: readInputs
+    Read the directions sent by a player until the player is disconnected
+    Input: reference to the player
+*/
+func (host *NetHost) readInputs(player *RemotePlayer) {
+    defer host.removePlayer(player)
+
+    reader := bufio.NewReader(player.conn)
+    for {
+        input, err := reader.ReadByte()
+        if err != nil {
+            return
+        }
+        switch input {
+        case 'U', 'R', 'D', 'L', 'I':
+            host.mutex.Lock()
+            player.input = input
+            host.mutex.Unlock()
+        default:
+            log.Println("player", player.number+1, "sent an unknown input:", input)
+        }
+    }
+}
+
+/*
+    Function: sendSnapshots
+    Send the snapshots to a player until the player is disconnected. The first snapshot is a full snapshot, others are
+    delta snapshots unless the size of the snapshot changes (Ex: new level)
+    Input: reference to the player
+*/
+func (host *NetHost) sendSnapshots(player *RemotePlayer) {
+    var previous []byte
+    for {
+        var snapshot []byte
+        select {
+        case snapshot = <-player.snapshots:
+        case <-player.done:
+            return
+        }
+
+        kind, data := byte('F'), snapshot
+        if len(previous) == len(snapshot) {
+            kind, data = 'D', encodeDelta(previous, snapshot)
+        }
+        if err := writeMessage(player.conn, kind, data); err != nil {
+            // the connection is broken, closing it stops readInputs which removes the player
+            player.conn.Close()
+            return
+        }
+        previous = snapshot
+
+        host.mutex.Lock()
+        if kind == 'F' {
+            host.fullBytes = host.fullBytes+len(data)
+        } else {
+            host.deltaBytes = host.deltaBytes+len(data)
+            host.snapshotBytes = host.snapshotBytes+len(snapshot)
+        }
+        host.mutex.Unlock()
+    }
+}
+
+/*
+    Function: broadcast
+    Send the state of the game to all the players. This is called after each frame of the game
+    If a player hasn't sent the previous snapshot yet, it's replaced with this one
+*/
+func (host *NetHost) broadcast() {
+    snapshot := encodeSnapshot(host.tick)
+
+    host.mutex.Lock()
+    defer host.mutex.Unlock()
+    host.tick++
+    host.lastSnapshot = snapshot
+    for _, player := range host.players {
+        select {
+        case <-player.snapshots:
+        default:
+        }
+        player.snapshots <- snapshot
+    }
+}
+
+/*
+    Function: getNetRatio
+    Get how big the delta snapshots are compared to full snapshots (0 to 1), to see how well they are compressed
+*/
+func (host *NetHost) getNetRatio() float64 {
+    host.mutex.Lock()
+    defer host.mutex.Unlock()
+    if host.snapshotBytes == 0 {
+        return math.NaN()
+    }
+    return float64(host.deltaBytes)/float64(host.snapshotBytes)
+}
+
+/*
+    Function: close
+    Stop accepting players and disconnect all the players
+*/
+func (host *NetHost) close() {
+    host.listener.Close()
+    if host.announceConn != nil {
+        host.announceConn.Close()
+    }
+    host.mutex.Lock()
+    players := []*RemotePlayer{}
+    for _, player := range host.players {
+        players = append(players, player)
+    }
+    host.mutex.Unlock()
+    for _, player := range players {
+        host.removePlayer(player)
+    }
+}
+
+/*
+    Function: isRemotePlayer
+    Check if a player plays from another game over the network (all the players except player 1 when hosting)
+    Input: player (position in gameInfo.stats, or the player number in the versus mode)
+*/
+func isRemotePlayer(player int) bool {
+    return netHost != nil && player > 0
+}
+
+/*
+    Function: getRemoteInput
+    Get the direction a remote player is pressing. A disconnected player is idle
+    Input: player
+*/
+func getRemoteInput(player int) byte {
+    netHost.mutex.Lock()
+    defer netHost.mutex.Unlock()
+    if remote, ok := netHost.players[player]; ok {
+        return remote.input
+    }
+    return 'I'
+}
+
+/*
+    Function: isAIPlayer
+    Check if a player should be moved by the game, because it's a remote player who is not connected
+    Input: player
+*/
+func isAIPlayer(player int) bool {
+    // the autopilot plays player 1 in the demo (see demo.go). Nobody plays a headless game (see spectate.go),
+    // so the autopilot plays player 1 there too, unless a bot plays it (see bot.go)
+    if isDemo && player == 0 {
+        return true
+    }
+    if isHeadless && player == 0 && (gameBot == nil || gameBot.isStopped) {
+        return true
+    }
+    if !isRemotePlayer(player) {
+        return false
+    }
+    netHost.mutex.Lock()
+    defer netHost.mutex.Unlock()
+    _, ok := netHost.players[player]
+    return !ok
+}
+
+/*
+    Function: getRemotePlayerCount
+    Get the number of players who joined the host
+*/
+func getRemotePlayerCount() int {
+    if netHost == nil {
+        return 0
+    }
+    netHost.mutex.Lock()
+    defer netHost.mutex.Unlock()
+    return len(netHost.players)
+}
+
+/*
+    ###################################
+    ## Functions of a player (-join) ##
+    ###################################
+*/
+
+/*
+    Function: joinGame
+    Connect to a host and get a player number
+    Input: address of the host (Ex: 192.168.1.10:7777)
+*/
+func joinGame(address string) (*NetClient, error) {
+    conn, err := net.DialTimeout("tcp", getNetAddress(address), 5*time.Second)
+    if err != nil {
+        return nil, err
+    }
+    if _, err := conn.Write([]byte{netProtocolVersion}); err != nil {
+        conn.Close()
+        return nil, err
+    }
+
+    reader := bufio.NewReader(conn)
+    kind, data, err := readMessage(reader)
+    if err != nil {
+        conn.Close()
+        return nil, err
+    }
+    if kind == 'R' {
+        conn.Close()
+        return nil, fmt.Errorf("the host rejected the game: %s", data)
+    }
+    if kind != 'H' || len(data) != 2 {
+        conn.Close()
+        return nil, errors.New("the host sent a wrong hello message")
+    }
+
+    client := &NetClient{
+        conn: conn,
+        number: int(data[1]),
+        lastInput: 'I',
+    }
+    go client.readSnapshots(reader)
+    return client, nil
+}
+
+/*
+    Function: readSnapshots
+    Read the snapshots sent by the host and keep the latest one, until the connection is closed
+    Input: reader of the connection
+*/
+func (client *NetClient) readSnapshots(reader io.Reader) {
+    var snapshot []byte
+    var err error
+    for err == nil {
+        var kind byte
+        var data []byte
+        kind, data, err = readMessage(reader)
+        if err != nil {
+            break
+        }
+
+        switch kind {
+        case 'F':
+            snapshot = data
+        case 'D':
+            snapshot, err = applyDelta(snapshot, data)
+        default:
+            err = fmt.Errorf("the host sent an unknown message: %c", kind)
+        }
+        if err == nil {
+            client.mutex.Lock()
+            client.snapshot = snapshot
+            client.mutex.Unlock()
+        }
+    }
+
+    client.mutex.Lock()
+    client.err = err
+    client.mutex.Unlock()
+}
+
+/*
+    Function: getSnapshot
+    Get the latest snapshot from the host, or the error which closed the connection
+*/
+func (client *NetClient) getSnapshot() ([]byte, error) {
+    client.mutex.Lock()
+    defer client.mutex.Unlock()
+    return client.snapshot, client.err
+}
+
+/*
+    Function: sendInput
+    Send the direction the player is pressing to the host, if it changed
+    Input: direction U, R, D, L or I
+*/
+func (client *NetClient) sendInput(input byte) error {
+    if input == client.lastInput {
+        return nil
+    }
+    client.lastInput = input
+    _, err := client.conn.Write([]byte{input})
+    return err
+}
+
+/*
+    Function: applySnapshot
+    Change the game to the state in a snapshot, so it can be drawn
+    When the level or the number of game objects changes, the game objects are created again from the maze (see setMaze)
+    Input: decoded snapshot
+*/
+func applySnapshot(snapshot NetSnapshot) error {
+    header := snapshot.header
+    if _, ok := LEVELS[int(header.Level)]; !ok {
+        return fmt.Errorf("snapshot has an unknown level %d", header.Level)
+    }
+
+    gameInfo.stats = []PlayerStats{}
+    for _, stats := range snapshot.stats {
+        gameInfo.stats = append(gameInfo.stats, PlayerStats{lives: int(stats.Lives), points: int(stats.Points)})
+    }
+
+    // let's change the level and the maze, and show or hide the food which changed
+    setMaze(int(header.Level), snapshot.maze)
+    if len(snapshot.enemies) != len(enemies) {
+        return errors.New("snapshot has a different number of enemies than the level")
+    }
+
+    gameInfo.score = int(header.Score)
+    gameInfo.maxScore = int(header.MaxScore)
+    gameInfo.fruits = int(header.Fruits)
+    gameInfo.frightenedTimer = int(header.FrightenedTimer)
+    gameInfo.fruitTimer = int(header.FruitTimer)
+    gameInfo.freezeTimer = int(header.FreezeTimer)
+    gameInfo.isStarted = header.IsStarted
+    gameInfo.isGameOver = header.IsGameOver
+    gameInfo.isLevelComplete = header.IsLevelComplete
+    isNewGame = header.IsNewGame
+    numPlayers = int(header.NumPlayers)
+    currentPlayer = int(header.CurrentPlayer)
+    highScore = int(header.HighScore)
+    isVersus = header.IsVersus
+    versusRound = int(header.VersusRound)
+    versusRounds = int(header.VersusRounds)
+    versusPacmanPlayer = int(header.VersusPacmanPlayer)
+    for i, score := range header.VersusScores {
+        versusScores[i] = VersusScore{dots: int(score[0]), catches: int(score[1])}
+    }
+
+    for i, pacman := range pacmen {
+        setNetSprite(pacman, snapshot.pacmen[i])
+        if face := pacman.faces[pacman.direction]; face != nil {
+            pacman.img = face
+        }
+    }
+    for i, enemy := range enemies {
+        setNetSprite(enemy, snapshot.enemies[i])
+        enemy.img = enemy.faces['N']
+        if enemy.isFrightened {
+            enemy.img = enemy.faces['F']
+        }
+        enemy.tint = color.RGBA{}
+    }
+    // the first enemy is steered by a player in the versus mode
+    if isVersus && len(enemies) > 0 {
+        enemies[0].tint = versusEnemyColor
+    }
+    setNetSprite(&fruit, snapshot.fruit)
+    return nil
+}
+
+/*
+    Function: setNetSprite
+    Change a game object to the state in a snapshot
+    Inputs: reference to the game object and the game object in the snapshot
+*/
+func setNetSprite(sprite *Sprite, netSprite NetSprite) {
+    sprite.x = float64(netSprite.X)
+    sprite.y = float64(netSprite.Y)
+    sprite.direction = netSprite.Direction
+    sprite.visibility = netSprite.Visibility
+    sprite.isFrightened = netSprite.IsFrightened
+}
+
+/*
+    Function: updateClient
+    The update function of a player who joined a host (instead of update in main.go)
+    The direction pressed is sent to the host and the latest snapshot from the host is drawn
+*/
+func updateClient(screen *Image) error {
+    // spectators only watch the game, they don't send directions (see spectate.go)
+    if !netClient.isSpectator() {
+        if err := netClient.sendInput(getPlayerInput(0)); err != nil {
+            return fmt.Errorf("connection to the host is lost: %v", err)
+        }
+    }
+
+    data, err := netClient.getSnapshot()
+    if err != nil {
+        return fmt.Errorf("connection to the host is lost: %v", err)
+    }
+    if data != nil {
+        snapshot, err := decodeSnapshot(data)
+        if err != nil {
+            return err
+        }
+        if err := applySnapshot(snapshot); err != nil {
+            return err
+        }
+    }
+
+    // play the siren only while PacMan and enemies are moving
+    updateSiren(data != nil && gameInfo.isStarted && !gameInfo.isLevelComplete && !gameInfo.isGameOver && gameInfo.freezeTimer == 0)
+
+    // When M is pressed, turn the sound on or off
+    if isKeyJustPressed(KeyM) {
+        toggleMute()
+    }
+
+    if isDrawingSkipped() {
+        return nil
+    }
+    drawGame(screen)
+    return nil
+}
+
+/*
+    Function: drawJoinedPlayers
+    Show the number of players who joined the host under the prompt of the start screen
+    Input: screen
+*/
+func drawJoinedPlayers(screen *Image) {
+    w, h := startLogo.img.Size()
+    x := playfieldX+int(startLogo.x)+w/2
+    y := playfieldY+int(startLogo.y)+h+blockSize
+    drawTextCentered(screen, "PLAYERS JOINED: "+fmt.Sprint(getRemotePlayerCount()), x, y, getTextColor())
+}
+
+/*
+    Function: getSpacePrompt
+    Get the prompt of a screen which waits for space. Only the host can press space, so players who joined wait for it
+    Input: prompt shown on the host
+*/
+func getSpacePrompt(prompt string) string {
+    if netClient != nil {
+        return "Waiting for the host"
+    }
+    return prompt
+}
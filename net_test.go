@@ -0,0 +1,216 @@
+package main
+
+/*
+    This file contains the tests of the networked game (see net.go).
+
+    A host and two players run in the test and talk over TCP on 127.0.0.1, without opening the game window.
+    The host plays a co-op game with three PacMen. The players who joined press the directions the autopilot gives
+    for their PacMen, like a good player would.
+    The game is also streamed, and a spectator starts watching the stream in the middle of the game (see spectate.go).
+*/
+import (
+    "bytes"
+    "errors"
+    "testing"
+    "time"
+)
+
+// Let's define the number of frames the networked games of the tests run
+var netTestFrames = 600
+
+/*
+    Function: waitFor
+    Wait until a condition is true, or give an error after 2 seconds
+    Input: function which checks the condition
+*/
+func waitFor(condition func() bool) error {
+    deadline := time.Now().Add(2*time.Second)
+    for !condition() {
+        if time.Now().After(deadline) {
+            return errors.New("timed out")
+        }
+        time.Sleep(5*time.Millisecond)
+    }
+    return nil
+}
+
+/*
+    Function: checkLoopbackSnapshots
+    Check that the players have the same snapshot as the last snapshot of the host
+    Inputs: the test, host and the players
+*/
+func checkLoopbackSnapshots(t *testing.T, host *NetHost, clients []*NetClient) {
+    host.mutex.Lock()
+    expected := host.lastSnapshot
+    host.mutex.Unlock()
+
+    for _, client := range clients {
+        err := waitFor(func() bool {
+            snapshot, _ := client.getSnapshot()
+            return bytes.Equal(snapshot, expected)
+        })
+        if err != nil {
+            t.Fatalf("player %d doesn't have the snapshot of the host", client.number+1)
+        }
+
+        // the snapshot should also be readable
+        snapshot, _ := client.getSnapshot()
+        if _, err := decodeSnapshot(snapshot); err != nil {
+            t.Fatalf("player %d can't read the snapshot: %v", client.number+1, err)
+        }
+    }
+}
+
+func TestLoopback(t *testing.T) {
+    // the players get a player number and their directions move their PacMen on the host,
+    // and when a player is disconnected the autopilot takes over the PacMan of that player
+    host, err := startHost("127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    netHost = host
+    defer func() {
+        host.close()
+        netHost = nil
+        newGame()
+    }()
+
+    // let's join the host with two players
+    clients := []*NetClient{}
+    for i := 0; i < 2; i++ {
+        client, err := joinGame(host.listener.Addr().String())
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer client.conn.Close()
+        clients = append(clients, client)
+    }
+    if clients[0].number != 1 || clients[1].number != 2 {
+        t.Fatalf("players got the numbers %d and %d, expected 1 and 2", clients[0].number, clients[1].number)
+    }
+    if err := waitFor(func() bool { return getRemotePlayerCount() == 2 }); err != nil {
+        t.Fatal("the host didn't add both players")
+    }
+
+    // let's start a co-op game with the PacMan of the host and the PacMen of the two players
+    setScreenSize()
+    newGame()
+    startCoop(3)
+    isNewGame = false
+
+    moved := map[int]bool{}
+    for frame := 0; frame < netTestFrames; frame++ {
+        // the second player leaves in the middle of the game
+        if frame == netTestFrames/2 {
+            checkLoopbackSnapshots(t, host, clients)
+            clients[1].conn.Close()
+            if err := waitFor(func() bool { return isAIPlayer(2) }); err != nil {
+                t.Fatal("the host didn't notice the second player left")
+            }
+            clients = clients[:1]
+            moved[2] = false
+        }
+
+        // the players press the directions the autopilot gives for their PacMen
+        for _, client := range clients {
+            if err := client.sendInput(getAutopilotInput(pacmen[client.number])); err != nil {
+                t.Fatal(err)
+            }
+        }
+
+        positions := map[int][]float64{}
+        for _, pacman := range pacmen {
+            positions[pacman.player] = []float64{pacman.x, pacman.y}
+        }
+        updateGame()
+        host.broadcast()
+        for _, pacman := range pacmen {
+            if positions[pacman.player][0] != pacman.x || positions[pacman.player][1] != pacman.y {
+                moved[pacman.player] = true
+            }
+        }
+
+        // let's give the players some time to send their directions and read the snapshots
+        time.Sleep(time.Millisecond)
+    }
+
+    if !moved[1] {
+        t.Error("the PacMan of the first player didn't move")
+    }
+    if !moved[2] {
+        t.Error("the autopilot didn't move the PacMan of the second player after the player left")
+    }
+    checkLoopbackSnapshots(t, host, clients)
+
+    host.mutex.Lock()
+    t.Logf("snapshots: %d, full snapshots %d bytes, delta snapshots %d bytes", host.tick, host.fullBytes, host.deltaBytes)
+    host.mutex.Unlock()
+    t.Logf("delta snapshots are %.1f%% of the size of full snapshots", host.getNetRatio()*100)
+}
+
+func TestSpectator(t *testing.T) {
+    // a spectator who starts watching the stream in the middle of the game gets the same snapshots,
+    // and what the spectator sends doesn't change the game
+    host, err := startHost("127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    netHost = host
+    server, err := startStream("127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    spectatorServer = server
+    defer func() {
+        server.close()
+        spectatorServer = nil
+        host.close()
+        netHost = nil
+        newGame()
+    }()
+
+    setScreenSize()
+    newGame()
+    isNewGame = false
+
+    var spectator *NetClient
+    for frame := 0; frame < netTestFrames; frame++ {
+        // the spectator starts watching, and tries to send a direction
+        if frame == netTestFrames/4 {
+            spectator, err = watchGame(server.listener.Addr().String())
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer spectator.conn.Close()
+            if err := spectator.sendInput('U'); err != nil {
+                t.Fatal(err)
+            }
+        }
+
+        updateGame()
+        host.broadcast()
+        server.publish()
+        time.Sleep(time.Millisecond)
+    }
+
+    server.mutex.Lock()
+    expected := server.lastSnapshot
+    server.mutex.Unlock()
+    err = waitFor(func() bool {
+        snapshot, _ := spectator.getSnapshot()
+        return bytes.Equal(snapshot, expected)
+    })
+    if err != nil {
+        t.Error("the spectator doesn't have the snapshot of the stream")
+    }
+    if server.getSpectatorCount() != 1 {
+        t.Errorf("the stream has %d spectators, expected 1", server.getSpectatorCount())
+    }
+    if getRemotePlayerCount() != 0 {
+        t.Errorf("the host has %d players, the spectator shouldn't be one of them", getRemotePlayerCount())
+    }
+    // nobody presses a direction on the host, so PacMan stays where it starts
+    if pacmen[0].x != pacmen[0].startX || pacmen[0].y != pacmen[0].startY {
+        t.Error("the direction sent by the spectator moved PacMan")
+    }
+}
@@ -0,0 +1,204 @@
+//go:build nowindow
+// +build nowindow
+
+package main
+
+/*
+    This file contains the game without a window, built with:
+    go build -tags nowindow
+
+    ebiten opens a display when the game starts, even when the game is played headless or in the terminal. This build
+    leaves ebiten out (see window.go), so it runs on machines without a display (Ex: servers and containers):
+    - images only keep their size and nothing is drawn
+    - no key, mouse button or gamepad is ever pressed
+    - there's no sound (see audio.go)
+    - the game window and the editor can't be opened, only -headless, -tui and the modes which don't open a window work
+*/
+import (
+    "errors"
+    "golang.org/x/image/font"
+    "image"
+    "image/color"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure of an image which is never drawn, it only keeps its size
+type Image struct {
+    width int // holds the width of the image
+    height int // holds the height of the image
+}
+
+// Structure of the drawing options of an image, which do nothing
+type DrawImageOptions struct {
+    GeoM GeoM // holds the resizing and the moving of the image
+    ColorM ColorM // holds the tint of the image
+}
+
+// Structures of the resizing and the moving, and of the tint, of the drawing options
+type GeoM struct{}
+type ColorM struct{}
+
+// Let's give the keys and the mouse buttons numbers, they're never pressed
+type Key int
+type MouseButton int
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the keys and the mouse buttons used by the game
+const (
+    Key1 Key = iota
+    Key2
+    KeyA
+    KeyC
+    KeyD
+    KeyI
+    KeyJ
+    KeyK
+    KeyL
+    KeyM
+    KeyS
+    KeyT
+    KeyV
+    KeyW
+    KeyY
+    KeyZ
+    KeyKP4
+    KeyKP5
+    KeyKP6
+    KeyKP8
+    KeyF5
+    KeyF9
+    KeyUp
+    KeyRight
+    KeyDown
+    KeyLeft
+    KeyControl
+    KeyEnter
+    KeyEscape
+    KeySpace
+    KeyTab
+    KeyMax = KeyTab
+)
+const (
+    MouseButtonLeft MouseButton = iota
+    MouseButtonRight
+)
+
+// Let's define the error given when the window or the sound is used
+var errNoWindow = errors.New("the game is built without a window (nowindow tag), play it with -headless or -tui")
+
+/*
+    ################################
+    ## Functions of the no window ##
+    ################################
+*/
+func runWindow(update func(*Image) error, width int, height int, scale float64, title string) error {
+    return errNoWindow
+}
+func resizeWindow(width int, height int) {}
+func isDrawingSkipped() bool {
+    return true
+}
+
+/*
+    Function: newImage
+    Create an image which only keeps its size
+    Input: size of the image
+*/
+func newImage(width int, height int) (*Image, error) {
+    return &Image{width: width, height: height}, nil
+}
+
+/*
+    Function: newImageFromImage
+    Create an image with the size of a decoded image file
+    Input: decoded image
+*/
+func newImageFromImage(img image.Image) (*Image, error) {
+    size := img.Bounds().Size()
+    return newImage(size.X, size.Y)
+}
+
+func fillRect(img *Image, x float64, y float64, width float64, height float64, clr color.Color) {}
+func drawFontText(img *Image, str string, face font.Face, x int, y int, clr color.Color) {}
+
+func (img *Image) Size() (int, int) {
+    return img.width, img.height
+}
+func (img *Image) Fill(clr color.Color) error {
+    return nil
+}
+func (img *Image) DrawImage(src *Image, opts *DrawImageOptions) error {
+    return nil
+}
+
+/*
+    Function: SubImage
+    Get a part of the image, as ebiten does (see createWallTileFromImage)
+    Input: the part of the image
+*/
+func (img *Image) SubImage(part image.Rectangle) image.Image {
+    part = part.Intersect(img.Bounds())
+    return &Image{width: part.Dx(), height: part.Dy()}
+}
+
+// The image is also an image.Image, so SubImage can give it. All its pixels are transparent
+func (img *Image) ColorModel() color.Model {
+    return color.RGBAModel
+}
+func (img *Image) Bounds() image.Rectangle {
+    return image.Rect(0, 0, img.width, img.height)
+}
+func (img *Image) At(x int, y int) color.Color {
+    return color.RGBA{}
+}
+
+func (geoM *GeoM) Scale(x float64, y float64) {}
+func (geoM *GeoM) Translate(x float64, y float64) {}
+func (colorM *ColorM) Scale(r float64, g float64, b float64, a float64) {}
+
+/*
+    ##################################
+    ## Functions of input (nothing) ##
+    ##################################
+*/
+func isKeyPressed(key Key) bool {
+    return false
+}
+func isKeyJustPressed(key Key) bool {
+    return false
+}
+func isMouseButtonPressed(button MouseButton) bool {
+    return false
+}
+func isMouseButtonJustPressed(button MouseButton) bool {
+    return false
+}
+func cursorPosition() (int, int) {
+    return 0, 0
+}
+func gamepadIDs() []int {
+    return nil
+}
+func gamepadAxisNum(id int) int {
+    return 0
+}
+func gamepadAxis(id int, axis int) float64 {
+    return 0
+}
+
+/*
+    Function: newEbitenAudioBackend
+    There's no ebiten audio in this build, so the sound is turned off (see initSound)
+*/
+func newEbitenAudioBackend() (SoundBackend, error) {
+    return nil, errNoWindow
+}
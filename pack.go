@@ -0,0 +1,533 @@
+package main
+
+/*
+    This file contains the level packs: a set of levels distributed as a single zip file, and the pack browser.
+
+    A level pack has a pack.json manifest at the top of the zip file (or inside its only folder), the maze files of the
+    levels and optionally a theme with its images and sounds. Paths in the manifest are relative to the manifest.
+
+    Ex: haunted.zip
+    pack.json
+    mazes/level1.txt
+    mazes/level2.png
+    theme/theme.json
+    theme/pacman.png
+
+    Ex: pack.json
+    {
+        "name": "Haunted",
+        "author": "Someone",
+        "description": "Two spooky mazes",
+        "theme": "theme",
+        "levels": [
+            {"maze": "mazes/level1.txt", "enemies": 3},
+            {"maze": "mazes/level2.png", "enemySpeed": 3, "wallColor": "#8000ff", "frightenedTime": 240}
+        ]
+    }
+
+    A level can have pacmanSpeed, enemySpeed, enemies, wallColor, theme, frightenedTime and enemyAggression (see
+    LevelInfo). A setting which is not given is the same as in the classic level with the same number (or the last
+    classic level). Levels without their own theme use the theme of the pack.
+
+    While a pack is played, files are looked for in the pack first (see files.go). -pack haunted.zip starts the game
+    with a pack, and L on the start screen opens the pack browser with the packs inside the packs folder.
+*/
+import (
+    "archive/zip"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "golang.org/x/image/font"
+    "io/fs"
+    "io/ioutil"
+    "log"
+    "path"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure which matches the pack.json manifest file. Fields are exported so the json package can fill them
+type PackManifest struct {
+    Name string `json:"name"`
+    Author string `json:"author"`
+    Description string `json:"description"`
+    Theme string `json:"theme"` // theme of the levels without their own theme
+    Levels []json.RawMessage `json:"levels"` // each level is read into a PackLevel which has the classic settings (see getPackLevels)
+}
+
+// Structure which matches a level of the pack.json manifest file
+type PackLevel struct {
+    Maze string `json:"maze"`
+    PacmanSpeed float64 `json:"pacmanSpeed"`
+    EnemySpeed float64 `json:"enemySpeed"`
+    Enemies int `json:"enemies"`
+    WallColor string `json:"wallColor"`
+    Theme string `json:"theme"`
+    FrightenedTime int `json:"frightenedTime"`
+    EnemyAggression float64 `json:"enemyAggression"`
+}
+
+// Structure which keeps a level pack listed in the pack browser
+type LevelPack struct {
+    file string // holds the path to the zip file (empty for the classic levels)
+    manifest PackManifest // holds the manifest of the pack
+    err error // holds the reason the pack can't be played (nil if it can be played)
+}
+
+// Structure which keeps the pack browser
+type PackBrowser struct {
+    packs []LevelPack // holds the packs which can be chosen, the classic levels first
+    selected int // holds the position of the selected pack in the list
+    message string // holds the reason the last pack couldn't be loaded
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's have a variable to define the folder where level packs are kept, and the name of the manifest inside a pack
+var packsDir = "packs"
+var packManifestFile = "pack.json"
+
+// Let's define the name of the levels played without a pack
+var classicPackName = "classic"
+
+// Let's keep the levels of the game, which are played when no pack is loaded
+var classicLevels = LEVELS
+
+// Variable to hold the path to the zip file of the pack which is played (empty for the classic levels)
+var levelPackFile = ""
+
+// Variable to hold the opened zip file of the pack which is played, so it's closed when another pack is loaded
+var packArchive *zip.ReadCloser
+
+// Let's define the most packs shown in the pack browser at once
+var packBrowserMaxPacks = 12
+
+// Variable to hold the pack browser (nil if the pack browser isn't open)
+var packBrowser *PackBrowser
+
+/*
+    ###################################
+    ## Functions to read level packs ##
+    ###################################
+*/
+
+/*
+    Function: openLevelPack
+    Open the zip file of a level pack and find its manifest
+    Outputs the opened zip file and the files of the pack (the folder with the manifest)
+    Input: path to the zip file
+*/
+func openLevelPack(fileName string) (*zip.ReadCloser, fs.FS, error) {
+    archive, err := zip.OpenReader(fileName)
+    if err != nil {
+        return nil, nil, err
+    }
+    if _, err := fs.Stat(archive, packManifestFile); err == nil {
+        return archive, archive, nil
+    }
+
+    // a zipped folder has the manifest inside the folder
+    entries, err := fs.ReadDir(archive, ".")
+    if err == nil && len(entries) == 1 && entries[0].IsDir() {
+        if _, err := fs.Stat(archive, path.Join(entries[0].Name(), packManifestFile)); err == nil {
+            folder, err := fs.Sub(archive, entries[0].Name())
+            if err == nil {
+                return archive, folder, nil
+            }
+        }
+    }
+    archive.Close()
+    return nil, nil, fmt.Errorf("%s has no %s", fileName, packManifestFile)
+}
+
+/*
+    Function: readPackManifest
+    Read the manifest of a level pack. A pack without a name is named by its file (Ex: haunted.zip is haunted)
+    Inputs: files of the pack and the path to the zip file
+*/
+func readPackManifest(files fs.FS, fileName string) (PackManifest, error) {
+    manifest := PackManifest{}
+    data, err := fs.ReadFile(files, packManifestFile)
+    if err != nil {
+        return manifest, err
+    }
+    if err := json.Unmarshal(data, &manifest); err != nil {
+        return manifest, fmt.Errorf("%s: %s: %v", fileName, packManifestFile, err)
+    }
+    if manifest.Name == "" {
+        manifest.Name = strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+    }
+    if len(manifest.Levels) == 0 {
+        return manifest, fmt.Errorf("%s: %s has no levels", fileName, packManifestFile)
+    }
+    return manifest, nil
+}
+
+/*
+    Function: getPackLevels
+    Get the levels of a level pack from its manifest. A setting which is not given is the same as in the classic level
+    Inputs: manifest and the files of the pack
+*/
+func getPackLevels(manifest PackManifest, files fs.FS) (map[int]LevelInfo, error) {
+    levels := map[int]LevelInfo{}
+    for i, data := range manifest.Levels {
+        level := i+1
+
+        // let's start from the classic level with the same number, so unmarshal only changes the settings which are given
+        classic, ok := classicLevels[level]
+        if !ok {
+            classic = classicLevels[len(classicLevels)]
+        }
+        packLevel := PackLevel{
+            PacmanSpeed: classic.pacmanSpeed,
+            EnemySpeed: classic.enemySpeed,
+            Enemies: classic.numEnemies,
+            Theme: manifest.Theme,
+            FrightenedTime: classic.frightenedTime,
+            EnemyAggression: classic.enemyAggression,
+        }
+        if err := json.Unmarshal(data, &packLevel); err != nil {
+            return nil, fmt.Errorf("level %d: %v", level, err)
+        }
+
+        // the maze should be in the pack, or one of the files of the game (Ex: maze01.txt)
+        if packLevel.Maze == "" {
+            return nil, fmt.Errorf("level %d has no maze", level)
+        }
+        if _, err := fs.Stat(files, path.Clean(filepath.ToSlash(packLevel.Maze))); err != nil {
+            if _, err := statGameFile(packLevel.Maze); err != nil {
+                return nil, fmt.Errorf("level %d: %v", level, err)
+            }
+        }
+        // like the speeds of a maze file (see parseMazeSpeed)
+        if packLevel.PacmanSpeed <= 0 || packLevel.EnemySpeed <= 0 || packLevel.PacmanSpeed > float64(blockSize/2) || packLevel.EnemySpeed > float64(blockSize/2) {
+            return nil, fmt.Errorf("level %d: speeds should be above 0 and up to %d", level, blockSize/2)
+        }
+        // a level needs an enemy, the versus mode plays one of them (see versus.go)
+        if packLevel.Enemies < 1 {
+            return nil, fmt.Errorf("level %d: enemies should be at least 1", level)
+        }
+        if packLevel.FrightenedTime < 0 {
+            return nil, fmt.Errorf("level %d: frightened time can't be less than 0", level)
+        }
+        if packLevel.EnemyAggression < 0 || packLevel.EnemyAggression > 1 {
+            return nil, fmt.Errorf("level %d: enemy aggression should be from 0 to 1", level)
+        }
+
+        info := LevelInfo{
+            pacmanSpeed: packLevel.PacmanSpeed,
+            enemySpeed: packLevel.EnemySpeed,
+            numEnemies: packLevel.Enemies,
+            mazeFile: packLevel.Maze,
+            wallColor: classic.wallColor,
+            theme: packLevel.Theme,
+            frightenedTime: packLevel.FrightenedTime,
+            enemyAggression: packLevel.EnemyAggression,
+        }
+        if packLevel.WallColor != "" {
+            clr, err := parseHexColor(packLevel.WallColor)
+            if err != nil {
+                return nil, fmt.Errorf("level %d: wall color: %v", level, err)
+            }
+            info.wallColor = clr
+        }
+        levels[level] = info
+    }
+    return levels, nil
+}
+
+/*
+    Function: checkPackLevels
+    Check that the levels of a level pack can be played: each maze is read from the pack and can be played, each theme
+    is read from the pack, and the files of each theme are there. So a broken pack is found when it's opened, and not
+    when the game gets to the broken level
+    Inputs: levels of the pack and the files of the pack
+*/
+func checkPackLevels(levels map[int]LevelInfo, files fs.FS) error {
+    // the files are looked for in the pack being checked, like while it's played (see files.go)
+    playedFiles := packFiles
+    packFiles = files
+    defer func() {
+        packFiles = playedFiles
+    }()
+
+    for level := 1; level <= len(levels); level++ {
+        info := levels[level]
+        // the maze is checked like the editor checks it (a start, rows of the same length, food... see checkMaze)
+        maze, err := loadMazeFile(info.mazeFile)
+        if err != nil {
+            return fmt.Errorf("level %d: %v", level, err)
+        }
+
+        // a level without its own theme uses the theme of its maze (see getLevelTheme)
+        themeName := info.theme
+        if themeName == "" {
+            themeName = maze.theme
+        }
+        if themeName == "" || themeName == defaultTheme.name {
+            continue
+        }
+        theme, err := readTheme(themeName)
+        if err != nil {
+            return fmt.Errorf("level %d: %v", level, err)
+        }
+        for asset, fileName := range theme.assets {
+            if _, err := statGameFile(fileName); err != nil {
+                return fmt.Errorf("level %d: theme %s: %s: %v", level, themeName, asset, err)
+            }
+        }
+    }
+    return nil
+}
+
+/*
+    Function: loadLevelPack
+    Play the levels of a level pack. An empty path goes back to the classic levels
+    Files which were read from the previous pack are forgotten, so they're read again from the new pack
+    Input: path to the zip file of the pack
+*/
+func loadLevelPack(fileName string) error {
+    name := classicPackName
+    levels := classicLevels
+    var archive *zip.ReadCloser
+    var files fs.FS
+
+    if fileName != "" {
+        var err error
+        archive, files, err = openLevelPack(fileName)
+        if err != nil {
+            return err
+        }
+        manifest, err := readPackManifest(files, fileName)
+        if err == nil {
+            levels, err = getPackLevels(manifest, files)
+        }
+        if err == nil {
+            err = checkPackLevels(levels, files)
+        }
+        if err != nil {
+            archive.Close()
+            return fmt.Errorf("%s: %v", fileName, err)
+        }
+        name = manifest.Name
+    }
+
+    if packArchive != nil {
+        packArchive.Close()
+    }
+    packArchive = archive
+    packFiles = files
+    levelPackFile = fileName
+    levelPackName = name
+    LEVELS = levels
+
+    loadedMazes = map[string]Maze{}
+    loadedThemes = map[string]Theme{}
+    loadedFonts = map[string]font.Face{}
+    log.Println("playing the level pack", name, "with", len(levels), "levels")
+    return nil
+}
+
+/*
+    Function: getLevelPacks
+    Get the level packs of the pack browser: the classic levels, the packs inside the packs folder and the pack which
+    is played (if it's not in the packs folder). The levels of each pack are checked, so a broken pack is shown as
+    a pack which can't be played
+*/
+func getLevelPacks() []LevelPack {
+    packs := []LevelPack{{manifest: PackManifest{Name: classicPackName, Description: "The levels of the game"}}}
+    fileNames := []string{}
+    infos, err := ioutil.ReadDir(packsDir)
+    if err != nil && !errors.Is(err, fs.ErrNotExist) {
+        log.Println(err)
+    }
+    for _, info := range infos {
+        if !info.IsDir() && strings.ToLower(filepath.Ext(info.Name())) == ".zip" {
+            fileNames = append(fileNames, filepath.Join(packsDir, info.Name()))
+        }
+    }
+    if levelPackFile != "" && !containsString(fileNames, levelPackFile) {
+        fileNames = append(fileNames, levelPackFile)
+    }
+
+    for _, fileName := range fileNames {
+        pack := LevelPack{file: fileName}
+        archive, files, err := openLevelPack(fileName)
+        if err == nil {
+            pack.manifest, err = readPackManifest(files, fileName)
+            if err == nil {
+                var levels map[int]LevelInfo
+                if levels, err = getPackLevels(pack.manifest, files); err == nil {
+                    err = checkPackLevels(levels, files)
+                }
+            }
+            archive.Close()
+        }
+        if err != nil {
+            log.Println("the level pack can't be played:", err)
+            if pack.manifest.Name == "" {
+                pack.manifest.Name = filepath.Base(fileName)
+            }
+            pack.err = err
+        }
+        packs = append(packs, pack)
+    }
+    return packs
+}
+
+/*
+    Function: containsString
+    Check if a list of strings has a string
+    Inputs: list and the string
+*/
+func containsString(list []string, value string) bool {
+    for _, item := range list {
+        if filepath.Clean(item) == filepath.Clean(value) {
+            return true
+        }
+    }
+    return false
+}
+
+/*
+    Function: getPackLabel
+    Get the line of a pack in the pack browser. Ex: Haunted  2 levels  by Someone
+*/
+func (pack LevelPack) getPackLabel() string {
+    if pack.err != nil {
+        return pack.manifest.Name+"  can't be played"
+    }
+
+    levels := len(pack.manifest.Levels)
+    if pack.file == "" {
+        levels = len(classicLevels)
+    }
+    label := pack.manifest.Name+"  "+strconv.Itoa(levels)+" levels"
+    if pack.manifest.Author != "" {
+        label = label+"  by "+pack.manifest.Author
+    }
+    if filepath.Clean(pack.file) == filepath.Clean(levelPackFile) {
+        label = label+"  (playing)"
+    }
+    return label
+}
+
+/*
+    ###################################
+    ## Functions of the pack browser ##
+    ###################################
+*/
+
+/*
+    Function: canBrowsePacks
+    Check if the pack browser can be opened. The levels of a networked or rollback game can't be changed by one player
+*/
+func canBrowsePacks() bool {
+    return !isDemo && !isHeadless && !isTUI && mazeEditor == nil && netHost == nil && netClient == nil && rollbackSession == nil && gameBot == nil
+}
+
+/*
+    Function: openPackBrowser
+    Open the pack browser on the start screen, with the pack which is played selected
+*/
+func openPackBrowser() {
+    packBrowser = &PackBrowser{packs: getLevelPacks()}
+    for i, pack := range packBrowser.packs {
+        if filepath.Clean(pack.file) == filepath.Clean(levelPackFile) {
+            packBrowser.selected = i
+        }
+    }
+}
+
+/*
+    Function: updatePackBrowser
+    Up and down select a pack, Enter plays it and Escape closes the pack browser
+    It's called on each frame by updateGame while the pack browser is open
+*/
+func updatePackBrowser() {
+    if isKeyJustPressed(KeyUp) && packBrowser.selected > 0 {
+        packBrowser.selected--
+    }
+    if isKeyJustPressed(KeyDown) && packBrowser.selected < len(packBrowser.packs)-1 {
+        packBrowser.selected++
+    }
+    if isKeyJustPressed(KeyEscape) {
+        packBrowser = nil
+        return
+    }
+
+    pack := packBrowser.packs[packBrowser.selected]
+    if isKeyJustPressed(KeyEnter) && pack.err == nil {
+        if err := loadLevelPack(pack.file); err != nil {
+            log.Println("the level pack can't be played:", err)
+            packBrowser.message = "THE PACK CAN'T BE PLAYED"
+            return
+        }
+        packBrowser = nil
+
+        // the mazes of the pack can have another size, and the levels start again
+        setScreenSize()
+        resizeWindow(screenSizeX, screenSizeY)
+        newGame()
+    }
+}
+
+/*
+    Function: drawPackBrowser
+    Draw the pack browser with the list of packs. The selected pack has an arrow, packs which can't be played are gray
+    Input: screen
+*/
+func drawPackBrowser(screen *Image) {
+    background, _ := getPaletteColor("background")
+    screen.Fill(background)
+
+    clr := getTextColor()
+    _, textHeight := measureText("0")
+    lineHeight := textHeight+textHeight/2
+    y := blockSize*2
+    drawTextCentered(screen, "LEVEL PACKS", screenSizeX/2, y, clr)
+    y = y+lineHeight*2
+
+    // the list scrolls, so the selected pack is always shown
+    first := 0
+    if packBrowser.selected >= packBrowserMaxPacks {
+        first = packBrowser.selected-packBrowserMaxPacks+1
+    }
+    for i := first; i < len(packBrowser.packs) && i < first+packBrowserMaxPacks; i++ {
+        pack := packBrowser.packs[i]
+        packClr := lobbyGameColor
+        if pack.err != nil {
+            packClr = lobbyDisabledColor
+        }
+        if i == packBrowser.selected {
+            drawText(screen, ">", blockSize, y, clr)
+        }
+        drawText(screen, pack.getPackLabel(), blockSize*2, y, packClr)
+        y = y+lineHeight
+    }
+
+    // the description of the selected pack (or why it can't be played) is shown above the keys
+    bottomY := screenSizeY-blockSize*2-textHeight
+    drawTextCentered(screen, "Up/Down: SELECT  Enter: PLAY  Escape: BACK", screenSizeX/2, bottomY, clr)
+    selected := packBrowser.packs[packBrowser.selected]
+    description := selected.manifest.Description
+    if selected.err != nil {
+        description = selected.err.Error()
+    }
+    if packBrowser.message != "" {
+        description = packBrowser.message
+    }
+    if description != "" {
+        drawTextCentered(screen, description, screenSizeX/2, bottomY-lineHeight, clr)
+    }
+}
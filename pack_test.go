@@ -0,0 +1,68 @@
+package main
+
+/*
+    This file contains the tests of the level packs (see pack.go).
+
+    The packs are made in memory with the files of a zip file, so a broken pack is rejected when it's opened like in the
+    pack browser, and not when the game gets to the broken level.
+*/
+import (
+    "testing"
+    "testing/fstest"
+)
+
+// Let's define a maze which can be played, for the packs of the tests
+var testPackMaze = "00000\n0P.o0\n0.E.0\n00000\n"
+
+/*
+    Function: openTestPack
+    Read the levels of a pack made of some files and check them, like loadLevelPack does
+    Input: files of the pack (path to the content)
+*/
+func openTestPack(files map[string]string) error {
+    packFS := fstest.MapFS{}
+    for fileName, content := range files {
+        packFS[fileName] = &fstest.MapFile{Data: []byte(content)}
+    }
+    manifest, err := readPackManifest(packFS, "test.zip")
+    if err != nil {
+        return err
+    }
+    levels, err := getPackLevels(manifest, packFS)
+    if err != nil {
+        return err
+    }
+    return checkPackLevels(levels, packFS)
+}
+
+func TestPackCanBePlayed(t *testing.T) {
+    err := openTestPack(map[string]string{
+        "pack.json": `{"levels": [{"maze": "mazes/level1.txt", "enemies": 2}]}`,
+        "mazes/level1.txt": testPackMaze,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestPackRejectsBrokenLevels(t *testing.T) {
+    packs := map[string]map[string]string{
+        "no start": {
+            "pack.json": `{"levels": [{"maze": "mazes/level1.txt"}]}`,
+            "mazes/level1.txt": "00000\n0..o0\n0.E.0\n00000\n",
+        },
+        "rows of different lengths": {
+            "pack.json": `{"levels": [{"maze": "mazes/level1.txt"}]}`,
+            "mazes/level1.txt": "00000\n0P.o0\n0.E.00\n00000\n",
+        },
+        "no enemies": {
+            "pack.json": `{"levels": [{"maze": "mazes/level1.txt", "enemies": 0}]}`,
+            "mazes/level1.txt": testPackMaze,
+        },
+    }
+    for name, files := range packs {
+        if err := openTestPack(files); err == nil {
+            t.Errorf("a pack with %s should be rejected", name)
+        }
+    }
+}
@@ -0,0 +1,215 @@
+package main
+
+/*
+    This file contains the mazes drawn as PNG images (pixel mazes) and the converter between the maze formats.
+
+    A maze file ending with .png is read as a pixel maze (see readMazeFile). Each pixel is a maze point, and its color
+    tells the tile with the palette: the tile of the palette color nearest to the pixel color is used, so slightly
+    different colors (Ex: from a paint program) still work. Transparent pixels are empty paths.
+
+    Default palette
+    wall     : #2121de (blue)        food     : #ffffff (white)       pellet   : #ffb897 (peach)
+    empty    : #000000 (black)       spawn    : #ff0000 (red)         player 1 : #ffff00 (yellow)
+    player 2 : #00ff00 (green)       player 3 : #00ffff (cyan)        player 4 : #ff00ff (magenta)
+
+    The palette can be changed with -mazepalette palette.json, a JSON object with the colors of the tiles to change.
+    Ex: {"wall": "#0000ff", "pellet": "#ff8800"}
+
+    -convertmaze maze.png -to maze.txt converts a maze between the formats (text, PNG, or a Tiled map to one of them).
+    A PNG has only the grid, so the header of a maze (name, speeds, tunnels...) is left out when it's converted to a PNG.
+*/
+import (
+    "encoding/json"
+    "fmt"
+    "image"
+    "image/color"
+    "image/png"
+    "io/ioutil"
+    "log"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+)
+
+// Let's define the color of each tile of a pixel maze
+var pixelMazePalette = map[byte]color.RGBA{
+    '0': {R: 33, G: 33, B: 222, A: 255},
+    '.': {R: 255, G: 255, B: 255, A: 255},
+    'o': {R: 255, G: 184, B: 151, A: 255},
+    ' ': {A: 255},
+    'E': {R: 255, A: 255},
+    'P': {R: 255, G: 255, A: 255},
+    '2': {G: 255, A: 255},
+    '3': {G: 255, B: 255, A: 255},
+    '4': {R: 255, B: 255, A: 255},
+}
+
+// Let's define how far (sum of the squared differences of red, green and blue) a pixel color can be from the nearest
+// palette color. Pixels further from all the palette colors are errors, they're probably a mistake in the image
+var pixelMazeMaxDistance = 3*64*64
+
+/*
+    Function: isPixelMazeFile
+    Check if a maze file is a pixel maze, by the extension of the file
+    Input: path to the maze file
+*/
+func isPixelMazeFile(fileName string) bool {
+    return strings.ToLower(filepath.Ext(fileName)) == ".png"
+}
+
+/*
+    Function: loadPixelMazePalette
+    Change the colors of the pixel maze palette with the colors of a palette file
+    Input: path to the palette file
+*/
+func loadPixelMazePalette(fileName string) error {
+    data, err := ioutil.ReadFile(fileName)
+    if err != nil {
+        return err
+    }
+    colors := map[string]string{}
+    if err := json.Unmarshal(data, &colors); err != nil {
+        return fmt.Errorf("%s: %v", fileName, err)
+    }
+    for name, value := range colors {
+        tile, ok := mazeTileNames[strings.ToLower(name)]
+        if !ok {
+            return fmt.Errorf("%s: unknown tile %q", fileName, name)
+        }
+        clr, err := parseHexColor(value)
+        if err != nil {
+            return fmt.Errorf("%s: %s: %v", fileName, name, err)
+        }
+        pixelMazePalette[tile] = clr
+    }
+    return nil
+}
+
+/*
+    Function: readPixelMazeFile
+    Read a maze from a PNG image, a maze point from each pixel
+    Input: path to the image
+*/
+func readPixelMazeFile(fileName string) (Maze, error) {
+    file, err := openGameFile(fileName)
+    if err != nil {
+        return Maze{}, err
+    }
+    defer file.Close()
+    img, err := png.Decode(file)
+    if err != nil {
+        return Maze{}, err
+    }
+    return getPixelMaze(img)
+}
+
+/*
+    Function: getPixelMaze
+    Get the maze of an image, a maze point from each pixel
+    Input: image
+*/
+func getPixelMaze(img image.Image) (Maze, error) {
+    maze := Maze{}
+    bounds := img.Bounds()
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        row := make([]byte, bounds.Dx())
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            tile, ok := getPixelTile(img.At(x, y))
+            if !ok {
+                r, g, b, _ := img.At(x, y).RGBA()
+                return maze, fmt.Errorf("the color #%02x%02x%02x of the pixel %d,%d is not near any color of the palette", r>>8, g>>8, b>>8, x-bounds.Min.X, y-bounds.Min.Y)
+            }
+            row[x-bounds.Min.X] = tile
+        }
+        maze.rows = append(maze.rows, string(row))
+    }
+    return maze, nil
+}
+
+/*
+    Function: getPixelTile
+    Get the tile of a pixel color: the tile of the nearest palette color. Transparent pixels are empty paths
+    The second value is false if the color is too far from all the palette colors
+    Input: color of the pixel
+*/
+func getPixelTile(clr color.Color) (byte, bool) {
+    r, g, b, a := clr.RGBA()
+    if a < 0x8000 {
+        return ' ', true
+    }
+    bestTile := byte(0)
+    bestDistance := -1
+    for tile, paletteColor := range pixelMazePalette {
+        dr := int(r>>8)-int(paletteColor.R)
+        dg := int(g>>8)-int(paletteColor.G)
+        db := int(b>>8)-int(paletteColor.B)
+        distance := dr*dr+dg*dg+db*db
+        // the tiles are compared in the same order each time, so a color halfway between two palette colors always gets the same tile
+        if bestDistance < 0 || distance < bestDistance || (distance == bestDistance && tile < bestTile) {
+            bestTile = tile
+            bestDistance = distance
+        }
+    }
+    return bestTile, bestDistance <= pixelMazeMaxDistance
+}
+
+/*
+    Function: writePixelMazeFile
+    Write the grid of a maze as a PNG image, a pixel for each maze point with the color of its tile in the palette
+    Inputs: path to the image and the maze
+*/
+func writePixelMazeFile(fileName string, maze Maze) error {
+    cols := 0
+    for _, line := range maze.rows {
+        if len(line) > cols {
+            cols = len(line)
+        }
+    }
+    img := image.NewRGBA(image.Rect(0, 0, cols, len(maze.rows)))
+    for row, line := range maze.rows {
+        for col := 0; col < cols; col++ {
+            // short rows are filled with walls, like in the editor
+            tile := byte('0')
+            if col < len(line) {
+                tile = line[col]
+            }
+            clr, ok := pixelMazePalette[tile]
+            if !ok {
+                return fmt.Errorf("the tile %q at %d,%d has no color in the palette", tile, col, row)
+            }
+            img.Set(col, row, clr)
+        }
+    }
+
+    file, err := os.Create(fileName)
+    if err != nil {
+        return err
+    }
+    if err := png.Encode(file, img); err != nil {
+        file.Close()
+        return err
+    }
+    return file.Close()
+}
+
+/*
+    Function: convertMazeFile
+    Convert a maze file to another format. The format is chosen by the extension of the file: a .png is a pixel maze,
+    any other file is written as a text maze file. Everything readMazeFile reads (text, PNG, Tiled maps) can be converted
+    Inputs: path to the maze file to read and the path to the maze file to write
+*/
+func convertMazeFile(fromFile string, toFile string) error {
+    maze := readMazeFile(fromFile)
+    if !isPixelMazeFile(toFile) {
+        return ioutil.WriteFile(toFile, []byte(formatMaze(maze)), 0644)
+    }
+
+    // a PNG has only the grid, so let's tell what's left out
+    header := maze
+    header.rows = nil
+    if !reflect.DeepEqual(header, Maze{}) {
+        log.Println("the header of the maze (name, speeds, tunnels...) can't be kept in a PNG, it's left out")
+    }
+    return writePixelMazeFile(toFile, maze)
+}
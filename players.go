@@ -0,0 +1,132 @@
+package main
+
+/*
+    This file contains the two player mode.
+
+    In the two player mode players take turns (like the original Pac-Man). When PacMan loses a life, the other player
+    gets the turn. Each player has their own score, lives, level and maze (which food is eaten).
+    The game info of the player who is waiting is saved, and it's restored when the player gets the turn again.
+*/
+import (
+    "strconv"
+)
+
+// Structure which keeps the saved game of a player while the other player is playing
+type PlayerState struct {
+    number int // holds the player number (1 or 2)
+    gameInfo GameInfo // holds the game info of the player (level, score, lives, maze with the eaten food)
+}
+
+// Let's define how long (frames) the "PLAYER n" screen is shown when the players change
+var playerChangeTime = 150
+
+// Variable to hold the number of players of the current game
+var numPlayers = 1
+
+// Variable to hold the saved games of all the players. The game of the current player is in gameInfo
+var players []PlayerState
+
+// Variable to hold the position of the current player in players
+var currentPlayer = 0
+
+/*
+    Function: savePlayerState
+    Save the game of the current player
+    The maze is copied, so the saved game does not change when the other player eats food
+*/
+func savePlayerState() PlayerState {
+    state := PlayerState{
+        number: currentPlayer+1,
+        gameInfo: gameInfo,
+    }
+    state.gameInfo.maze = append([]string{}, gameInfo.maze...)
+    state.gameInfo.stats = append([]PlayerStats{}, gameInfo.stats...)
+    return state
+}
+
+/*
+    Function: restorePlayerState
+    Continue the saved game of a player
+    The game objects are created again from the saved maze, so the food eaten by the player stays eaten
+    Input: saved game of the player
+*/
+func restorePlayerState(state PlayerState) {
+    gameInfo = state.gameInfo
+    gameInfo.maze = append([]string{}, state.gameInfo.maze...)
+    gameInfo.stats = append([]PlayerStats{}, state.gameInfo.stats...)
+
+    // use the theme and the font of the level of this player
+    currentTheme = getLevelTheme(gameInfo.level)
+    gameFont = loadFont(getAsset("font"))
+
+    // locateGameObjects counts the food left in the maze as the maximum score, so let's put back the saved maximum score
+    locateGameObjects()
+    gameInfo.maxScore = state.gameInfo.maxScore
+
+    // the power pellet and the fruit of the last turn are over
+    gameInfo.frightenedTimer = 0
+    gameInfo.fruitTimer = 0
+}
+
+/*
+    Function: startPlayers
+    Start the game with the given number of players. All players start from the same new game
+    Input: number of players
+*/
+func startPlayers(count int) {
+    numPlayers = count
+    currentPlayer = 0
+    players = []PlayerState{}
+    for i := 0; i < count; i++ {
+        state := savePlayerState()
+        state.number = i+1
+        players = append(players, state)
+    }
+    gameInfo.isStarted = true
+}
+
+/*
+    Function: getNextPlayer
+    Get the position of the next player who still has lives and hasn't completed all the levels
+    Returns -1 if no other player can go on
+*/
+func getNextPlayer() int {
+    for i := 1; i < numPlayers; i++ {
+        next := (currentPlayer+i)%numPlayers
+        state := players[next].gameInfo
+        hasWon := state.isLevelComplete && state.level >= len(LEVELS)
+        if state.stats[0].lives > 0 && !hasWon {
+            return next
+        }
+    }
+    return -1
+}
+
+/*
+    Function: changePlayer
+    Save the game of the current player and give the turn to the next player who still has lives
+    Returns false if there's no other player to take the turn
+*/
+func changePlayer() bool {
+    next := getNextPlayer()
+    if next < 0 {
+        return false
+    }
+
+    players[currentPlayer] = savePlayerState()
+    currentPlayer = next
+    restorePlayerState(players[currentPlayer])
+
+    // let's show the "PLAYER n" screen for a while before the next player starts
+    gameInfo.isStarted = true
+    gameInfo.freezeTimer = playerChangeTime
+    return true
+}
+
+/*
+    Function: getPlayerLabel
+    Get the label of the current player to show on the screen
+*/
+func getPlayerLabel() string {
+    return "PLAYER " + strconv.Itoa(currentPlayer+1)
+}
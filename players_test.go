@@ -0,0 +1,59 @@
+package main
+
+/*
+    This file contains the tests of the two player mode (see players.go).
+*/
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+/*
+    Function: completeLastLevel
+    Let the current player complete the last level, like eating its last food
+*/
+func completeLastLevel() {
+    initLevel(len(LEVELS))
+    gameInfo.isStarted = true
+    gameInfo.isLevelComplete = true
+}
+
+func TestLastLevelGivesTurnToOtherPlayer(t *testing.T) {
+    // when a player completes all the levels, the other player goes on, and a new game starts only after that
+    dir, err := ioutil.TempDir("", "players")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    fileName := highScoreFile
+    highScoreFile = filepath.Join(dir, "highscore.txt")
+    defer func() {
+        highScoreFile = fileName
+        newGame()
+    }()
+
+    setScreenSize()
+    newGame()
+    startPlayers(2)
+    isNewGame = false
+
+    completeLastLevel()
+    startNextLevel()
+    if currentPlayer != 1 || gameInfo.isLevelComplete || gameInfo.level != 1 {
+        t.Fatalf("player %d has the turn on level %d, expected player 2 on level 1", currentPlayer+1, gameInfo.level)
+    }
+
+    // the first player completed the game, so they don't get the turn again when the second player loses a life
+    loseLife(pacmen[0])
+    if currentPlayer != 1 {
+        t.Fatalf("player %d has the turn, the first player completed all the levels", currentPlayer+1)
+    }
+
+    completeLastLevel()
+    startNextLevel()
+    if !isNewGame || numPlayers != 1 {
+        t.Error("a new game should start when both players completed all the levels")
+    }
+}
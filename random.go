@@ -0,0 +1,62 @@
+package main
+
+/*
+    This file contains the random number generator of the game.
+
+    The game has its own random number generator instead of the one in math/rand, because its whole state is a single
+    number. The state can be saved and restored with the rest of the game (see world.go), so a game started with the
+    same seed and the same inputs always plays the same way (needed by the rollback game, see rollback.go).
+    The numbers are made with SplitMix64.
+*/
+import (
+    "time"
+)
+
+// Variable to hold the state of the random number generator
+var gameRandom uint64
+
+/*
+    Function: seedRandom
+    Start the random numbers from a seed. The same seed always gives the same numbers
+    Input: seed
+*/
+func seedRandom(seed int64) {
+    gameRandom = uint64(seed)
+}
+
+/*
+    Function: seedRandomFromTime
+    Start the random numbers from the current time, so each game is different
+*/
+func seedRandomFromTime() {
+    seedRandom(time.Now().UnixNano())
+}
+
+/*
+    Function: nextRandom
+    Get the next random number (all 64 bits are random)
+*/
+func nextRandom() uint64 {
+    gameRandom = gameRandom+0x9E3779B97F4A7C15
+    z := gameRandom
+    z = (z ^ (z >> 30))*0xBF58476D1CE4E5B9
+    z = (z ^ (z >> 27))*0x94D049BB133111EB
+    return z ^ (z >> 31)
+}
+
+/*
+    Function: randomInt
+    Get a random integer from 0 to n-1
+    Input: n (more than 0)
+*/
+func randomInt(n int) int {
+    return int(nextRandom()%uint64(n))
+}
+
+/*
+    Function: randomFloat
+    Get a random number from 0 to 1 (1 is not included)
+*/
+func randomFloat() float64 {
+    return float64(nextRandom()>>11)/float64(1<<53)
+}
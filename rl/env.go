@@ -0,0 +1,640 @@
+/*
+    Package rl contains a reinforcement learning environment of the PacMan game, like the environments of Gym.
+
+    The environment runs the rules of the game (see main.go) without ebiten, so it runs without a window and many
+    environments can run at the same time (see vector.go). It reads the same maze files as the game, with their speeds,
+    enemies, fruit and tunnels (see maze.go).
+    An episode is a single level played by one PacMan. Each step is a single frame of the game:
+    - Reset(seed) starts a new episode and gives the first observation
+    - Step(action) moves PacMan in the direction of the action, runs the frame and gives the next observation,
+      the reward, whether the episode is done and the info of the game (points, lives, food left)
+    The episode is done when PacMan eats all the food (win), loses all the lives or runs out of steps (MaxSteps).
+    Unlike the game, PacMan and the enemies don't wait (freeze) before moving at the start and after losing a life.
+*/
+package rl
+
+import (
+    "fmt"
+    "io/fs"
+    "math"
+    "os"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure which keeps the settings of a level. These are the same as the levels of the game (LEVELS in main.go).
+// The speeds and the enemies of the header of the maze file are used first, like the game
+type Level struct {
+    PacmanSpeed float64 // holds the speed of PacMan (pixels per frame)
+    EnemySpeed float64 // holds the speed of an enemy (pixels per frame)
+    NumEnemies int // holds the number of enemies
+    MazeFile string // holds the name of the maze file
+    FrightenedTime int // holds the number of frames enemies stay frightened after PacMan eats a power pellet
+    EnemyAggression float64 // holds how often enemies chase PacMan at a junction, from 0 (never) to 1 (always)
+}
+
+// Structure which keeps the rewards given for the things which happen in a step (reward shaping)
+type RewardConfig struct {
+    Dot float64 // reward for eating a food
+    Pellet float64 // reward for eating a power pellet
+    Enemy float64 // reward for eating a frightened enemy
+    Fruit float64 // reward for eating the fruit
+    Death float64 // reward for losing a life (usually negative)
+    Win float64 // reward for eating all the food
+    Step float64 // reward given on every step (usually a small negative time penalty)
+}
+
+// Structure which keeps the settings of an environment
+type Config struct {
+    MazeFolder string // holds the folder of the maze files (the folder of the game)
+    Files fs.FS // holds the files the maze files are read from instead of MazeFolder (Ex: a level pack opened with zip.OpenReader)
+    Level int // holds the level played in each episode
+    Levels map[int]Level // holds the settings of the levels
+    Rewards RewardConfig // holds the rewards
+    MaxSteps int // holds the most steps of an episode (0 for no limit)
+}
+
+// Structure which keeps the info of the game after a step
+type Info struct {
+    Points int // points of the episode, counted like the game
+    Lives int // lives left
+    FoodLeft int // food and power pellets left in the maze
+    Steps int // steps run in the episode
+    IsWin bool // whether PacMan ate all the food
+    IsTruncated bool // whether the episode ran out of steps
+}
+
+// Structure which keeps a moving game object
+type sprite struct {
+    x float64 // holds the x position in the maze (pixels)
+    y float64 // holds the y position in the maze (pixels)
+    speed float64 // holds the speed (pixels per frame)
+    direction byte // holds the moving direction: U, R, D, L or I
+    aggression float64 // holds how often the enemy chases PacMan at a junction, from 0 (never) to 1 (always)
+    isFrightened bool // holds whether the enemy is frightened (PacMan can eat it)
+    isVisible bool // holds whether the fruit is in the maze
+}
+
+// Structure which keeps an environment. An environment is not safe to use from more than one goroutine at a time
+type Env struct {
+    config Config // holds the settings
+    level Level // holds the settings of the level played
+    mazeFile mazeInfo // holds the maze as it's in the maze file, with its header
+    random random // holds the random numbers of the environment, the same as the game (see random.go)
+    maze [][]byte // holds the maze with the food left
+    foodPoints [][2]float64 // holds the positions of all the food at the start, enemies are placed on them if there are no spawn points
+    spawnPoints [][2]float64 // holds the positions where enemies are spawned (E in the maze)
+    pacman sprite // holds PacMan
+    startX float64 // holds the position where PacMan starts
+    startY float64
+    enemies []sprite // holds the enemies
+    fruit sprite // holds the fruit
+    foodEaten int // holds the number of food eaten
+    foodTotal int // holds the number of food at the start
+    points int // holds the points of the episode
+    lives int // holds the lives left
+    nextExtraLife int // holds the points PacMan should reach to get the next extra life
+    frightenedTimer int // holds the number of frames left until enemies stop being frightened
+    fruitTimer int // holds the number of frames left until the fruit disappears
+    steps int // holds the number of steps run
+    done bool // holds whether the episode is over
+}
+
+// Actions of PacMan, the direction PacMan moves in a step
+type Action int
+
+const (
+    ActionIdle Action = iota
+    ActionUp
+    ActionRight
+    ActionDown
+    ActionLeft
+)
+
+// Let's define the number of actions
+const ActionCount = 5
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the rules of the game, the same as the game (see main.go)
+var blockSize = 15
+var startingLives = 3
+var foodPoints = 10
+var pelletPoints = 50
+var enemyPoints = 200
+var fruitPoints = 100
+var extraLifePoints = 3000
+var fruitAtFood = []int{70, 170}
+var fruitTime = 600
+
+// Let's define the levels, the same as the levels of the game
+var DefaultLevels = map[int]Level{
+    1: Level{PacmanSpeed: 2, EnemySpeed: 2, NumEnemies: 4, MazeFile: "maze01.txt", FrightenedTime: 360, EnemyAggression: 0.3},
+    2: Level{PacmanSpeed: 2, EnemySpeed: 3, NumEnemies: 5, MazeFile: "maze02.txt", FrightenedTime: 240, EnemyAggression: 0.5},
+}
+
+// Let's define the rewards used when no rewards are given
+var DefaultRewards = RewardConfig{
+    Dot: 1,
+    Pellet: 5,
+    Enemy: 20,
+    Fruit: 10,
+    Death: -50,
+    Win: 100,
+    Step: -0.01,
+}
+
+// Let's define the directions of the actions and the steps of the directions
+var actionDirections = map[Action]byte{ActionIdle: 'I', ActionUp: 'U', ActionRight: 'R', ActionDown: 'D', ActionLeft: 'L'}
+var directionSteps = map[byte][2]int{'U': {0, -1}, 'R': {1, 0}, 'D': {0, 1}, 'L': {-1, 0}}
+var oppositeDirections = map[byte]byte{'U': 'D', 'D': 'U', 'L': 'R', 'R': 'L'}
+
+/*
+    Function: DefaultConfig
+    Get the settings of an environment which plays level 1 of the game in the current folder
+*/
+func DefaultConfig() Config {
+    return Config{
+        MazeFolder: ".",
+        Level: 1,
+        Levels: DefaultLevels,
+        Rewards: DefaultRewards,
+        MaxSteps: 10000,
+    }
+}
+
+/*
+    Function: NewEnv
+    Create an environment. The maze file of the level is read here
+    Input: settings
+*/
+func NewEnv(config Config) (*Env, error) {
+    level, ok := config.Levels[config.Level]
+    if !ok {
+        return nil, fmt.Errorf("level %d is not in the levels", config.Level)
+    }
+    files := config.Files
+    if files == nil {
+        files = os.DirFS(config.MazeFolder)
+    }
+    maze, err := readMazeFile(files, level.MazeFile)
+    if err != nil {
+        return nil, err
+    }
+
+    env := &Env{
+        config: config,
+        level: level,
+        mazeFile: maze,
+        done: true,
+    }
+    return env, nil
+}
+
+/*
+    ##################################
+    ## Functions of the environment ##
+    ##################################
+*/
+
+/*
+    Function: Reset
+    Start a new episode. The same seed gives the same episode for the same actions
+    Input: seed of the random numbers (enemy moves). A game started from the same seed places and moves the enemies the
+    same way (see random.go)
+*/
+func (env *Env) Reset(seed int64) Observation {
+    env.random.seed(seed)
+    env.maze = make([][]byte, len(env.mazeFile.rows))
+    env.foodPoints = [][2]float64{}
+    env.spawnPoints = [][2]float64{}
+    env.foodTotal = 0
+    for row, line := range env.mazeFile.rows {
+        env.maze[row] = []byte(line)
+        for col, char := range line {
+            x, y := getPositionFromMazePoint(col, row)
+            switch char {
+            case 'P', '1':
+                env.startX, env.startY = x, y
+            case '.', 'o':
+                env.foodPoints = append(env.foodPoints, [2]float64{x, y})
+                env.foodTotal++
+            case 'E':
+                env.spawnPoints = append(env.spawnPoints, [2]float64{x, y})
+            }
+        }
+    }
+
+    env.pacman = sprite{x: env.startX, y: env.startY, speed: env.getSpeed(env.mazeFile.pacmanSpeed, env.level.PacmanSpeed), direction: 'I'}
+    env.fruit = sprite{x: env.startX, y: env.startY}
+    if fruit := env.mazeFile.fruit; fruit != nil {
+        env.fruit.x, env.fruit.y = getPositionFromMazePoint(fruit[0], fruit[1])
+    }
+    env.foodEaten = 0
+    env.points = 0
+    env.lives = startingLives
+    env.nextExtraLife = extraLifePoints
+    env.frightenedTimer = 0
+    env.fruitTimer = 0
+    env.steps = 0
+    env.done = false
+
+    aggressions := env.getEnemyAggressions()
+    env.enemies = make([]sprite, len(aggressions))
+    for i := range env.enemies {
+        env.enemies[i].speed = env.getSpeed(env.mazeFile.enemySpeed, env.level.EnemySpeed)
+        env.enemies[i].aggression = aggressions[i]
+        env.placeEnemy(&env.enemies[i])
+    }
+    return env.getObservation()
+}
+
+/*
+    Function: Step
+    Run a frame of the game with PacMan moving in the direction of the action
+    Input: action
+    Outputs the next observation, the reward, whether the episode is done and the info of the game
+*/
+func (env *Env) Step(action Action) (Observation, float64, bool, Info) {
+    if env.done {
+        return env.getObservation(), 0, true, env.getInfo()
+    }
+    rewards := env.config.Rewards
+    reward := rewards.Step
+    env.steps++
+
+    // let's move PacMan (through a tunnel) and eat the food on its place, like the game
+    env.movePacman(actionDirections[action])
+    env.useTunnel(&env.pacman)
+    reward = reward+env.eatFood()
+
+    // then each enemy moves. An enemy which is on PacMan eats PacMan, or is eaten when it's frightened.
+    // Like the game, the other enemies still move in the frame PacMan loses the last life
+    for i := range env.enemies {
+        reward = reward+env.moveEnemy(&env.enemies[i])
+        env.useTunnel(&env.enemies[i])
+    }
+    env.updateTimers()
+
+    info := env.getInfo()
+    if info.IsWin {
+        reward = reward+rewards.Win
+    }
+    if env.config.MaxSteps > 0 && env.steps >= env.config.MaxSteps && !info.IsWin && env.lives > 0 {
+        info.IsTruncated = true
+    }
+    env.done = info.IsWin || env.lives == 0 || info.IsTruncated
+    return env.getObservation(), reward, env.done, info
+}
+
+/*
+    Function: getInfo
+    Get the info of the game
+*/
+func (env *Env) getInfo() Info {
+    return Info{
+        Points: env.points,
+        Lives: env.lives,
+        FoodLeft: env.foodTotal-env.foodEaten,
+        Steps: env.steps,
+        IsWin: env.foodEaten >= env.foodTotal,
+    }
+}
+
+/*
+    Function: getSpeed
+    Get a speed of the maze, or the speed of the level if the maze doesn't give it (like getPacmanSpeed of the game)
+    Inputs: speed of the maze (0 if it's not given) and the speed of the level
+*/
+func (env *Env) getSpeed(mazeSpeed float64, levelSpeed float64) float64 {
+    if mazeSpeed > 0 {
+        return mazeSpeed
+    }
+    return levelSpeed
+}
+
+/*
+    Function: getEnemyAggressions
+    Get how often each enemy chases PacMan. The enemies of the maze are used first, then the level (like the game)
+*/
+func (env *Env) getEnemyAggressions() []float64 {
+    if env.mazeFile.enemies != nil {
+        return env.mazeFile.enemies
+    }
+    aggressions := make([]float64, env.level.NumEnemies)
+    for i := range aggressions {
+        aggressions[i] = env.level.EnemyAggression
+    }
+    return aggressions
+}
+
+/*
+    #######################
+    ## Rules of the game ##
+    #######################
+*/
+
+/*
+    Function: getPositionFromMazePoint
+    Get the position (x, y) of a maze point, like the game
+    Inputs: column and row
+*/
+func getPositionFromMazePoint(col int, row int) (float64, float64) {
+    return float64(blockSize*col), float64(blockSize*row)
+}
+
+/*
+    Function: getMazePointFromPosition
+    Get the maze point (column, row) of a position, like the game
+    Inputs: position (x, y)
+*/
+func getMazePointFromPosition(x float64, y float64) (int, int) {
+    return int(math.Round(x/float64(blockSize))), int(math.Round(y/float64(blockSize)))
+}
+
+/*
+    Function: isPath
+    Check if a maze point is in the maze and it's not a wall
+    Inputs: column and row
+*/
+func (env *Env) isPath(col int, row int) bool {
+    return row >= 0 && row < len(env.maze) && col >= 0 && col < len(env.maze[row]) && env.maze[row][col] != '0'
+}
+
+/*
+    Function: movePacman
+    Move PacMan in a direction if there's no wall, like movePacman of the game
+    Input: direction: U, R, D, L or I
+*/
+func (env *Env) movePacman(direction byte) {
+    pacman := &env.pacman
+    col, row := getMazePointFromPosition(pacman.x, pacman.y)
+    alignedX, alignedY := getPositionFromMazePoint(col, row)
+
+    x, y := alignedX, alignedY
+    switch direction {
+    case 'U':
+        y = pacman.y-pacman.speed
+    case 'D':
+        y = pacman.y+pacman.speed
+    case 'L':
+        x = pacman.x-pacman.speed
+    case 'R':
+        x = pacman.x+pacman.speed
+    default:
+        direction = 'I'
+    }
+
+    colNew, rowNew := getMazePointFromPosition(x, y)
+    if env.isPath(colNew, rowNew) {
+        pacman.x, pacman.y, pacman.direction = x, y, direction
+    }
+}
+
+/*
+    Function: eatFood
+    Let PacMan eat the food and the fruit on its place, like eatFood of the game
+    Outputs the reward
+*/
+func (env *Env) eatFood() float64 {
+    rewards := env.config.Rewards
+    reward := 0.0
+    col, row := getMazePointFromPosition(env.pacman.x, env.pacman.y)
+
+    if env.isPath(col, row) && (env.maze[row][col] == '.' || env.maze[row][col] == 'o') {
+        if env.maze[row][col] == 'o' {
+            env.addPoints(pelletPoints)
+            env.frightenedTimer = env.level.FrightenedTime
+            for i := range env.enemies {
+                env.enemies[i].isFrightened = true
+            }
+            reward = reward+rewards.Pellet
+        } else {
+            env.addPoints(foodPoints)
+            reward = reward+rewards.Dot
+        }
+        env.maze[row][col] = ' '
+        env.foodEaten++
+
+        // the fruit shows up when PacMan has eaten enough food
+        for _, foodCount := range fruitAtFood {
+            if env.foodEaten == foodCount {
+                env.fruit.isVisible = true
+                env.fruitTimer = fruitTime
+            }
+        }
+    }
+
+    colFruit, rowFruit := getMazePointFromPosition(env.fruit.x, env.fruit.y)
+    if env.fruit.isVisible && col == colFruit && row == rowFruit {
+        env.fruit.isVisible = false
+        env.fruitTimer = 0
+        env.addPoints(fruitPoints*env.config.Level)
+        reward = reward+rewards.Fruit
+    }
+    return reward
+}
+
+/*
+    Function: addPoints
+    Add points, and give an extra life for every extraLifePoints points like the game
+    Input: points
+*/
+func (env *Env) addPoints(points int) {
+    env.points = env.points+points
+    if env.points >= env.nextExtraLife {
+        env.lives++
+        env.nextExtraLife = env.nextExtraLife+extraLifePoints
+    }
+}
+
+/*
+    Function: moveEnemy
+    Move an enemy, like moveEnemy of the game. An enemy on PacMan eats PacMan, or is eaten when it's frightened
+    Input: enemy
+    Outputs the reward
+*/
+func (env *Env) moveEnemy(enemy *sprite) float64 {
+    col, row := getMazePointFromPosition(enemy.x, enemy.y)
+    colPac, rowPac := getMazePointFromPosition(env.pacman.x, env.pacman.y)
+    isAlive := env.lives > 0
+    if isAlive && col == colPac && row == rowPac {
+        if enemy.isFrightened {
+            env.addPoints(enemyPoints)
+            enemy.isFrightened = false
+            env.placeEnemy(enemy)
+            return env.config.Rewards.Enemy
+        }
+        env.loseLife()
+        return env.config.Rewards.Death
+    }
+
+    // the enemy chooses a new direction when it has moved far enough into a maze point (see moveEnemy of the game)
+    alignedX, alignedY := getPositionFromMazePoint(col, row)
+    reasonableMoveAmount := math.Floor(float64(blockSize)/2.0)-1.0
+    if math.Abs(enemy.x-alignedX) > reasonableMoveAmount || math.Abs(enemy.y-alignedY) > reasonableMoveAmount {
+        // without lives there's no PacMan to chase, and no random number is used for it (like getEnemyTarget of the game)
+        if isAlive && !enemy.isFrightened && env.random.float() < enemy.aggression {
+            enemy.direction = env.getChaseDirection(col, row, enemy.direction, colPac, rowPac)
+        } else {
+            enemy.direction = env.getMovableDirection(col, row, enemy.direction)
+        }
+    }
+
+    switch enemy.direction {
+    case 'U':
+        enemy.x, enemy.y = alignedX, enemy.y-enemy.speed
+    case 'R':
+        enemy.x, enemy.y = enemy.x+enemy.speed, alignedY
+    case 'D':
+        enemy.x, enemy.y = alignedX, enemy.y+enemy.speed
+    case 'L':
+        enemy.x, enemy.y = enemy.x-enemy.speed, alignedY
+    }
+    return 0
+}
+
+/*
+    Function: getMovableDirection
+    Get a random movable direction from a maze point, keeping the current direction instead of turning back (like the game)
+    Inputs: maze point and the current direction
+*/
+func (env *Env) getMovableDirection(col int, row int, currentDirection byte) byte {
+    possibilities := []byte{}
+    for _, direction := range []byte{'U', 'R', 'D', 'L'} {
+        step := directionSteps[direction]
+        if env.isPath(col+step[0], row+step[1]) {
+            possibilities = append(possibilities, direction)
+        }
+    }
+    if len(possibilities) == 0 {
+        return currentDirection
+    }
+
+    direction := possibilities[env.random.intn(len(possibilities))]
+    if direction == oppositeDirections[currentDirection] {
+        step := directionSteps[currentDirection]
+        if env.isPath(col+step[0], row+step[1]) {
+            return currentDirection
+        }
+    }
+    return direction
+}
+
+/*
+    Function: getChaseDirection
+    Get the movable direction which gets closest to PacMan, without turning back unless it's a dead end (like the game)
+    Inputs: maze point, current direction and the maze point of PacMan
+*/
+func (env *Env) getChaseDirection(col int, row int, currentDirection byte, targetCol int, targetRow int) byte {
+    bestDirection := byte(0)
+    bestDistance := math.Inf(1)
+    for _, direction := range []byte{'U', 'L', 'D', 'R'} {
+        step := directionSteps[direction]
+        if !env.isPath(col+step[0], row+step[1]) || direction == oppositeDirections[currentDirection] {
+            continue
+        }
+        distance := math.Hypot(float64(targetCol-col-step[0]), float64(targetRow-row-step[1]))
+        if distance < bestDistance {
+            bestDirection = direction
+            bestDistance = distance
+        }
+    }
+    if bestDirection == 0 {
+        return env.getMovableDirection(col, row, currentDirection)
+    }
+    return bestDirection
+}
+
+/*
+    Function: placeEnemy
+    Place an enemy on a random spawn point (or a random food if there are none) which is not near PacMan (like the game)
+    Input: enemy
+*/
+func (env *Env) placeEnemy(enemy *sprite) {
+    points := env.spawnPoints
+    if len(points) == 0 {
+        points = env.foodPoints
+    }
+    point := points[env.random.intn(len(points))]
+    for i := 0; i < 10 && math.Abs(point[0]-env.pacman.x)+math.Abs(point[1]-env.pacman.y) < float64(blockSize*5); i++ {
+        point = points[env.random.intn(len(points))]
+    }
+    enemy.x, enemy.y = point[0], point[1]
+    col, row := getMazePointFromPosition(enemy.x, enemy.y)
+    enemy.direction = env.getMovableDirection(col, row, enemy.direction)
+}
+
+/*
+    Function: useTunnel
+    Move a game object which is on an end of a tunnel to the other end, going on in the same direction (like useTunnel
+    of the game)
+    Input: game object
+*/
+func (env *Env) useTunnel(object *sprite) {
+    step, ok := directionSteps[object.direction]
+    if !ok {
+        return
+    }
+    col, row := getMazePointFromPosition(object.x, object.y)
+    centerX, centerY := getPositionFromMazePoint(col, row)
+    if math.Abs(object.x-centerX)+math.Abs(object.y-centerY) >= object.speed {
+        return
+    }
+    for _, tunnel := range env.mazeFile.tunnels {
+        for end, point := range tunnel {
+            if point != [2]int{col, row} {
+                continue
+            }
+            exitX, exitY := getPositionFromMazePoint(tunnel[1-end][0], tunnel[1-end][1])
+            object.x = exitX+float64(step[0])*object.speed
+            object.y = exitY+float64(step[1])*object.speed
+            return
+        }
+    }
+}
+
+/*
+    Function: loseLife
+    PacMan loses a life. PacMan goes back to the start and the enemies are placed again (like the game)
+*/
+func (env *Env) loseLife() {
+    env.lives--
+    if env.lives == 0 {
+        return
+    }
+    env.pacman.x, env.pacman.y, env.pacman.direction = env.startX, env.startY, 'I'
+    env.frightenedTimer = 0
+    for i := range env.enemies {
+        env.enemies[i].isFrightened = false
+        env.placeEnemy(&env.enemies[i])
+    }
+}
+
+/*
+    Function: updateTimers
+    Count down the power pellet and fruit timers (like the game)
+*/
+func (env *Env) updateTimers() {
+    if env.frightenedTimer > 0 {
+        env.frightenedTimer--
+        if env.frightenedTimer == 0 {
+            for i := range env.enemies {
+                env.enemies[i].isFrightened = false
+            }
+        }
+    }
+    if env.fruitTimer > 0 {
+        env.fruitTimer--
+        if env.fruitTimer == 0 {
+            env.fruit.isVisible = false
+        }
+    }
+}
@@ -0,0 +1,182 @@
+package rl
+
+/*
+    This file contains the tests of the environment against the game.
+
+    The game plays the traces of ../testdata (see rules_test.go of the game): each line is a direction pressed for a
+    number of frames and the state of the game after them. The environment plays the same directions and has to end up
+    in the same state after each line, so its rules are the rules of the game. Traces without a seed have no enemies,
+    traces with a seed have the enemies of the level and check where they are, so the enemies, the power pellets,
+    eating enemies and losing lives of the environment are the same as in the game.
+*/
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// Let's define the folder of the game, where the maze files and the traces are
+var gameFolder = ".."
+
+// Let's define the action of each direction of a trace
+var directionActions = map[byte]Action{'I': ActionIdle, 'U': ActionUp, 'R': ActionRight, 'D': ActionDown, 'L': ActionLeft}
+
+/*
+    Function: playTrace
+    Play the directions of a trace file of the game in an environment and compare the state after each line
+    A trace with a seed is played with the enemies of the level, started from the same seed as the game
+    Inputs: test and the path to the trace file
+*/
+func playTrace(t *testing.T, traceFile string) {
+    file, err := os.Open(traceFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer file.Close()
+
+    var env *Env
+    mazeFile := ""
+    seed := int64(0)
+    scanner := bufio.NewScanner(file)
+    for lineNumber := 1; scanner.Scan(); lineNumber++ {
+        line := scanner.Text()
+        if strings.HasPrefix(line, "maze: ") {
+            mazeFile = strings.TrimPrefix(line, "maze: ")
+            continue
+        }
+        if strings.HasPrefix(line, "seed: ") {
+            if _, err := fmt.Sscanf(line, "seed: %d", &seed); err != nil {
+                t.Fatalf("%s: %q: %v", traceFile, line, err)
+            }
+            continue
+        }
+
+        // the environment starts on the first line of directions, after the maze and the seed
+        if env == nil {
+            if mazeFile == "" {
+                t.Fatalf("%s: the first line should be the maze", traceFile)
+            }
+            config := DefaultConfig()
+            config.MazeFolder = gameFolder
+            config.MaxSteps = 0
+            level := config.Levels[1]
+            level.MazeFile = mazeFile
+            if seed == 0 {
+                level.NumEnemies = 0
+            }
+            config.Levels = map[int]Level{1: level}
+            env, err = NewEnv(config)
+            if err != nil {
+                t.Fatal(err)
+            }
+            env.Reset(seed)
+        }
+
+        var direction byte
+        var frames int
+        if _, err := fmt.Sscanf(line, "%c %d", &direction, &frames); err != nil {
+            t.Fatalf("%s: %q: %v", traceFile, line, err)
+        }
+        for frame := 0; frame < frames; frame++ {
+            env.Step(directionActions[direction])
+        }
+
+        info := env.getInfo()
+        state := fmt.Sprintf("%c %d x=%g y=%g points=%d food=%d lives=%d", direction, frames, env.pacman.x, env.pacman.y,
+            info.Points, info.FoodLeft, info.Lives)
+        if len(env.enemies) > 0 {
+            positions := []string{}
+            for _, enemy := range env.enemies {
+                position := fmt.Sprintf("%g,%g", enemy.x, enemy.y)
+                if enemy.isFrightened {
+                    position = position+"F"
+                }
+                positions = append(positions, position)
+            }
+            state = state+" enemies="+strings.Join(positions, " ")
+        }
+        if state != line {
+            t.Fatalf("%s: line %d is %q, the environment gives %q", traceFile, lineNumber, line, state)
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestEnvPlaysLikeTheGame(t *testing.T) {
+    traceFiles, err := filepath.Glob(filepath.Join(gameFolder, "testdata", "rules-*.txt"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(traceFiles) == 0 {
+        t.Fatal("there are no traces of the game")
+    }
+    for _, traceFile := range traceFiles {
+        playTrace(t, traceFile)
+    }
+}
+
+func TestEnvReadsTheMazeHeader(t *testing.T) {
+    config := DefaultConfig()
+    config.MazeFolder = gameFolder
+    config.Levels = map[int]Level{1: {PacmanSpeed: 2, EnemySpeed: 2, NumEnemies: 4, MazeFile: "testdata/maze-tunnel.txt", EnemyAggression: 0.3}}
+    env, err := NewEnv(config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    env.Reset(1)
+
+    // the speed of PacMan, the fruit and the tunnel come from the header, the enemies from the level
+    if env.pacman.speed != 3 {
+        t.Errorf("PacMan has the speed %v, the maze gives 3", env.pacman.speed)
+    }
+    if env.fruit.x != 225 || env.fruit.y != 75 {
+        t.Errorf("the fruit is at %v,%v, the maze gives the maze point 15,5", env.fruit.x, env.fruit.y)
+    }
+    if len(env.mazeFile.tunnels) != 1 || len(env.enemies) != 4 {
+        t.Errorf("the maze has %d tunnels and %d enemies, expected 1 tunnel and the 4 enemies of the level", len(env.mazeFile.tunnels), len(env.enemies))
+    }
+
+    // the enemies of the header are used instead of the enemies of the level
+    maze, err := parseMaze([]string{"enemies: 0.2, 1", "---", "0000", "0P.0", "0000"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    env.mazeFile = maze
+    if aggressions := env.getEnemyAggressions(); len(aggressions) != 2 || aggressions[1] != 1 {
+        t.Errorf("the enemies are %v, the maze gives 0.2, 1", aggressions)
+    }
+}
+
+func TestEnvRejectsMapsToConvert(t *testing.T) {
+    config := DefaultConfig()
+    config.MazeFolder = gameFolder
+    level := config.Levels[1]
+    level.MazeFile = "maze.tmx"
+    config.Levels = map[int]Level{1: level}
+    if _, err := NewEnv(config); err == nil || !strings.Contains(err.Error(), "-convertmaze") {
+        t.Errorf("a Tiled map should be converted first, got %v", err)
+    }
+}
+
+func TestEnvRejectsBrokenMazes(t *testing.T) {
+    // the same broken mazes are rejected by the game (see maze_test.go of the game)
+    mazeFiles, err := filepath.Glob(filepath.Join(gameFolder, "testdata", "bad-maze-*.txt"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, mazeFile := range mazeFiles {
+        config := DefaultConfig()
+        config.MazeFolder = gameFolder
+        level := config.Levels[1]
+        level.MazeFile = filepath.Join("testdata", filepath.Base(mazeFile))
+        config.Levels = map[int]Level{1: level}
+        if _, err := NewEnv(config); err == nil {
+            t.Errorf("%s can't be played, it should be rejected", mazeFile)
+        }
+    }
+}
@@ -0,0 +1,308 @@
+package rl
+
+/*
+    This file contains the reading of the maze files, in the same format as the game (see maze.go of the game).
+
+    The header of a maze file gives the speeds, the enemies, the fruit and the tunnels of the maze, which are used
+    instead of the ones of the level, like the game. The other keys (name, author, wall color, theme and par time) are
+    only shown by the game, so they're skipped here.
+    Tiled maps and PNG mazes are not read by the environment, they're changed to maze files with the game first:
+    ./SimplePacmanGame -convertmaze maze.tmx -to maze.txt
+
+    The rules of the environment are checked against the game with the traces of ../testdata (see env_test.go), so a
+    change to the rules of the game which isn't made here too makes the tests fail.
+*/
+import (
+    "bufio"
+    "bytes"
+    "errors"
+    "fmt"
+    "io/fs"
+    "path"
+    "strconv"
+    "strings"
+)
+
+// Structure which keeps a maze read from a maze file
+type mazeInfo struct {
+    pacmanSpeed float64 // holds the speed of PacMan (0 if the maze doesn't give it)
+    enemySpeed float64 // holds the speed of the enemies (0 if the maze doesn't give it)
+    enemies []float64 // holds how often each enemy chases PacMan (nil if the maze doesn't give the enemies)
+    fruit []int // holds the maze point (column, row) where the fruit shows up (nil if it's the start of PacMan)
+    tunnels [][2][2]int // holds the two ends (column, row) of each tunnel
+    rows []string // holds the grid, the tiles of the legend are already changed to the tiles of the game
+}
+
+// Let's define the line which ends the header of a maze file, and the keys of the header which are only shown by the game
+var mazeHeaderEnd = "---"
+var shownMazeKeys = map[string]bool{"name": true, "author": true, "wall color": true, "theme": true, "par time": true}
+
+// Let's define the names of the tiles of the legend of a maze file, the same as the game
+var mazeTileNames = map[string]byte{
+    "wall": '0',
+    "food": '.',
+    "pellet": 'o',
+    "empty": ' ',
+    "spawn": 'E',
+    "player 1": 'P',
+    "player 2": '2',
+    "player 3": '3',
+    "player 4": '4',
+}
+
+/*
+    Function: readMazeFile
+    Read a maze file and its header
+    Inputs: file system (Ex: a folder, the files of a level pack opened with zip.OpenReader) and path to the maze file in it
+*/
+func readMazeFile(files fs.FS, fileName string) (mazeInfo, error) {
+    switch strings.ToLower(path.Ext(fileName)) {
+    case ".tmx", ".json", ".png":
+        return mazeInfo{}, fmt.Errorf("%s: change the map to a maze file with -convertmaze of the game first", fileName)
+    }
+    content, err := fs.ReadFile(files, fileName)
+    if err != nil {
+        return mazeInfo{}, err
+    }
+
+    lines := []string{}
+    scanner := bufio.NewScanner(bytes.NewReader(content))
+    for scanner.Scan() {
+        lines = append(lines, scanner.Text())
+    }
+    if err := scanner.Err(); err != nil {
+        return mazeInfo{}, err
+    }
+
+    maze, err := parseMaze(lines)
+    if err == nil {
+        err = checkMaze(maze)
+    }
+    if err != nil {
+        return maze, fmt.Errorf("%s: %v", fileName, err)
+    }
+    return maze, nil
+}
+
+/*
+    Function: checkMaze
+    Check that a maze can be played, with the same checks as getMazeProblems of the game: rows of the same length,
+    known tiles, a single start of each player, food, the fruit and the tunnels on paths, and the food, starts and spawn
+    points reachable from the start of PacMan. The broken mazes of ../testdata are rejected here and by the game
+    Input: maze
+*/
+func checkMaze(maze mazeInfo) error {
+    rows := maze.rows
+    if len(rows) == 0 {
+        return errors.New("THE MAZE IS EMPTY")
+    }
+
+    starts := map[byte][2]int{}
+    foodCount := 0
+    for row, line := range rows {
+        if len(line) != len(rows[0]) {
+            return fmt.Errorf("0,%d: THE ROW IS NOT AS LONG AS THE FIRST ROW", row)
+        }
+        for col := 0; col < len(line); col++ {
+            tile := line[col]
+            switch tile {
+            case '0', ' ', 'E':
+            case '.', 'o':
+                foodCount++
+            case 'P', '1', '2', '3', '4':
+                // P is the start of player 1, the same as 1
+                if tile == '1' {
+                    tile = 'P'
+                }
+                if _, ok := starts[tile]; ok {
+                    return fmt.Errorf("%d,%d: THE START IS ALREADY IN THE MAZE", col, row)
+                }
+                starts[tile] = [2]int{col, row}
+            default:
+                return fmt.Errorf("%d,%d: UNKNOWN TILE %q", col, row, tile)
+            }
+        }
+    }
+    if foodCount == 0 {
+        return errors.New("THE MAZE HAS NO FOOD")
+    }
+    if fruit := maze.fruit; fruit != nil && !isMazePath(rows, fruit[0], fruit[1]) {
+        return errors.New("THE FRUIT IS NOT ON A PATH")
+    }
+    for _, tunnel := range maze.tunnels {
+        for _, end := range tunnel {
+            if !isMazePath(rows, end[0], end[1]) {
+                return fmt.Errorf("THE TUNNEL END %d,%d IS NOT ON A PATH", end[0], end[1])
+            }
+        }
+    }
+    start, ok := starts['P']
+    if !ok {
+        return errors.New("THE MAZE HAS NO START OF PLAYER 1 (P)")
+    }
+
+    // everything PacMan eats, the other starts and the spawn points of the enemies should be reachable from the start
+    reached := map[[2]int]bool{start: true}
+    queue := [][2]int{start}
+    for len(queue) > 0 {
+        point := queue[0]
+        queue = queue[1:]
+        for _, next := range getMazeNeighbors(maze, point[0], point[1]) {
+            if !reached[next] {
+                reached[next] = true
+                queue = append(queue, next)
+            }
+        }
+    }
+    for row, line := range rows {
+        for col := 0; col < len(line); col++ {
+            if strings.IndexByte(".o234E", line[col]) >= 0 && !reached[[2]int{col, row}] {
+                return fmt.Errorf("%d,%d: PACMAN CAN'T GET HERE FROM THE START", col, row)
+            }
+        }
+    }
+    return nil
+}
+
+/*
+    Function: isMazePath
+    Check if a maze point is inside the grid and it's not a wall, like isMazePath of the game
+    Inputs: rows of the maze and the maze point
+*/
+func isMazePath(rows []string, col int, row int) bool {
+    return row >= 0 && row < len(rows) && col >= 0 && col < len(rows[row]) && rows[row][col] != '0'
+}
+
+/*
+    Function: getMazeNeighbors
+    Get the paths next to a maze point and the other end of its tunnel, like getMazeNeighbors of the game
+    Inputs: maze and the maze point
+*/
+func getMazeNeighbors(maze mazeInfo, col int, row int) [][2]int {
+    neighbors := [][2]int{}
+    for _, direction := range []byte{'U', 'R', 'D', 'L'} {
+        step := directionSteps[direction]
+        if isMazePath(maze.rows, col+step[0], row+step[1]) {
+            neighbors = append(neighbors, [2]int{col+step[0], row+step[1]})
+        }
+    }
+    for _, tunnel := range maze.tunnels {
+        for end := range tunnel {
+            if tunnel[end] == [2]int{col, row} && isMazePath(maze.rows, tunnel[1-end][0], tunnel[1-end][1]) {
+                neighbors = append(neighbors, tunnel[1-end])
+            }
+        }
+    }
+    return neighbors
+}
+
+/*
+    Function: parseMaze
+    Read the header and the grid of a maze file, like parseMaze of the game
+    Input: lines of the maze file
+*/
+func parseMaze(lines []string) (mazeInfo, error) {
+    maze := mazeInfo{}
+    headerEnd := -1
+    for i, line := range lines {
+        if strings.TrimSpace(line) == mazeHeaderEnd {
+            headerEnd = i
+            break
+        }
+    }
+    if headerEnd < 0 {
+        maze.rows = lines
+        return maze, nil
+    }
+
+    legend := map[byte]byte{}
+    for i, line := range lines[:headerEnd] {
+        if strings.TrimSpace(line) == "" {
+            continue
+        }
+        if err := parseMazeHeaderLine(&maze, legend, line); err != nil {
+            return maze, fmt.Errorf("line %d: %v", i+1, err)
+        }
+    }
+    for _, line := range lines[headerEnd+1:] {
+        row := []byte(line)
+        for col, char := range row {
+            if tile, ok := legend[char]; ok {
+                row[col] = tile
+            }
+        }
+        maze.rows = append(maze.rows, string(row))
+    }
+    return maze, nil
+}
+
+/*
+    Function: parseMazeHeaderLine
+    Read a "key: value" line of the header of a maze file, like parseMazeHeaderLine of the game
+    Inputs: maze to fill, legend to fill (character of the grid to the tile of the game) and the line
+*/
+func parseMazeHeaderLine(maze *mazeInfo, legend map[byte]byte, line string) error {
+    parts := strings.SplitN(line, ":", 2)
+    if len(parts) != 2 {
+        return fmt.Errorf("%q is not a key: value line", line)
+    }
+    key := strings.ToLower(strings.TrimSpace(parts[0]))
+    value := strings.TrimSpace(parts[1])
+
+    var err error
+    switch {
+    case shownMazeKeys[key]:
+    case key == "pacman speed":
+        maze.pacmanSpeed, err = parseMazeSpeed(value)
+    case key == "enemy speed":
+        maze.enemySpeed, err = parseMazeSpeed(value)
+    case key == "enemies":
+        maze.enemies = []float64{}
+        for _, enemy := range strings.Split(value, ",") {
+            aggression, err := strconv.ParseFloat(strings.TrimSpace(enemy), 64)
+            if err != nil || aggression < 0 || aggression > 1 {
+                return fmt.Errorf("enemies: %q should be a number from 0 to 1", strings.TrimSpace(enemy))
+            }
+            maze.enemies = append(maze.enemies, aggression)
+        }
+    case key == "fruit":
+        maze.fruit = make([]int, 2)
+        if _, scanErr := fmt.Sscanf(value, "%d,%d", &maze.fruit[0], &maze.fruit[1]); scanErr != nil {
+            err = fmt.Errorf("%q should be column,row", value)
+        }
+    case key == "tunnel":
+        tunnel := [2][2]int{}
+        if _, scanErr := fmt.Sscanf(value, "%d,%d %d,%d", &tunnel[0][0], &tunnel[0][1], &tunnel[1][0], &tunnel[1][1]); scanErr != nil {
+            err = fmt.Errorf("%q should be column,row column,row", value)
+        }
+        maze.tunnels = append(maze.tunnels, tunnel)
+    case strings.HasPrefix(key, "tile ") && len(key) == len("tile X"):
+        tile, ok := mazeTileNames[strings.ToLower(value)]
+        if !ok && len(value) == 1 {
+            tile, ok = value[0], true
+        }
+        if !ok {
+            return fmt.Errorf("%s: unknown tile %q", key, value)
+        }
+        legend[strings.TrimSpace(parts[0])[len("tile ")]] = tile
+    default:
+        return fmt.Errorf("unknown key %q", key)
+    }
+    if err != nil {
+        return fmt.Errorf("%s: %v", key, err)
+    }
+    return nil
+}
+
+/*
+    Function: parseMazeSpeed
+    Read a speed of the header of a maze file, up to half a block like the game
+    Input: speed as a string
+*/
+func parseMazeSpeed(value string) (float64, error) {
+    speed, err := strconv.ParseFloat(value, 64)
+    if err != nil || speed <= 0 || speed > float64(blockSize/2) {
+        return 0, fmt.Errorf("%q should be a number above 0 and up to %d", value, blockSize/2)
+    }
+    return speed, nil
+}
@@ -0,0 +1,91 @@
+package rl
+
+/*
+    This file contains the observations of the environment.
+
+    An observation is a stack of grids (channels) with the size of the maze. Each channel has 1 where something is and
+    0 everywhere else:
+    ChannelWalls      : walls
+    ChannelDots       : food which is left
+    ChannelPellets    : power pellets which are left
+    ChannelPacman     : PacMan
+    ChannelFrightened : frightened enemies (PacMan can eat them)
+    ChannelEnemies+i  : enemy i, one channel for each enemy of the level
+    Game objects which move are on the maze point they are closest to.
+*/
+
+// Let's define the channels of an observation
+const (
+    ChannelWalls = iota
+    ChannelDots
+    ChannelPellets
+    ChannelPacman
+    ChannelFrightened
+    ChannelEnemies // first enemy channel, the other enemies follow
+)
+
+// Structure which keeps an observation
+type Observation struct {
+    Channels int // number of channels
+    Height int // number of rows of the maze
+    Width int // number of columns of the maze
+    Data []float32 // values of all the channels, channel by channel and row by row (the index is (channel*Height+row)*Width+col)
+}
+
+/*
+    Function: At
+    Get the value of a channel at a maze point
+    Inputs: channel, row and column
+*/
+func (observation Observation) At(channel int, row int, col int) float32 {
+    return observation.Data[(channel*observation.Height+row)*observation.Width+col]
+}
+
+/*
+    Function: set
+    Set a channel at a maze point to 1. Points outside the maze are skipped
+    Inputs: channel, row and column
+*/
+func (observation Observation) set(channel int, row int, col int) {
+    if row < 0 || row >= observation.Height || col < 0 || col >= observation.Width {
+        return
+    }
+    observation.Data[(channel*observation.Height+row)*observation.Width+col] = 1
+}
+
+/*
+    Function: getObservation
+    Get the observation of the current state of the environment
+*/
+func (env *Env) getObservation() Observation {
+    observation := Observation{
+        Channels: ChannelEnemies+len(env.enemies),
+        Height: len(env.maze),
+        Width: len(env.maze[0]),
+    }
+    observation.Data = make([]float32, observation.Channels*observation.Height*observation.Width)
+
+    for row, line := range env.maze {
+        for col, char := range line {
+            switch char {
+            case '0':
+                observation.set(ChannelWalls, row, col)
+            case '.':
+                observation.set(ChannelDots, row, col)
+            case 'o':
+                observation.set(ChannelPellets, row, col)
+            }
+        }
+    }
+
+    col, row := getMazePointFromPosition(env.pacman.x, env.pacman.y)
+    observation.set(ChannelPacman, row, col)
+    for i, enemy := range env.enemies {
+        col, row := getMazePointFromPosition(enemy.x, enemy.y)
+        observation.set(ChannelEnemies+i, row, col)
+        if enemy.isFrightened {
+            observation.set(ChannelFrightened, row, col)
+        }
+    }
+    return observation
+}
@@ -0,0 +1,52 @@
+package rl
+
+/*
+    This file contains the random numbers of the environment.
+
+    The environment makes the same random numbers as the game (SplitMix64, see random.go of the game) and asks for them
+    in the same order, so an episode started with the same seed as a game plays the same way: the enemies are placed on
+    the same places and turn the same way. The traces of ../testdata with a seed check this (see env_test.go).
+*/
+
+// Structure which keeps the state of the random numbers. Its whole state is a single number, like the game
+type random struct {
+    state uint64 // holds the state of SplitMix64
+}
+
+/*
+    Function: seed
+    Start the random numbers from a seed, like seedRandom of the game
+    Input: seed
+*/
+func (r *random) seed(seed int64) {
+    r.state = uint64(seed)
+}
+
+/*
+    Function: next
+    Get the next random number (all 64 bits are random), like nextRandom of the game
+*/
+func (r *random) next() uint64 {
+    r.state = r.state+0x9E3779B97F4A7C15
+    z := r.state
+    z = (z ^ (z >> 30))*0xBF58476D1CE4E5B9
+    z = (z ^ (z >> 27))*0x94D049BB133111EB
+    return z ^ (z >> 31)
+}
+
+/*
+    Function: intn
+    Get a random integer from 0 to n-1, like randomInt of the game
+    Input: n (more than 0)
+*/
+func (r *random) intn(n int) int {
+    return int(r.next()%uint64(n))
+}
+
+/*
+    Function: float
+    Get a random number from 0 to 1 (1 is not included), like randomFloat of the game
+*/
+func (r *random) float() float64 {
+    return float64(r.next()>>11)/float64(1<<53)
+}
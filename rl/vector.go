@@ -0,0 +1,114 @@
+package rl
+
+/*
+    This file contains the vectorized environment, which runs many environments in parallel goroutines.
+
+    All the environments are stepped together: Step takes an action for each environment and gives back the results
+    of each environment. The environments are split between a number of workers (one goroutine each), so the work
+    runs on all the CPUs. An environment whose episode is done is reset by itself with the next seed, and the
+    observation of the new episode is given back (the info still has the result of the episode which ended).
+*/
+import (
+    "errors"
+    "runtime"
+    "sync"
+)
+
+// Structure which keeps environments which are stepped together
+type VecEnv struct {
+    envs []*Env // holds the environments
+    seeds []int64 // holds the seed of the current episode of each environment
+    workers int // holds the number of goroutines which step the environments
+}
+
+/*
+    Function: NewVecEnv
+    Create a number of environments with the same settings
+    Inputs: number of environments and the settings
+*/
+func NewVecEnv(count int, config Config) (*VecEnv, error) {
+    if count <= 0 {
+        return nil, errors.New("a vectorized environment needs at least one environment")
+    }
+
+    vecEnv := &VecEnv{
+        seeds: make([]int64, count),
+        workers: runtime.NumCPU(),
+    }
+    for i := 0; i < count; i++ {
+        env, err := NewEnv(config)
+        if err != nil {
+            return nil, err
+        }
+        vecEnv.envs = append(vecEnv.envs, env)
+    }
+    if vecEnv.workers > count {
+        vecEnv.workers = count
+    }
+    return vecEnv, nil
+}
+
+/*
+    Function: Len
+    Get the number of environments
+*/
+func (vecEnv *VecEnv) Len() int {
+    return len(vecEnv.envs)
+}
+
+/*
+    Function: Reset
+    Start a new episode in each environment. Environment i gets the seed seed+i
+    Input: seed
+*/
+func (vecEnv *VecEnv) Reset(seed int64) []Observation {
+    observations := make([]Observation, len(vecEnv.envs))
+    vecEnv.forEach(func(i int, env *Env) {
+        vecEnv.seeds[i] = seed+int64(i)
+        observations[i] = env.Reset(vecEnv.seeds[i])
+    })
+    return observations
+}
+
+/*
+    Function: Step
+    Step each environment with its action. Environments whose episode is done are reset with their next seed
+    (the seed of the episode plus the number of environments, so no two episodes have the same seed)
+    Input: an action for each environment
+*/
+func (vecEnv *VecEnv) Step(actions []Action) ([]Observation, []float64, []bool, []Info) {
+    count := len(vecEnv.envs)
+    observations := make([]Observation, count)
+    rewards := make([]float64, count)
+    dones := make([]bool, count)
+    infos := make([]Info, count)
+
+    vecEnv.forEach(func(i int, env *Env) {
+        observations[i], rewards[i], dones[i], infos[i] = env.Step(actions[i])
+        if dones[i] {
+            vecEnv.seeds[i] = vecEnv.seeds[i]+int64(count)
+            observations[i] = env.Reset(vecEnv.seeds[i])
+        }
+    })
+    return observations, rewards, dones, infos
+}
+
+/*
+    Function: forEach
+    Run a function for each environment on the workers, and wait until all of them are done
+    Input: function which gets the position of an environment and the environment
+*/
+func (vecEnv *VecEnv) forEach(function func(i int, env *Env)) {
+    wait := sync.WaitGroup{}
+    for worker := 0; worker < vecEnv.workers; worker++ {
+        wait.Add(1)
+        go func(worker int) {
+            defer wait.Done()
+            // each worker takes every workers-th environment, so each environment is used by a single goroutine
+            for i := worker; i < len(vecEnv.envs); i = i+vecEnv.workers {
+                function(i, vecEnv.envs[i])
+            }
+        }(worker)
+    }
+    wait.Wait()
+}
@@ -0,0 +1,628 @@
+package main
+
+/*
+    This file contains the rollback game, where two players play the versus mode over UDP without a host.
+
+    Both games run the whole game (peer to peer) and only send the inputs of their player to each other:
+    - Fixed tick: the game runs one frame (tick) on each call of the update function, and a tick only depends on the
+      world and the inputs of the tick (the game has its own random numbers, see random.go). So both games get the
+      same world from the same inputs
+    - Input delay: an input is used a few ticks after it's pressed, so it usually gets to the other game in time
+    - Prediction: when the input of the other player for a tick hasn't arrived yet, it's predicted to be the same as
+      the last input which arrived
+    - Rollback: the world is saved before each tick (see world.go). When an input arrives which is different from the
+      prediction, the world of that tick is restored and the ticks from there are run again with the right input
+    - Checksums: each game sends the checksum of the world at the last tick where the inputs of both players are known.
+      If the checksums of a tick are different, the games are out of sync (desync) and it's logged
+    If the other game falls behind by more than rollbackMaxPrediction ticks, the game waits for it.
+
+    Each packet has (big endian):
+    "PR" and the version (1 byte)
+    seed of the random numbers (8 bytes) and the number of rounds (1 byte), from player 1 so both games start the same
+    last tick with an input from the other player (4 bytes)
+    a tick and the checksum of the world at that tick (4 + 8 bytes)
+    first tick of the inputs (4 bytes), number of inputs (1 byte) and the inputs (1 byte each: U, R, D, L, I or S for space)
+    The inputs the other game hasn't received yet are sent again in every packet, so lost packets don't matter.
+*/
+import (
+    "bytes"
+    "encoding/binary"
+    "fmt"
+    "log"
+    "math/rand"
+    "net"
+    "sync"
+    "time"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure which keeps a rollback game between two players
+type RollbackSession struct {
+    conn *net.UDPConn // holds the UDP socket of this game
+    peer *net.UDPAddr // holds the address of the other game
+    link *LossyLink // holds the simulated network which delays and drops packets (nil to send them directly)
+    packets chan []byte // holds the packets received from the other game, until the next tick reads them
+    localPlayer int // holds the player of this game (0 or 1)
+    seed int64 // holds the seed of the random numbers, both games start from the same seed
+    isStarted bool // holds whether the game is started (player 2 waits for the seed from player 1)
+    isWaiting bool // holds whether the game is waiting for the other game to catch up
+    tick int // holds the next tick to run
+    inputs [2]map[int]byte // holds the known inputs of each player, by tick
+    lastLocalTick int // holds the last tick with a local input
+    lastRemoteTick int // holds the last tick where all the inputs of the other player up to it are known
+    remoteAck int // holds the last tick where the other game has all the inputs of this player up to it
+    predictions map[int]byte // holds the predicted inputs of the other player, by tick
+    rollbackFrom int // holds the first tick which has to be run again (-1 if none)
+    worlds map[int]World // holds the world at the start of each tick
+    checksums map[int]uint64 // holds the checksum of the world at the end of each tick
+    remoteChecksums map[int]uint64 // holds the checksums sent by the other game, by tick
+    frameInputs [2]byte // holds the inputs of the tick being run
+    rollbacks int // holds the number of rollbacks
+    resimulatedTicks int // holds the number of ticks which were run again
+    maxRollback int // holds the most ticks run again in a single rollback
+    checksumsCompared int // holds the number of checksums compared with the other game
+    desyncs int // holds the number of ticks where the checksums were different
+    hasVersionError bool // holds whether a packet with a different version was logged
+}
+
+// Structure which simulates a slow network. Packets are delayed and some of them are lost
+type LossyLink struct {
+    latency time.Duration // holds the time a packet takes to get to the other game
+    jitter time.Duration // holds how much the latency changes from packet to packet (more or less)
+    loss float64 // holds the part of the packets which are lost, from 0 (none) to 1 (all)
+    mutex sync.Mutex // packets are sent from more than one game, so the fields below are guarded by a mutex
+    random *rand.Rand // holds the random numbers of the network (not the random numbers of the game)
+    sent int // holds the number of packets sent
+    dropped int // holds the number of packets lost
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the version of the packets. Packets with a different version are not used
+var rollbackVersion byte = 1
+
+// Let's define the number of ticks between pressing an input and using it
+var rollbackInputDelay = 2
+
+// Let's define how many ticks the game can run ahead of the other game with predicted inputs
+var rollbackMaxPrediction = 8
+
+// Let's define how many confirmed ticks are kept (worlds, inputs and checksums)
+var rollbackHistory = 120
+
+// Variable to hold the rollback game (nil if this isn't a rollback game)
+var rollbackSession *RollbackSession
+
+// Variable to know if ticks are being run again after a rollback (sounds of these ticks were already played)
+var isResimulating = false
+
+/*
+    Function: startRollback
+    Start a rollback game on a local UDP address with the other game at the peer address
+    Inputs: local address (Ex: :7778), address of the other game and the player of this game (0 or 1)
+*/
+func startRollback(localAddress string, peerAddress string, player int) (*RollbackSession, error) {
+    local, err := net.ResolveUDPAddr("udp", localAddress)
+    if err != nil {
+        return nil, err
+    }
+    peer, err := net.ResolveUDPAddr("udp", getNetAddress(peerAddress))
+    if err != nil {
+        return nil, err
+    }
+    conn, err := net.ListenUDP("udp", local)
+    if err != nil {
+        return nil, err
+    }
+
+    // player 1 chooses the seed, player 2 gets it with the first packet
+    seed := int64(0)
+    if player == 0 {
+        seed = time.Now().UnixNano()
+    }
+    return newRollbackSession(conn, peer, player, seed), nil
+}
+
+/*
+    Function: newRollbackSession
+    Create a rollback game on a UDP socket. If the seed is given (not 0), the game starts right away
+    Inputs: UDP socket, address of the other game, the player of this game (0 or 1) and the seed
+*/
+func newRollbackSession(conn *net.UDPConn, peer *net.UDPAddr, player int, seed int64) *RollbackSession {
+    session := &RollbackSession{
+        conn: conn,
+        peer: peer,
+        packets: make(chan []byte, 256),
+        localPlayer: player,
+        lastLocalTick: -1,
+        lastRemoteTick: -1,
+        remoteAck: -1,
+        rollbackFrom: -1,
+        inputs: [2]map[int]byte{{}, {}},
+        predictions: map[int]byte{},
+        worlds: map[int]World{},
+        checksums: map[int]uint64{},
+        remoteChecksums: map[int]uint64{},
+    }
+    go session.readPackets()
+    if seed != 0 {
+        session.start(seed)
+    }
+    return session
+}
+
+/*
+    Function: start
+    Start the versus match from the seed. Both games do the same, so they have the same world
+    Input: seed
+*/
+func (session *RollbackSession) start(seed int64) {
+    session.seed = seed
+    session.isStarted = true
+
+    seedRandom(seed)
+    newGame()
+    startVersus()
+    isNewGame = false
+
+    // the first ticks have no input because of the input delay
+    for tick := 0; tick < rollbackInputDelay; tick++ {
+        session.inputs[session.localPlayer][tick] = 'I'
+    }
+    session.lastLocalTick = rollbackInputDelay-1
+}
+
+/*
+    Function: close
+    Close the UDP socket of the game
+*/
+func (session *RollbackSession) close() {
+    session.conn.Close()
+}
+
+/*
+    #############################
+    ## Functions to run a tick ##
+    #############################
+*/
+
+/*
+    Function: advance
+    Run the next tick of the game with the input pressed now. The tick is not run if the game waits for the other game
+    Input: input of the local player: U, R, D, L, I or S (space)
+*/
+func (session *RollbackSession) advance(input byte) {
+    session.receive()
+    if !session.isStarted {
+        // player 2 lets player 1 know it's there, and waits for the seed
+        session.send()
+        session.isWaiting = true
+        return
+    }
+
+    // the input pressed now is used after the input delay. While waiting, the input of that tick is already there
+    if session.lastLocalTick < session.tick+rollbackInputDelay {
+        session.lastLocalTick = session.tick+rollbackInputDelay
+        session.inputs[session.localPlayer][session.lastLocalTick] = input
+    }
+
+    // an input which is different from the prediction arrived, let's run the ticks again from there
+    if session.rollbackFrom >= 0 {
+        session.rollback()
+    }
+
+    session.isWaiting = session.tick-session.lastRemoteTick > rollbackMaxPrediction
+    if !session.isWaiting {
+        session.runTick(session.tick)
+        session.tick++
+    }
+
+    session.send()
+    session.compareChecksums()
+    session.trim()
+}
+
+/*
+    Function: runTick
+    Save the world and run a tick with the inputs of the tick. The input of the other player is predicted if it's not known
+    Input: tick
+*/
+func (session *RollbackSession) runTick(tick int) {
+    session.worlds[tick] = saveWorld()
+
+    remote := 1-session.localPlayer
+    session.frameInputs[session.localPlayer] = session.inputs[session.localPlayer][tick]
+    input, ok := session.inputs[remote][tick]
+    if ok {
+        delete(session.predictions, tick)
+    } else {
+        input = session.predictInput()
+        session.predictions[tick] = input
+    }
+    session.frameInputs[remote] = input
+
+    updateGame()
+    session.checksums[tick] = getWorldChecksum(saveWorld())
+}
+
+/*
+    Function: predictInput
+    Predict the input of the other player, which is the last known input. Space is only pressed once, so it's not repeated
+*/
+func (session *RollbackSession) predictInput() byte {
+    input, ok := session.inputs[1-session.localPlayer][session.lastRemoteTick]
+    if !ok || input == 'S' {
+        return 'I'
+    }
+    return input
+}
+
+/*
+    Function: rollback
+    Restore the world of the first tick with a wrong prediction, and run the ticks from there again up to the current tick
+*/
+func (session *RollbackSession) rollback() {
+    from := session.rollbackFrom
+    session.rollbackFrom = -1
+    world, ok := session.worlds[from]
+    if !ok {
+        log.Println("can't roll back to tick", from, "because its world is not kept")
+        return
+    }
+
+    restoreWorld(world)
+    isResimulating = true
+    for tick := from; tick < session.tick; tick++ {
+        session.runTick(tick)
+    }
+    isResimulating = false
+
+    session.rollbacks++
+    session.resimulatedTicks = session.resimulatedTicks+session.tick-from
+    if session.tick-from > session.maxRollback {
+        session.maxRollback = session.tick-from
+    }
+}
+
+/*
+    Function: getInput
+    Get the direction of a player in the tick being run
+    Input: player (0 or 1)
+*/
+func (session *RollbackSession) getInput(player int) byte {
+    input := session.frameInputs[player]
+    if input == 'S' || input == 0 {
+        return 'I'
+    }
+    return input
+}
+
+/*
+    Function: isSpacePressed
+    Check if any of the players pressed space in the tick being run
+*/
+func (session *RollbackSession) isSpacePressed() bool {
+    return session.frameInputs[0] == 'S' || session.frameInputs[1] == 'S'
+}
+
+/*
+    Function: getConfirmedTick
+    Get the last tick which was run with the known inputs of both players, so its world doesn't change anymore
+*/
+func (session *RollbackSession) getConfirmedTick() int {
+    if session.lastRemoteTick < session.tick-1 {
+        return session.lastRemoteTick
+    }
+    return session.tick-1
+}
+
+/*
+    Function: compareChecksums
+    Compare the checksums sent by the other game with the checksums of the confirmed ticks of this game
+*/
+func (session *RollbackSession) compareChecksums() {
+    confirmed := session.getConfirmedTick()
+    for tick, remoteChecksum := range session.remoteChecksums {
+        if tick > confirmed {
+            continue
+        }
+        delete(session.remoteChecksums, tick)
+
+        checksum, ok := session.checksums[tick]
+        if !ok {
+            continue
+        }
+        session.checksumsCompared++
+        if checksum != remoteChecksum {
+            session.desyncs++
+            log.Printf("desync at tick %d: the world has the checksum %x, the other game has %x", tick, checksum, remoteChecksum)
+        }
+    }
+}
+
+/*
+    Function: trim
+    Forget the worlds, inputs and checksums of old ticks which are not needed anymore
+*/
+func (session *RollbackSession) trim() {
+    oldest := session.getConfirmedTick()
+    if session.remoteAck < oldest {
+        oldest = session.remoteAck
+    }
+    oldest = oldest-rollbackHistory
+
+    // each map is trimmed by its own ticks, not only by the ticks which still have a world
+    for tick := range session.worlds {
+        if tick < oldest {
+            delete(session.worlds, tick)
+        }
+    }
+    for tick := range session.checksums {
+        if tick < oldest {
+            delete(session.checksums, tick)
+        }
+    }
+    for _, inputs := range session.inputs {
+        for tick := range inputs {
+            if tick < oldest {
+                delete(inputs, tick)
+            }
+        }
+    }
+    for tick := range session.predictions {
+        if tick < oldest {
+            delete(session.predictions, tick)
+        }
+    }
+    for tick := range session.remoteChecksums {
+        if tick < oldest {
+            delete(session.remoteChecksums, tick)
+        }
+    }
+}
+
+/*
+    ########################################
+    ## Functions to send and read packets ##
+    ########################################
+*/
+
+/*
+    Function: readPackets
+    Read the packets of the other game until the socket is closed. If too many packets are waiting, new ones are dropped
+*/
+func (session *RollbackSession) readPackets() {
+    buffer := make([]byte, 2048)
+    for {
+        size, _, err := session.conn.ReadFromUDP(buffer)
+        if err != nil {
+            return
+        }
+        select {
+        case session.packets <- append([]byte{}, buffer[:size]...):
+        default:
+        }
+    }
+}
+
+/*
+    Function: send
+    Send the inputs the other game hasn't received yet, and the checksum of the last confirmed tick
+*/
+func (session *RollbackSession) send() {
+    confirmed := session.getConfirmedTick()
+    checksum, ok := session.checksums[confirmed]
+    if !ok {
+        confirmed = -1
+    }
+
+    first := session.remoteAck+1
+    count := session.lastLocalTick-first+1
+    if count < 0 {
+        count = 0
+    }
+    if count > 255 {
+        count = 255
+    }
+
+    // writing into a bytes.Buffer doesn't fail, so the errors of binary.Write are not checked
+    buffer := &bytes.Buffer{}
+    buffer.WriteString("PR")
+    buffer.WriteByte(rollbackVersion)
+    binary.Write(buffer, binary.BigEndian, session.seed)
+    buffer.WriteByte(byte(versusRounds))
+    binary.Write(buffer, binary.BigEndian, int32(session.lastRemoteTick))
+    binary.Write(buffer, binary.BigEndian, int32(confirmed))
+    binary.Write(buffer, binary.BigEndian, checksum)
+    binary.Write(buffer, binary.BigEndian, int32(first))
+    buffer.WriteByte(byte(count))
+    for tick := first; tick < first+count; tick++ {
+        buffer.WriteByte(session.inputs[session.localPlayer][tick])
+    }
+
+    // a packet which can't be sent is like a lost packet, its inputs are sent again with the next packet
+    if session.link != nil {
+        session.link.send(session.conn, session.peer, buffer.Bytes())
+    } else {
+        session.conn.WriteToUDP(buffer.Bytes(), session.peer)
+    }
+}
+
+/*
+    Function: receive
+    Read all the packets which arrived since the last tick
+*/
+func (session *RollbackSession) receive() {
+    for {
+        select {
+        case packet := <-session.packets:
+            if err := session.readPacket(packet); err != nil && !session.hasVersionError {
+                log.Println("packet from the other game is not used:", err)
+            }
+        default:
+            return
+        }
+    }
+}
+
+/*
+    Function: readPacket
+    Read a packet of the other game, keep the new inputs and find wrong predictions
+    Input: packet
+*/
+func (session *RollbackSession) readPacket(packet []byte) error {
+    reader := bytes.NewReader(packet)
+    header := struct {
+        Magic [2]byte
+        Version byte
+        Seed int64
+        Rounds byte
+        Ack int32
+        ChecksumTick int32
+        Checksum uint64
+        First int32
+        Count byte
+    }{}
+    if err := binary.Read(reader, binary.BigEndian, &header); err != nil {
+        return err
+    }
+    if string(header.Magic[:]) != "PR" {
+        return fmt.Errorf("unknown packet")
+    }
+    if header.Version != rollbackVersion {
+        err := fmt.Errorf("the other game has version %d, this game has version %d", header.Version, rollbackVersion)
+        if !session.hasVersionError {
+            log.Println(err)
+            session.hasVersionError = true
+        }
+        return err
+    }
+    if int(header.Count) != reader.Len() {
+        return fmt.Errorf("packet has %d inputs, but %d bytes left", header.Count, reader.Len())
+    }
+
+    // player 2 starts when the seed of player 1 arrives
+    if !session.isStarted {
+        if header.Seed == 0 {
+            return nil
+        }
+        versusRounds = int(header.Rounds)
+        session.start(header.Seed)
+    }
+
+    if int(header.Ack) > session.remoteAck {
+        session.remoteAck = int(header.Ack)
+    }
+    if header.ChecksumTick >= 0 {
+        session.remoteChecksums[int(header.ChecksumTick)] = header.Checksum
+    }
+
+    remote := 1-session.localPlayer
+    for i := 0; i < int(header.Count); i++ {
+        tick := int(header.First)+i
+        input, _ := reader.ReadByte()
+        if tick <= session.lastRemoteTick {
+            continue
+        }
+        if _, ok := session.inputs[remote][tick]; ok {
+            continue
+        }
+        switch input {
+        case 'U', 'R', 'D', 'L', 'I', 'S':
+        default:
+            return fmt.Errorf("unknown input %q", input)
+        }
+        session.inputs[remote][tick] = input
+
+        // if this tick was run with a different prediction, the game has to roll back to this tick
+        if predicted, ok := session.predictions[tick]; ok {
+            delete(session.predictions, tick)
+            if predicted != input && (session.rollbackFrom < 0 || tick < session.rollbackFrom) {
+                session.rollbackFrom = tick
+            }
+        }
+    }
+
+    for {
+        if _, ok := session.inputs[remote][session.lastRemoteTick+1]; !ok {
+            break
+        }
+        session.lastRemoteTick++
+    }
+    return nil
+}
+
+/*
+    Function: send (LossyLink)
+    Send a packet over the simulated network. The packet is lost, or sent after the latency
+    Inputs: UDP socket, address of the other game and the packet
+*/
+func (link *LossyLink) send(conn *net.UDPConn, peer *net.UDPAddr, packet []byte) {
+    link.mutex.Lock()
+    link.sent++
+    lost := link.random.Float64() < link.loss
+    delay := link.latency+time.Duration((link.random.Float64()*2-1)*float64(link.jitter))
+    if lost {
+        link.dropped++
+    }
+    link.mutex.Unlock()
+
+    if lost {
+        return
+    }
+    packet = append([]byte{}, packet...)
+    time.AfterFunc(delay, func() {
+        conn.WriteToUDP(packet, peer)
+    })
+}
+
+/*
+    ########################################
+    ## Update function of a rollback game ##
+    ########################################
+*/
+
+/*
+    Function: updateRollback
+    The update function of a rollback game (instead of update in main.go)
+    The input of the local player is read from the keys, and the next tick is run
+*/
+func updateRollback(screen *Image) error {
+    input := getKeyInput(0)
+    if isKeyJustPressed(KeySpace) {
+        input = 'S'
+    }
+    rollbackSession.advance(input)
+
+    // when the game is streamed, let's send the new state of the game to the spectators
+    if spectatorServer != nil {
+        spectatorServer.publish()
+    }
+
+    // play the siren only while PacMan and enemies are moving
+    updateSiren(rollbackSession.isStarted && gameInfo.isStarted && !gameInfo.isLevelComplete && !gameInfo.isGameOver && gameInfo.freezeTimer == 0)
+
+    // When M is pressed, turn the sound on or off
+    if isKeyJustPressed(KeyM) {
+        toggleMute()
+    }
+
+    if isDrawingSkipped() {
+        return nil
+    }
+    drawGame(screen)
+
+    // let's let the player know when the game waits for the other game
+    if rollbackSession.isWaiting {
+        drawTextCentered(screen, "WAITING FOR THE OTHER PLAYER", screenSizeX/2, playfieldY+blockSize, getTextColor())
+    }
+    return nil
+}
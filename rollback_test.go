@@ -0,0 +1,186 @@
+package main
+
+/*
+    This file contains the tests of the rollback game (see rollback.go).
+
+    Two players run in the test and send their inputs over UDP on 127.0.0.1, without opening the game window.
+    The packets go through a simulated network which delays them (latency) and loses some of them (loss), so the games
+    have to predict inputs and roll back. The games take turns running a tick on the same global variables, so the
+    world of each game is saved after its tick and restored before its next tick (see world.go).
+    The player who plays PacMan presses the directions the autopilot gives, the player of the enemy changes its
+    direction now and then.
+*/
+import (
+    "math/rand"
+    "net"
+    "testing"
+    "time"
+)
+
+// Let's have variables to define the simulated network of the rollback test
+var rollbackTestLatency = 60*time.Millisecond
+var rollbackTestLoss = 0.1
+
+// Let's define the number of frames each game runs while the players press inputs (then the games run until all inputs arrived)
+var rollbackTestFrames = 600
+
+/*
+    Function: getRollbackTestInput
+    Get the input a player presses in the rollback test
+    The player of PacMan follows the autopilot, the player of the enemy changes its direction every 20 frames
+    Space is pressed now and then, so the next round starts when a round is over
+    Inputs: rollback game of the player, frame, random numbers of the player and the direction of the enemy
+*/
+func getRollbackTestInput(session *RollbackSession, frame int, random *rand.Rand, enemyInput *byte) byte {
+    if (gameInfo.isLevelComplete || gameInfo.isGameOver) && frame%30 == session.localPlayer*15 {
+        return 'S'
+    }
+    if session.localPlayer == versusPacmanPlayer && len(pacmen) > 0 {
+        return getAutopilotInput(pacmen[0])
+    }
+    if frame%20 == 0 {
+        *enemyInput = "URDL"[random.Intn(4)]
+    }
+    return *enemyInput
+}
+
+func TestRollback(t *testing.T) {
+    // player 2 starts from the seed of player 1, the checksums of all the confirmed ticks compared by the games
+    // are the same (no desync) and both games end with the same world at the last tick confirmed by both of them
+    if testing.Short() {
+        t.Skip("the rollback game runs in real time")
+    }
+
+    // let's count the dots eaten by PacMan, like in the game
+    listeners := eventListeners
+    addEventListener(countVersusDot)
+    defer func() {
+        eventListeners = listeners
+        rollbackSession = nil
+        versusRound = 0
+        newGame()
+    }()
+
+    conns := []*net.UDPConn{}
+    for i := 0; i < 2; i++ {
+        conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer conn.Close()
+        conns = append(conns, conn)
+    }
+    link := &LossyLink{
+        latency: rollbackTestLatency,
+        jitter: rollbackTestLatency/3,
+        loss: rollbackTestLoss,
+        random: rand.New(rand.NewSource(1)),
+    }
+
+    // player 1 starts right away, player 2 starts when the seed of player 1 arrives
+    setScreenSize()
+    sessions := []*RollbackSession{}
+    worlds := []World{}
+    for i := 0; i < 2; i++ {
+        seed := int64(0)
+        if i == 0 {
+            seed = 20240601
+        }
+        session := newRollbackSession(conns[i], conns[1-i].LocalAddr().(*net.UDPAddr), i, seed)
+        session.link = link
+        sessions = append(sessions, session)
+        worlds = append(worlds, saveWorld())
+    }
+
+    // each player has their own random numbers to choose the direction of the enemy
+    randoms := []*rand.Rand{rand.New(rand.NewSource(2)), rand.New(rand.NewSource(3))}
+    enemyInputs := []byte{'I', 'I'}
+
+    // after the frames with inputs, let's give the games some time until all the inputs arrived
+    frames := rollbackTestFrames
+    for frame := 0; frame < frames*2; frame++ {
+        if frame >= frames && sessions[0].getConfirmedTick() >= frames && sessions[1].getConfirmedTick() >= frames {
+            break
+        }
+        for i, session := range sessions {
+            restoreWorld(worlds[i])
+            rollbackSession = session
+
+            input := byte('I')
+            if frame < frames && session.isStarted {
+                input = getRollbackTestInput(session, frame, randoms[i], &enemyInputs[i])
+            }
+            session.advance(input)
+            worlds[i] = saveWorld()
+        }
+        time.Sleep(time.Second/60)
+    }
+
+    if !sessions[1].isStarted || sessions[1].seed != sessions[0].seed {
+        t.Fatal("player 2 didn't start from the seed of player 1")
+    }
+    for i, session := range sessions {
+        if session.desyncs > 0 {
+            t.Errorf("player %d found %d desyncs", i+1, session.desyncs)
+        }
+        if session.checksumsCompared == 0 {
+            t.Errorf("player %d didn't compare any checksums", i+1)
+        }
+    }
+
+    confirmed := sessions[0].getConfirmedTick()
+    if sessions[1].getConfirmedTick() < confirmed {
+        confirmed = sessions[1].getConfirmedTick()
+    }
+    if confirmed < frames {
+        t.Fatalf("only %d ticks were confirmed by both players", confirmed+1)
+    }
+    if sessions[0].checksums[confirmed] != sessions[1].checksums[confirmed] {
+        t.Errorf("the players have different worlds at tick %d", confirmed)
+    }
+
+    for i, session := range sessions {
+        t.Logf("player %d: %d ticks, %d rollbacks, %d ticks run again (at most %d at once), %d checksums compared",
+            i+1, session.tick, session.rollbacks, session.resimulatedTicks, session.maxRollback, session.checksumsCompared)
+    }
+    t.Logf("round %d/%d, match points %d - %d", versusRound, versusRounds, getVersusPoints(0), getVersusPoints(1))
+    t.Logf("network: %d packets sent, %d lost, latency %v", link.sent, link.dropped, link.latency)
+}
+
+func TestRollbackChecksum(t *testing.T) {
+    // the checksum changes when the world changes, so a desync would be found
+    setScreenSize()
+    newGame()
+    world := saveWorld()
+    checksum := getWorldChecksum(world)
+    world.pacmen[0].x = world.pacmen[0].x+0.5
+    if getWorldChecksum(world) == checksum {
+        t.Error("the checksum didn't change when PacMan moved")
+    }
+}
+
+func TestRollbackTrim(t *testing.T) {
+    // the inputs of old ticks are forgotten, also the ticks which don't have a world
+    session := &RollbackSession{
+        tick: 1000,
+        lastRemoteTick: 999,
+        remoteAck: 999,
+        inputs: [2]map[int]byte{{5: 'U', 990: 'L'}, {6: 'R', 990: 'D'}},
+        predictions: map[int]byte{7: 'I'},
+        worlds: map[int]World{990: World{}},
+        checksums: map[int]uint64{8: 1},
+        remoteChecksums: map[int]uint64{9: 1},
+    }
+    session.trim()
+    for player, inputs := range session.inputs {
+        if len(inputs) != 1 {
+            t.Errorf("player %d has the inputs of %d ticks, expected only the input of tick 990", player+1, len(inputs))
+        }
+    }
+    if len(session.predictions) != 0 || len(session.checksums) != 0 || len(session.remoteChecksums) != 0 {
+        t.Error("the predictions and checksums of old ticks should be forgotten")
+    }
+    if len(session.worlds) != 1 {
+        t.Error("the world of tick 990 should be kept")
+    }
+}
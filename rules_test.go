@@ -0,0 +1,225 @@
+package main
+
+/*
+    This file contains the tests of the rules of the game, played along the traces of testdata.
+
+    A trace is a game played on a maze (testdata/rules-*.txt). Its first line is the maze file, then each line is a
+    direction pressed for a number of frames and where PacMan is after them, with the points, the food left and the
+    lives. Ex:
+        maze: maze01.txt
+        L 12 x=225 y=240 points=60 food=268 lives=3
+    A trace without a seed is played without enemies. A trace with a seed line after the maze is played with the
+    enemies of the level, the random numbers started from the seed, and each line ends with where the enemies are
+    (F after a frightened enemy). So the enemies, the power pellets, eating enemies and losing lives are checked too:
+        maze: maze01.txt
+        seed: 7
+        L 12 x=225 y=240 points=60 food=268 lives=3 enemies=195,15 300,75F
+
+    The rl package plays the same traces (see rl/env_test.go), so the rules of the environment can't drift from the
+    rules of the game. After changing the rules on purpose, the traces are written again with the same directions (the
+    autopilot plays the mazes which have no trace yet) with:
+    go test -run TestRulesTraces -update
+*/
+import (
+    "bufio"
+    "flag"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "strings"
+    "testing"
+)
+
+// Structure which keeps a trace of the game
+type RulesTrace struct {
+    fileName string // holds the path to the trace file
+    mazeFile string // holds the path to the maze file the trace is played on
+    seed int64 // holds the seed of the random numbers (0 for a trace without enemies)
+}
+
+// Structure which keeps a line of a trace
+type RulesTraceStep struct {
+    direction byte // holds the direction pressed
+    frames int // holds the number of frames it's pressed for
+    line string // holds the line of the trace, with the state of the game after the frames
+}
+
+// Let's define the traces and the most frames of a trace
+var rulesTraces = []RulesTrace{
+    {"testdata/rules-maze01.txt", "maze01.txt", 0},
+    {"testdata/rules-maze02.txt", "maze02.txt", 0},
+    {"testdata/rules-maze-tunnel.txt", "testdata/maze-tunnel.txt", 0},
+    {"testdata/rules-enemies-maze01.txt", "maze01.txt", 7},
+    {"testdata/rules-enemies-maze02.txt", "maze02.txt", 3},
+    {"testdata/rules-deaths-maze01.txt", "maze01.txt", 12},
+}
+var rulesTraceMaxFrames = 6000
+
+// Variable to know if the traces should be written again
+var updateTraces = flag.Bool("update", false, "play the traces of testdata again with the autopilot and write them")
+
+/*
+    Function: formatRulesTraceStep
+    Write the state of the game after some frames as a line of a trace
+    Inputs: direction and the number of frames
+*/
+func formatRulesTraceStep(direction byte, frames int) string {
+    pacman := pacmen[0]
+    line := fmt.Sprintf("%c %d x=%g y=%g points=%d food=%d lives=%d", direction, frames, pacman.x, pacman.y,
+        gameInfo.stats[0].points, gameInfo.maxScore-gameInfo.score, gameInfo.stats[0].lives)
+    if len(enemies) == 0 {
+        return line
+    }
+    positions := []string{}
+    for _, enemy := range enemies {
+        position := fmt.Sprintf("%g,%g", enemy.x, enemy.y)
+        if enemy.isFrightened {
+            position = position+"F"
+        }
+        positions = append(positions, position)
+    }
+    return line+" enemies="+strings.Join(positions, " ")
+}
+
+/*
+    Function: readRulesTrace
+    Read the lines of a trace file
+    Inputs: test and the path to the trace file
+    Outputs the maze file, the seed (0 if the trace has no seed) and the lines
+*/
+func readRulesTrace(t *testing.T, fileName string) (string, int64, []RulesTraceStep) {
+    file, err := os.Open(fileName)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer file.Close()
+
+    mazeFile := ""
+    seed := int64(0)
+    steps := []RulesTraceStep{}
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        line := scanner.Text()
+        if strings.HasPrefix(line, "maze: ") {
+            mazeFile = strings.TrimPrefix(line, "maze: ")
+            continue
+        }
+        if strings.HasPrefix(line, "seed: ") {
+            if _, err := fmt.Sscanf(line, "seed: %d", &seed); err != nil {
+                t.Fatalf("%s: %q: %v", fileName, line, err)
+            }
+            continue
+        }
+        step := RulesTraceStep{line: line}
+        if _, err := fmt.Sscanf(line, "%c %d", &step.direction, &step.frames); err != nil {
+            t.Fatalf("%s: %q: %v", fileName, line, err)
+        }
+        steps = append(steps, step)
+    }
+    if err := scanner.Err(); err != nil {
+        t.Fatal(err)
+    }
+    return mazeFile, seed, steps
+}
+
+/*
+    Function: startRulesTrace
+    Start a game of one player on the maze of a trace, without waiting before moving (freezeTime is 0 while the traces
+    are played). A trace without a seed has no enemies
+    Input: trace
+*/
+func startRulesTrace(trace RulesTrace) {
+    level := LEVELS[1]
+    level.mazeFile = trace.mazeFile
+    LEVELS[1] = level
+
+    // the enemies are placed with the random numbers when the level starts, like Reset of the environment
+    setScreenSize()
+    seedRandom(trace.seed)
+    newGame()
+    startPlayers(1)
+    isNewGame = false
+    if trace.seed == 0 {
+        enemies = nil
+    }
+}
+
+/*
+    Function: playRulesTrace
+    Play a game with the directions of a trace (or the autopilot when the trace is written) and get the lines of the trace
+    Inputs: trace and its lines (nil to let the autopilot play)
+*/
+func playRulesTrace(trace RulesTrace, steps []RulesTraceStep) []string {
+    startRulesTrace(trace)
+    lines := []string{}
+
+    // the directions are pressed like in the terminal, where PacMan keeps going in the last direction pressed
+    if steps != nil {
+        for _, step := range steps {
+            tuiMove = step.direction
+            for frame := 0; frame < step.frames; frame++ {
+                updateGame()
+            }
+            lines = append(lines, formatRulesTraceStep(step.direction, step.frames))
+        }
+        return lines
+    }
+
+    // the autopilot plays until the maze is cleared or the game is over, the direction is written each time it changes
+    direction, frames := byte(0), 0
+    for frame := 0; frame < rulesTraceMaxFrames && !gameInfo.isLevelComplete && !gameInfo.isGameOver; frame++ {
+        input := getAutopilotInput(pacmen[0])
+        if frames > 0 && input != direction {
+            lines = append(lines, formatRulesTraceStep(direction, frames))
+            frames = 0
+        }
+        direction, frames = input, frames+1
+        tuiMove = input
+        updateGame()
+    }
+    return append(lines, formatRulesTraceStep(direction, frames))
+}
+
+func TestRulesTraces(t *testing.T) {
+    level := LEVELS[1]
+    waitTime := freezeTime
+    isTUI = true
+    freezeTime = 0
+    defer func() {
+        LEVELS[1] = level
+        freezeTime = waitTime
+        isTUI = false
+    }()
+
+    for _, trace := range rulesTraces {
+        header := "maze: "+trace.mazeFile+"\n"
+        if trace.seed != 0 {
+            header = header+fmt.Sprintf("seed: %d\n", trace.seed)
+        }
+        if *updateTraces {
+            // a trace which is already there keeps its directions (Ex: the tunnel, which the autopilot doesn't take)
+            var steps []RulesTraceStep
+            if _, err := os.Stat(trace.fileName); err == nil {
+                _, _, steps = readRulesTrace(t, trace.fileName)
+            }
+            lines := playRulesTrace(trace, steps)
+            content := header+strings.Join(lines, "\n")+"\n"
+            if err := ioutil.WriteFile(trace.fileName, []byte(content), 0644); err != nil {
+                t.Fatal(err)
+            }
+            continue
+        }
+
+        traceMaze, seed, steps := readRulesTrace(t, trace.fileName)
+        if traceMaze != trace.mazeFile || seed != trace.seed {
+            t.Fatalf("%s is a trace of %s with the seed %d, not %s with the seed %d", trace.fileName, traceMaze, seed, trace.mazeFile, trace.seed)
+        }
+        lineOffset := strings.Count(header, "\n")+1
+        for i, line := range playRulesTrace(trace, steps) {
+            if line != steps[i].line {
+                t.Errorf("%s: line %d is %q, the game gives %q", trace.fileName, i+lineOffset, steps[i].line, line)
+                break
+            }
+        }
+    }
+}
@@ -0,0 +1,579 @@
+package main
+
+/*
+    This file contains saving and continuing a game.
+
+    F5 saves the game being played into savegame.json (quicksave). When a saved game exists, the start screen lets the
+    player continue it with Enter. The saved game has the whole state of the game (see world.go): the level, the maze
+    with the eaten food, the positions and directions of PacMen and enemies, the timers, the lives and points of each
+    player, the state of the random numbers, the players waiting for their turn and the versus match.
+
+    The file has the version of its format. When the format changes, saveGameVersion goes up and a function which
+    changes a saved game of the previous version to the new version is added to saveGameMigrations, so older saved
+    games can still be continued. A saved game of a version which can't be read is not loaded and the reason is shown.
+*/
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "log"
+    "os"
+    "time"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+
+// Structure of a saved game file
+type SaveGame struct {
+    Version int `json:"version"` // version of the format of the file
+    SavedAt time.Time `json:"savedAt"` // when the game was saved
+    Random uint64 `json:"random,string"` // state of the random numbers (as a string, a JSON number can't hold all of it)
+    Pack string `json:"pack,omitempty"` // level pack the game is played with (saved games without it are of the classic levels)
+    IsNewGame bool `json:"isNewGame"` // whether the start screen is for a new game
+    NumPlayers int `json:"numPlayers"` // number of players taking turns
+    CurrentPlayer int `json:"currentPlayer"` // player who has the turn
+    Game SavedGame `json:"game"` // game being played
+    Players []SavedPlayer `json:"players"` // saved games of the players taking turns
+    Versus SavedVersus `json:"versus"` // the versus match
+}
+
+// Structure of the game of a player in a saved game
+type SavedGame struct {
+    Level int `json:"level"`
+    Score int `json:"score"` // food eaten on this level
+    MaxScore int `json:"maxScore"`
+    IsStarted bool `json:"isStarted"`
+    IsGameOver bool `json:"isGameOver"`
+    IsLevelComplete bool `json:"isLevelComplete"`
+    Maze []string `json:"maze"` // rows of the maze with the eaten food
+    Stats []SavedStats `json:"stats"` // lives and points of each PacMan
+    Fruits int `json:"fruits"`
+    FrightenedTimer int `json:"frightenedTimer"`
+    FruitTimer int `json:"fruitTimer"`
+    FreezeTimer int `json:"freezeTimer"`
+    Pacmen []SavedSprite `json:"pacmen,omitempty"` // only the game being played has game objects
+    Enemies []SavedSprite `json:"enemies,omitempty"`
+    Fruit *SavedSprite `json:"fruit,omitempty"`
+}
+
+// Structure of the lives and points of a player in a saved game
+type SavedStats struct {
+    Lives int `json:"lives"`
+    Points int `json:"points"`
+    NextExtraLife int `json:"nextExtraLife"`
+}
+
+// Structure of a game object in a saved game
+type SavedSprite struct {
+    X float64 `json:"x"`
+    Y float64 `json:"y"`
+    Direction string `json:"direction"` // U, R, D, L, I or empty (didn't move yet)
+    Speed float64 `json:"speed"`
+    StartX float64 `json:"startX"`
+    StartY float64 `json:"startY"`
+    Visible bool `json:"visible"`
+    IsFrightened bool `json:"isFrightened"`
+    Aggression float64 `json:"aggression"` // how often an enemy chases PacMan (-1 in older saved games, the enemy takes the one of the level)
+    Player int `json:"player"`
+}
+
+// Structure of a player waiting for their turn in a saved game
+type SavedPlayer struct {
+    Number int `json:"number"`
+    Game SavedGame `json:"game"`
+}
+
+// Structure of the versus match in a saved game
+type SavedVersus struct {
+    IsPlaying bool `json:"isPlaying"`
+    Round int `json:"round"`
+    Rounds int `json:"rounds"`
+    PacmanPlayer int `json:"pacmanPlayer"`
+    EnemyInput string `json:"enemyInput"`
+    Scores [2]SavedVersusScore `json:"scores"`
+}
+
+// Structure of the score of a player of the versus match in a saved game
+type SavedVersusScore struct {
+    Dots int `json:"dots"`
+    Catches int `json:"catches"`
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the file of the saved game and the version of its format
+var saveGameFile = "savegame.json"
+var saveGameVersion = 2
+
+/*
+    Let's define the functions which change a saved game of a version to the next version (the key is the old version).
+    A saved game is changed as decoded JSON, Ex: for a new field "speedBonus" in version 2:
+    1: func(save map[string]interface{}) error { save["speedBonus"] = 0; return nil },
+*/
+var saveGameMigrations = map[int]func(save map[string]interface{}) error{
+    // version 2 saves how often each enemy chases PacMan. The enemies of older saved games take the one of the level
+    1: func(save map[string]interface{}) error {
+        game, _ := save["game"].(map[string]interface{})
+        enemies, _ := game["enemies"].([]interface{})
+        for _, enemy := range enemies {
+            if enemy, ok := enemy.(map[string]interface{}); ok {
+                enemy["aggression"] = -1
+            }
+        }
+        return nil
+    },
+}
+
+// Let's define how long (frames) a message about saving is shown
+var saveMessageTime = 120
+
+// Variable to know if there's a saved game to continue
+var hasSaveGame = false
+
+// Variables to hold the message about saving shown on the screen, and the frames it's shown for
+var saveMessage = ""
+var saveMessageTimer = 0
+
+/*
+    #####################
+    ## Saving the game ##
+    #####################
+*/
+
+/*
+    Function: saveGame
+    Write the game being played into the saved game file
+*/
+func saveGame() {
+    content, err := json.MarshalIndent(getSaveGame(saveWorld()), "", "  ")
+    if err == nil {
+        err = ioutil.WriteFile(saveGameFile, content, 0644)
+    }
+    if err != nil {
+        log.Println("the game can't be saved:", err)
+        showSaveMessage("SAVE FAILED")
+        return
+    }
+    hasSaveGame = true
+    showSaveMessage("GAME SAVED")
+}
+
+/*
+    Function: getSaveGame
+    Get the saved game of a saved world
+    Input: saved world
+*/
+func getSaveGame(world World) SaveGame {
+    save := SaveGame{
+        Version: saveGameVersion,
+        SavedAt: time.Now(),
+        Random: world.random,
+        Pack: levelPackName,
+        IsNewGame: world.isNewGame,
+        NumPlayers: world.numPlayers,
+        CurrentPlayer: world.currentPlayer,
+        Game: getSavedGame(world.gameInfo),
+        Players: []SavedPlayer{},
+        Versus: SavedVersus{
+            IsPlaying: world.isVersus,
+            Round: world.versusRound,
+            Rounds: world.versusRounds,
+            PacmanPlayer: world.versusPacmanPlayer,
+            EnemyInput: formatSavedDirection(world.versusEnemyInput),
+        },
+    }
+    for i, score := range world.versusScores {
+        save.Versus.Scores[i] = SavedVersusScore{Dots: score.dots, Catches: score.catches}
+    }
+
+    for _, pacman := range world.pacmen {
+        save.Game.Pacmen = append(save.Game.Pacmen, getSavedSprite(pacman))
+    }
+    for _, enemy := range world.enemies {
+        save.Game.Enemies = append(save.Game.Enemies, getSavedSprite(enemy))
+    }
+    savedFruit := getSavedSprite(world.fruit)
+    save.Game.Fruit = &savedFruit
+
+    for _, player := range world.players {
+        save.Players = append(save.Players, SavedPlayer{Number: player.number, Game: getSavedGame(player.gameInfo)})
+    }
+    return save
+}
+
+/*
+    Function: getSavedGame
+    Get the saved game of a game info (without the game objects)
+    Input: game info
+*/
+func getSavedGame(info GameInfo) SavedGame {
+    game := SavedGame{
+        Level: info.level,
+        Score: info.score,
+        MaxScore: info.maxScore,
+        IsStarted: info.isStarted,
+        IsGameOver: info.isGameOver,
+        IsLevelComplete: info.isLevelComplete,
+        Maze: append([]string{}, info.maze...),
+        Stats: []SavedStats{},
+        Fruits: info.fruits,
+        FrightenedTimer: info.frightenedTimer,
+        FruitTimer: info.fruitTimer,
+        FreezeTimer: info.freezeTimer,
+    }
+    for _, stats := range info.stats {
+        game.Stats = append(game.Stats, SavedStats{Lives: stats.lives, Points: stats.points, NextExtraLife: stats.nextExtraLife})
+    }
+    return game
+}
+
+/*
+    Function: getSavedSprite
+    Get the saved game object of a game object
+    Input: game object
+*/
+func getSavedSprite(sprite Sprite) SavedSprite {
+    return SavedSprite{
+        X: sprite.x,
+        Y: sprite.y,
+        Direction: formatSavedDirection(sprite.direction),
+        Speed: sprite.speed,
+        StartX: sprite.startX,
+        StartY: sprite.startY,
+        Visible: sprite.visibility,
+        IsFrightened: sprite.isFrightened,
+        Aggression: sprite.aggression,
+        Player: sprite.player,
+    }
+}
+
+/*
+    Function: formatSavedDirection
+    Get the direction of a saved game. A game object which didn't move yet has no direction, which is saved as ""
+    Input: direction
+*/
+func formatSavedDirection(direction byte) string {
+    if direction == 0 {
+        return ""
+    }
+    return string(direction)
+}
+
+/*
+    #########################
+    ## Continuing the game ##
+    #########################
+*/
+
+/*
+    Function: readSaveGame
+    Read a saved game file into a saved world. A saved game of an older version is changed to the current version first
+    Input: path to the saved game file
+*/
+func readSaveGame(fileName string) (World, error) {
+    content, err := ioutil.ReadFile(fileName)
+    if err != nil {
+        return World{}, err
+    }
+
+    // let's read the version first. Numbers are kept as they are written, so nothing is lost while changing the saved game
+    decoder := json.NewDecoder(bytes.NewReader(content))
+    decoder.UseNumber()
+    raw := map[string]interface{}{}
+    if err := decoder.Decode(&raw); err != nil {
+        return World{}, fmt.Errorf("%s is not a saved game: %v", fileName, err)
+    }
+    number, ok := raw["version"].(json.Number)
+    if !ok {
+        return World{}, fmt.Errorf("%s is not a saved game: it has no version", fileName)
+    }
+    version, err := number.Int64()
+    if err != nil || version < 1 {
+        return World{}, fmt.Errorf("%s has an unknown version %s", fileName, number)
+    }
+    if int(version) > saveGameVersion {
+        return World{}, fmt.Errorf("%s was saved by a newer game (version %d), this game reads saved games up to version %d", fileName, version, saveGameVersion)
+    }
+
+    // let's change an older saved game to the current version, one version at a time
+    for oldVersion := int(version); oldVersion < saveGameVersion; oldVersion++ {
+        migrate, ok := saveGameMigrations[oldVersion]
+        if !ok {
+            return World{}, fmt.Errorf("%s has version %d, which this game can't read anymore", fileName, oldVersion)
+        }
+        if err := migrate(raw); err != nil {
+            return World{}, fmt.Errorf("%s can't be changed from version %d: %v", fileName, oldVersion, err)
+        }
+        raw["version"] = oldVersion+1
+    }
+    if content, err = json.Marshal(raw); err != nil {
+        return World{}, err
+    }
+
+    save := SaveGame{}
+    if err := json.Unmarshal(content, &save); err != nil {
+        return World{}, fmt.Errorf("%s: %v", fileName, err)
+    }
+    world, err := getSavedWorld(save)
+    if err != nil {
+        return World{}, fmt.Errorf("%s: %v", fileName, err)
+    }
+    return world, nil
+}
+
+/*
+    Function: getSavedWorld
+    Get the saved world of a saved game. The game objects have no images, loadWorld gives them the images of the game
+    Input: saved game
+*/
+func getSavedWorld(save SaveGame) (World, error) {
+    // the levels of another pack are different, so the game can only be continued with the same pack
+    pack := save.Pack
+    if pack == "" {
+        pack = classicPackName
+    }
+    if pack != levelPackName {
+        return World{}, fmt.Errorf("the game was saved with the level pack %s, it's played with %s now", pack, levelPackName)
+    }
+    if err := checkSavedGame(save.Game); err != nil {
+        return World{}, err
+    }
+    if save.Game.Fruit == nil {
+        return World{}, errors.New("the saved game has no fruit")
+    }
+
+    // a hand-edited saved game could point to players and PacMen which aren't there, so let's check them all
+    if save.NumPlayers < 1 || save.NumPlayers > len(save.Players) {
+        return World{}, fmt.Errorf("the saved game has %d players taking turns and the saved games of %d players", save.NumPlayers, len(save.Players))
+    }
+    if save.CurrentPlayer < 0 || save.CurrentPlayer >= save.NumPlayers {
+        return World{}, fmt.Errorf("player %d has the turn, the saved game has %d players", save.CurrentPlayer+1, save.NumPlayers)
+    }
+    for i, player := range save.Players {
+        if err := checkSavedGame(player.Game); err != nil {
+            return World{}, fmt.Errorf("player %d: %v", i+1, err)
+        }
+    }
+    for i, pacman := range save.Game.Pacmen {
+        if pacman.Player < 0 || pacman.Player >= len(save.Game.Stats) {
+            return World{}, fmt.Errorf("PacMan %d is of player %d, the saved game has %d players", i+1, pacman.Player+1, len(save.Game.Stats))
+        }
+    }
+    for i, sprite := range append(append([]SavedSprite{*save.Game.Fruit}, save.Game.Pacmen...), save.Game.Enemies...) {
+        if col, row := getMazePointFromPosition(sprite.X, sprite.Y); row < 0 || row >= len(save.Game.Maze) || col < 0 || col >= len(save.Game.Maze[row]) {
+            return World{}, fmt.Errorf("game object %d is outside the maze (%g,%g)", i+1, sprite.X, sprite.Y)
+        }
+    }
+    if save.Versus.IsPlaying && (save.Versus.PacmanPlayer < 0 || save.Versus.PacmanPlayer > 1 || save.Versus.Round < 1) {
+        return World{}, fmt.Errorf("the versus match has round %d and PacMan player %d", save.Versus.Round, save.Versus.PacmanPlayer+1)
+    }
+
+    world := World{
+        gameInfo: getSavedGameInfo(save.Game),
+        fruit: getSavedSpriteState(*save.Game.Fruit),
+        random: save.Random,
+        isNewGame: save.IsNewGame,
+        numPlayers: save.NumPlayers,
+        currentPlayer: save.CurrentPlayer,
+        isVersus: save.Versus.IsPlaying,
+        versusRound: save.Versus.Round,
+        versusRounds: save.Versus.Rounds,
+        versusPacmanPlayer: save.Versus.PacmanPlayer,
+        versusEnemyInput: parseSavedDirection(save.Versus.EnemyInput),
+    }
+    for i, score := range save.Versus.Scores {
+        world.versusScores[i] = VersusScore{dots: score.Dots, catches: score.Catches}
+    }
+    for _, pacman := range save.Game.Pacmen {
+        world.pacmen = append(world.pacmen, getSavedSpriteState(pacman))
+    }
+    for _, enemy := range save.Game.Enemies {
+        world.enemies = append(world.enemies, getSavedSpriteState(enemy))
+    }
+    for _, player := range save.Players {
+        world.players = append(world.players, PlayerState{number: player.Number, gameInfo: getSavedGameInfo(player.Game)})
+    }
+    return world, nil
+}
+
+/*
+    Function: checkSavedGame
+    Check that the game of a player in a saved game can be played: the level exists, the maze has the size of the maze
+    of the level and the game has the lives and points of its players
+    Input: saved game
+*/
+func checkSavedGame(game SavedGame) error {
+    if _, ok := LEVELS[game.Level]; !ok {
+        return fmt.Errorf("level %d doesn't exist", game.Level)
+    }
+    if len(game.Stats) == 0 {
+        return errors.New("the saved game has no players")
+    }
+    levelMaze := getLevelMaze(game.Level).rows
+    if len(game.Maze) != len(levelMaze) {
+        return fmt.Errorf("the maze has %d rows, the maze of level %d has %d rows", len(game.Maze), game.Level, len(levelMaze))
+    }
+    for row, line := range game.Maze {
+        if len(line) != len(levelMaze[row]) {
+            return fmt.Errorf("row %d of the maze has %d columns, the maze of level %d has %d columns", row+1, len(line), game.Level, len(levelMaze[row]))
+        }
+    }
+    return nil
+}
+
+/*
+    Function: getSavedGameInfo
+    Get the game info of a saved game
+    Input: saved game
+*/
+func getSavedGameInfo(game SavedGame) GameInfo {
+    info := GameInfo{
+        level: game.Level,
+        score: game.Score,
+        maxScore: game.MaxScore,
+        isStarted: game.IsStarted,
+        isGameOver: game.IsGameOver,
+        isLevelComplete: game.IsLevelComplete,
+        maze: append([]string{}, game.Maze...),
+        fruits: game.Fruits,
+        frightenedTimer: game.FrightenedTimer,
+        fruitTimer: game.FruitTimer,
+        freezeTimer: game.FreezeTimer,
+    }
+    for _, stats := range game.Stats {
+        info.stats = append(info.stats, PlayerStats{lives: stats.Lives, points: stats.Points, nextExtraLife: stats.NextExtraLife})
+    }
+    return info
+}
+
+/*
+    Function: getSavedSpriteState
+    Get the game object of a saved game object (without images)
+    Input: saved game object
+*/
+func getSavedSpriteState(saved SavedSprite) Sprite {
+    return Sprite{
+        x: saved.X,
+        y: saved.Y,
+        direction: parseSavedDirection(saved.Direction),
+        speed: saved.Speed,
+        startX: saved.StartX,
+        startY: saved.StartY,
+        visibility: saved.Visible,
+        isFrightened: saved.IsFrightened,
+        aggression: saved.Aggression,
+        player: saved.Player,
+    }
+}
+
+/*
+    Function: parseSavedDirection
+    Get the direction of a saved game ("" is no direction)
+    Input: direction
+*/
+func parseSavedDirection(direction string) byte {
+    if direction == "" {
+        return 0
+    }
+    return direction[0]
+}
+
+/*
+    Function: continueGame
+    Load the saved game into the game. If it can't be loaded, the reason is shown and the start screen stays
+*/
+func continueGame() {
+    world, err := readSaveGame(saveGameFile)
+    if err == nil {
+        err = loadWorld(world)
+    }
+    if err != nil {
+        log.Println("the saved game can't be continued:", err)
+        showSaveMessage("SAVED GAME CAN'T BE LOADED")
+        newGame()
+        return
+    }
+
+    // PacMan and enemies wait a moment before moving, so the player is ready to play
+    if gameInfo.isStarted && !gameInfo.isLevelComplete && !gameInfo.isGameOver && gameInfo.freezeTimer < freezeTime {
+        gameInfo.freezeTimer = freezeTime
+    }
+    showSaveMessage("GAME LOADED")
+}
+
+/*
+    ################################
+    ## Saving and continuing keys ##
+    ################################
+*/
+
+/*
+    Function: checkSaveGame
+    Check if there's a saved game to continue
+*/
+func checkSaveGame() {
+    _, err := os.Stat(saveGameFile)
+    hasSaveGame = err == nil
+}
+
+/*
+    Function: canContinueGame
+    Check if a saved game can be continued. Only a game played on this computer is saved and continued
+    (not a networked game, a rollback game, a headless game or the demo)
+*/
+func canContinueGame() bool {
+    return !isDemo && !isHeadless && mazeEditor == nil && netHost == nil && netClient == nil && rollbackSession == nil && lobby == nil
+}
+
+/*
+    Function: canSaveGame
+    Check if the game can be saved now (see canContinueGame)
+*/
+func canSaveGame() bool {
+    return gameInfo.isStarted && canContinueGame()
+}
+
+/*
+    Function: updateSaveGame
+    Save the game when F5 is pressed, and count down the message about saving
+    It's called on each frame by the update function
+*/
+func updateSaveGame() {
+    if isKeyJustPressed(KeyF5) && canSaveGame() {
+        saveGame()
+    }
+    if saveMessageTimer > 0 {
+        saveMessageTimer--
+    }
+}
+
+/*
+    Function: showSaveMessage
+    Show a message about saving for a while
+    Input: message
+*/
+func showSaveMessage(message string) {
+    saveMessage = message
+    saveMessageTimer = saveMessageTime
+}
+
+/*
+    Function: drawSaveMessage
+    Show the message about saving at the top of the playfield
+    Input: screen
+*/
+func drawSaveMessage(screen *Image) {
+    if saveMessageTimer > 0 {
+        drawTextCentered(screen, saveMessage, screenSizeX/2, playfieldY+blockSize*2, getTextColor())
+    }
+}
@@ -0,0 +1,90 @@
+package main
+
+/*
+    This file contains the tests of the saved games (see savegame.go).
+
+    A saved game can be edited by hand or broken, so a saved game which points to players, PacMen or maze points which
+    aren't there is not loaded: the reason is shown instead of the game crashing.
+*/
+import (
+    "encoding/json"
+    "io/ioutil"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+/*
+    Function: getTestSaveGame
+    Start a game of one player on the first level and get its saved game
+*/
+func getTestSaveGame() SaveGame {
+    setScreenSize()
+    newGame()
+    startPlayers(1)
+    isNewGame = false
+    return getSaveGame(saveWorld())
+}
+
+func TestSavedGameCanBeLoaded(t *testing.T) {
+    save := getTestSaveGame()
+    world, err := getSavedWorld(save)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := loadWorld(world); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestBrokenSavedGames(t *testing.T) {
+    broken := map[string]func(save *SaveGame){
+        "PacMan of a player who isn't there": func(save *SaveGame) { save.Game.Pacmen[0].Player = 3 },
+        "turn of a player who isn't there": func(save *SaveGame) { save.CurrentPlayer = 2 },
+        "more players than saved games": func(save *SaveGame) { save.NumPlayers = 2 },
+        "no players": func(save *SaveGame) { save.NumPlayers = 0 },
+        "a row missing from the maze": func(save *SaveGame) { save.Game.Maze = save.Game.Maze[1:] },
+        "a short row in the maze": func(save *SaveGame) { save.Game.Maze[3] = save.Game.Maze[3][1:] },
+        "a short row in the maze of a player": func(save *SaveGame) { save.Players[0].Game.Maze[2] = "0" },
+        "an enemy outside the maze": func(save *SaveGame) { save.Game.Enemies[0].Y = -100 },
+        "a versus match without a round": func(save *SaveGame) { save.Versus.IsPlaying = true },
+    }
+    for name, breakSave := range broken {
+        // the saved game is copied through JSON, so each test breaks its own copy
+        content, err := json.Marshal(getTestSaveGame())
+        if err != nil {
+            t.Fatal(err)
+        }
+        save := SaveGame{}
+        if err := json.Unmarshal(content, &save); err != nil {
+            t.Fatal(err)
+        }
+        breakSave(&save)
+        if _, err := getSavedWorld(save); err == nil {
+            t.Errorf("a saved game with %s should not be loaded", name)
+        }
+    }
+}
+
+func TestContinueBrokenSavedGame(t *testing.T) {
+    // continuing a broken saved game shows the message and stays on the start screen
+    save := getTestSaveGame()
+    save.Game.Pacmen[0].Player = 5
+    content, err := json.Marshal(save)
+    if err != nil {
+        t.Fatal(err)
+    }
+    fileName := saveGameFile
+    saveGameFile = filepath.Join(t.TempDir(), "savegame.json")
+    defer func() {
+        saveGameFile = fileName
+    }()
+    if err := ioutil.WriteFile(saveGameFile, content, 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    continueGame()
+    if !strings.Contains(saveMessage, "CAN'T BE LOADED") || !isNewGame {
+        t.Errorf("the message is %q, expected the saved game can't be loaded", saveMessage)
+    }
+}
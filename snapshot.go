@@ -0,0 +1,757 @@
+package main
+
+/*
+    This file contains the textual snapshots of the world (see world.go).
+
+    A snapshot is the whole state of the game written as readable text: a header with the random numbers, the players
+    and the versus match, then a section for the game being played and a section for each player waiting for their turn.
+    A section has the level, score, flags, timers, the lives and points of each PacMan, each game object and the maze
+    with the eaten food. Game objects are drawn on the maze of the game being played (@ PacMan, A B C ... enemies,
+    % fruit), and the maze character under each game object is kept with the game object. Ex:
+
+        ThePacMan world 2
+        random: 1234
+        newGame: false
+        players: 1 current=0
+        versus: off round=0 rounds=4 pacman=0 enemyInput=I scores=0/0,0/0
+
+        [game]
+        level: 1
+        score: 12/275
+        flags: started
+        timers: frightened=0 fruit=0 freeze=0
+        fruits: 0
+        stats: lives=3 points=120 nextExtraLife=3000
+        pacman: tile=17,16 offset=3,0 dir=R speed=1 start=17,16 player=0 visible=true under=space
+        enemy: tile=14,10 offset=0,-2 dir=U speed=1 aggression=0.3 frightened=false visible=true under=.
+        fruit: tile=17,16 offset=0,0 visible=false under=P
+        maze: 28x31
+        0000000000000000000000000000
+        ...
+
+    Pressing F9 while playing writes a snapshot into a file (to attach to a bug report), -snapshot starts the game from
+    a snapshot, and -snapshotcheck runs a snapshot for a number of frames and compares the result with another snapshot.
+    Snapshots of version 1 have no aggression on the enemy lines, the enemies take the one of the level.
+*/
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "log"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Let's define the first line of a snapshot, with the version of the format (and the oldest version which is read)
+var snapshotVersion = 2
+var snapshotOldestVersion = 1
+var snapshotTitle = "ThePacMan world"
+
+// Let's define the characters of the game objects drawn on the maze
+var snapshotPacman = byte('@')
+var snapshotFirstEnemy = byte('A')
+var snapshotFruit = byte('%')
+
+/*
+    ########################
+    ## Writing a snapshot ##
+    ########################
+*/
+
+/*
+    Function: formatWorld
+    Write a saved world as a snapshot
+    Input: saved world
+*/
+func formatWorld(world World) string {
+    lines := []string{
+        snapshotTitle+" "+strconv.Itoa(snapshotVersion),
+        "random: "+strconv.FormatUint(world.random, 10),
+        "newGame: "+strconv.FormatBool(world.isNewGame),
+        fmt.Sprintf("players: %d current=%d", world.numPlayers, world.currentPlayer),
+    }
+    // the versus line is written even when the versus mode is not played, the next match starts from its values
+    playing := "off"
+    if world.isVersus {
+        playing = "on"
+    }
+    scores := world.versusScores
+    lines = append(lines, fmt.Sprintf("versus: %s round=%d rounds=%d pacman=%d enemyInput=%s scores=%d/%d,%d/%d", playing,
+        world.versusRound, world.versusRounds, world.versusPacmanPlayer, formatDirection(world.versusEnemyInput),
+        scores[0].dots, scores[0].catches, scores[1].dots, scores[1].catches))
+
+    // the game being played, with its game objects drawn on the maze
+    maze := append([]string{}, world.gameInfo.maze...)
+    objects := []string{}
+    for _, pacman := range world.pacmen {
+        objects = append(objects, "pacman: "+formatSnapshotSprite(pacman)+
+            fmt.Sprintf(" start=%s player=%d visible=%t under=%s", formatTile(pacman.startX, pacman.startY), pacman.player, pacman.visibility, getSnapshotUnder(pacman, maze)))
+    }
+    for _, enemy := range world.enemies {
+        objects = append(objects, "enemy: "+formatSnapshotSprite(enemy)+
+            fmt.Sprintf(" aggression=%s frightened=%t visible=%t under=%s", formatNumber(enemy.aggression), enemy.isFrightened, enemy.visibility, getSnapshotUnder(enemy, maze)))
+    }
+    objects = append(objects, fmt.Sprintf("fruit: tile=%s offset=%s visible=%t under=%s",
+        formatTile(world.fruit.x, world.fruit.y), formatOffset(world.fruit.x, world.fruit.y), world.fruit.visibility, getSnapshotUnder(world.fruit, maze)))
+
+    // let's draw the fruit first and PacMen last, so PacMen are on top when game objects are on the same maze point
+    drawSnapshotSprite(maze, world.fruit, snapshotFruit)
+    for i, enemy := range world.enemies {
+        drawSnapshotSprite(maze, enemy, snapshotFirstEnemy+byte(i))
+    }
+    for _, pacman := range world.pacmen {
+        drawSnapshotSprite(maze, pacman, snapshotPacman)
+    }
+
+    lines = append(lines, "", "[game]")
+    lines = append(lines, formatSnapshotGameInfo(world.gameInfo, objects, maze)...)
+
+    // the players waiting for their turn
+    for _, player := range world.players {
+        lines = append(lines, "", "[player "+strconv.Itoa(player.number)+"]")
+        lines = append(lines, formatSnapshotGameInfo(player.gameInfo, nil, player.gameInfo.maze)...)
+    }
+    return strings.Join(lines, "\n")+"\n"
+}
+
+/*
+    Function: formatSnapshotGameInfo
+    Write the lines of a section: the game info, the game objects and the maze
+    Inputs: game info, lines of the game objects and the rows of the maze to write
+*/
+func formatSnapshotGameInfo(info GameInfo, objects []string, maze []string) []string {
+    flags := []string{}
+    if info.isStarted {
+        flags = append(flags, "started")
+    }
+    if info.isGameOver {
+        flags = append(flags, "gameOver")
+    }
+    if info.isLevelComplete {
+        flags = append(flags, "levelComplete")
+    }
+    if len(flags) == 0 {
+        flags = append(flags, "none")
+    }
+
+    lines := []string{
+        "level: "+strconv.Itoa(info.level),
+        fmt.Sprintf("score: %d/%d", info.score, info.maxScore),
+        "flags: "+strings.Join(flags, " "),
+        fmt.Sprintf("timers: frightened=%d fruit=%d freeze=%d", info.frightenedTimer, info.fruitTimer, info.freezeTimer),
+        "fruits: "+strconv.Itoa(info.fruits),
+    }
+    for _, stats := range info.stats {
+        lines = append(lines, fmt.Sprintf("stats: lives=%d points=%d nextExtraLife=%d", stats.lives, stats.points, stats.nextExtraLife))
+    }
+    lines = append(lines, objects...)
+
+    width := 0
+    if len(maze) > 0 {
+        width = len(maze[0])
+    }
+    lines = append(lines, fmt.Sprintf("maze: %dx%d", width, len(maze)))
+    return append(lines, maze...)
+}
+
+/*
+    Function: formatSnapshotSprite
+    Write the maze point, the offset from the maze point, the direction and the speed of a moving game object
+    Input: game object
+*/
+func formatSnapshotSprite(sprite Sprite) string {
+    return fmt.Sprintf("tile=%s offset=%s dir=%s speed=%s", formatTile(sprite.x, sprite.y), formatOffset(sprite.x, sprite.y),
+        formatDirection(sprite.direction), formatNumber(sprite.speed))
+}
+
+/*
+    Function: formatTile
+    Write the maze point closest to a position as column,row
+    Input: position
+*/
+func formatTile(x float64, y float64) string {
+    col, row := getMazePointFromPosition(x, y)
+    return strconv.Itoa(col)+","+strconv.Itoa(row)
+}
+
+/*
+    Function: formatOffset
+    Write how far (pixels) a position is from the maze point closest to it, as x,y
+    Input: position
+*/
+func formatOffset(x float64, y float64) string {
+    alignedX, alignedY := getPositionFromMazePoint(getMazePointFromPosition(x, y))
+    return formatNumber(x-alignedX)+","+formatNumber(y-alignedY)
+}
+
+/*
+    Function: formatNumber
+    Write a number with as few digits as possible, so it's read back as the same number
+    Input: number
+*/
+func formatNumber(number float64) string {
+    return strconv.FormatFloat(number, 'g', -1, 64)
+}
+
+/*
+    Function: formatDirection
+    Write a direction. A game object which didn't move yet has no direction, which is written as -
+    Input: direction
+*/
+func formatDirection(direction byte) string {
+    if direction == 0 {
+        return "-"
+    }
+    return string(direction)
+}
+
+/*
+    Function: getSnapshotUnder
+    Get the maze character under a game object. A space is written as space, so it can be read back
+    Inputs: game object and the maze (without game objects drawn on it)
+*/
+func getSnapshotUnder(sprite Sprite, maze []string) string {
+    col, row := getMazePointFromPosition(sprite.x, sprite.y)
+    if row < 0 || row >= len(maze) || col < 0 || col >= len(maze[row]) {
+        return "none"
+    }
+    if maze[row][col] == ' ' {
+        return "space"
+    }
+    return string(maze[row][col])
+}
+
+/*
+    Function: drawSnapshotSprite
+    Draw a visible game object on the maze point it's closest to
+    Inputs: rows of the maze, game object and its character
+*/
+func drawSnapshotSprite(maze []string, sprite Sprite, char byte) {
+    col, row := getMazePointFromPosition(sprite.x, sprite.y)
+    if !sprite.visibility || row < 0 || row >= len(maze) || col < 0 || col >= len(maze[row]) {
+        return
+    }
+    maze[row] = maze[row][:col]+string(char)+maze[row][col+1:]
+}
+
+/*
+    ########################
+    ## Reading a snapshot ##
+    ########################
+*/
+
+/*
+    Function: parseWorld
+    Read a snapshot back into a saved world. The game objects have no images, loadWorld gives them the images of the game
+    Input: text of the snapshot
+*/
+func parseWorld(text string) (World, error) {
+    world := World{}
+    scanner := bufio.NewScanner(strings.NewReader(text))
+    lineNumber := 0
+    nextLine := func() (string, bool) {
+        for scanner.Scan() {
+            lineNumber++
+            // let's skip empty lines outside the maze (a maze row is read with readMazeRows)
+            if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+                return line, true
+            }
+        }
+        return "", false
+    }
+    fail := func(err error) (World, error) {
+        return World{}, fmt.Errorf("snapshot line %d: %v", lineNumber, err)
+    }
+
+    line, _ := nextLine()
+    if !strings.HasPrefix(line, snapshotTitle+" ") {
+        return fail(errors.New("this is not a snapshot of the world"))
+    }
+    version, err := strconv.Atoi(strings.TrimPrefix(line, snapshotTitle+" "))
+    if err != nil || version < snapshotOldestVersion || version > snapshotVersion {
+        return fail(fmt.Errorf("snapshot version %q is not supported, versions %d to %d are read", strings.TrimPrefix(line, snapshotTitle+" "), snapshotOldestVersion, snapshotVersion))
+    }
+
+    // info is the game info of the section being read (the game being played or a waiting player).
+    // The maze characters under the game objects are kept until the maze is read
+    var info *GameInfo
+    unders := []SnapshotUnder{}
+    for {
+        line, ok := nextLine()
+        if !ok {
+            break
+        }
+        key, value := splitSnapshotLine(line)
+        fields := parseSnapshotFields(value)
+
+        var err error
+        switch {
+        case key == "random":
+            world.random, err = strconv.ParseUint(value, 10, 64)
+        case key == "newGame":
+            world.isNewGame, err = strconv.ParseBool(value)
+        case key == "players":
+            _, err = fmt.Sscanf(value, "%d current=%d", &world.numPlayers, &world.currentPlayer)
+        case key == "versus":
+            err = parseSnapshotVersus(&world, value, fields)
+        case key == "[game]":
+            info = &world.gameInfo
+        case strings.HasPrefix(key, "[player ") && strings.HasSuffix(key, "]"):
+            number, convErr := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(key, "[player "), "]"))
+            world.players = append(world.players, PlayerState{number: number})
+            info, err = &world.players[len(world.players)-1].gameInfo, convErr
+        case info == nil:
+            err = fmt.Errorf("%s is not in a section", key)
+        case key == "maze":
+            err = readSnapshotMaze(info, value, nextLine)
+            if err == nil && info == &world.gameInfo {
+                err = putSnapshotUnders(info.maze, unders)
+            }
+        default:
+            err = parseSnapshotGameLine(&world, info, key, value, fields, &unders, version)
+        }
+        if err != nil {
+            return fail(fmt.Errorf("%s: %v", key, err))
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return World{}, err
+    }
+    if world.gameInfo.maze == nil {
+        return World{}, errors.New("the snapshot has no [game] section with a maze")
+    }
+    return world, nil
+}
+
+/*
+    Function: parseSnapshotGameLine
+    Read a line of a section (game info, stats or a game object)
+    Inputs: world being read, game info of the section, key, value, the fields of the value, the maze characters under the game objects
+    and the version of the snapshot
+*/
+func parseSnapshotGameLine(world *World, info *GameInfo, key string, value string, fields SnapshotFields, unders *[]SnapshotUnder, version int) error {
+    var err error
+    switch key {
+    case "level":
+        info.level, err = strconv.Atoi(value)
+    case "score":
+        _, err = fmt.Sscanf(value, "%d/%d", &info.score, &info.maxScore)
+    case "flags":
+        for _, flag := range strings.Fields(value) {
+            switch flag {
+            case "started":
+                info.isStarted = true
+            case "gameOver":
+                info.isGameOver = true
+            case "levelComplete":
+                info.isLevelComplete = true
+            case "none":
+            default:
+                return fmt.Errorf("unknown flag %q", flag)
+            }
+        }
+    case "timers":
+        info.frightenedTimer = fields.getInt("frightened", &err)
+        info.fruitTimer = fields.getInt("fruit", &err)
+        info.freezeTimer = fields.getInt("freeze", &err)
+    case "fruits":
+        info.fruits, err = strconv.Atoi(value)
+    case "stats":
+        info.stats = append(info.stats, PlayerStats{
+            lives: fields.getInt("lives", &err),
+            points: fields.getInt("points", &err),
+            nextExtraLife: fields.getInt("nextExtraLife", &err),
+        })
+    case "pacman", "enemy", "fruit":
+        // only the game being played has game objects
+        if info != &world.gameInfo {
+            return fmt.Errorf("a waiting player has no %s", key)
+        }
+        sprite := Sprite{}
+        sprite.x, sprite.y = fields.getPosition(&err)
+        sprite.visibility = fields.getBool("visible", &err)
+        *unders = append(*unders, SnapshotUnder{x: sprite.x, y: sprite.y, char: fields.get("under", &err)})
+        switch key {
+        case "pacman":
+            parseSnapshotMovement(&sprite, fields, &err)
+            sprite.startX, sprite.startY = fields.getTile("start", &err)
+            sprite.player = fields.getInt("player", &err)
+            world.pacmen = append(world.pacmen, sprite)
+        case "enemy":
+            parseSnapshotMovement(&sprite, fields, &err)
+            // version 1 has no aggression, -1 lets loadWorld give the enemy the one of the level
+            sprite.aggression = -1
+            if version > 1 {
+                sprite.aggression = fields.getNumber("aggression", &err)
+            }
+            sprite.isFrightened = fields.getBool("frightened", &err)
+            world.enemies = append(world.enemies, sprite)
+        case "fruit":
+            world.fruit = sprite
+        }
+    default:
+        err = fmt.Errorf("unknown line %q", key)
+    }
+    return err
+}
+
+/*
+    Function: parseSnapshotMovement
+    Read the direction and the speed of a moving game object
+    Inputs: game object, fields of the line and the variable to keep the first error
+*/
+func parseSnapshotMovement(sprite *Sprite, fields SnapshotFields, err *error) {
+    sprite.direction = parseDirection(fields.get("dir", err), err)
+    sprite.speed = fields.getNumber("speed", err)
+}
+
+/*
+    Function: parseSnapshotVersus
+    Read the versus line of the header. It starts with on or off (whether the versus mode is played)
+    Inputs: world being read, value and the fields of the value
+*/
+func parseSnapshotVersus(world *World, value string, fields SnapshotFields) error {
+    playing := strings.Fields(value+" ")[0]
+    if playing != "on" && playing != "off" {
+        return fmt.Errorf("versus should start with on or off, not %q", playing)
+    }
+    var err error
+    world.isVersus = playing == "on"
+    world.versusRound = fields.getInt("round", &err)
+    world.versusRounds = fields.getInt("rounds", &err)
+    world.versusPacmanPlayer = fields.getInt("pacman", &err)
+    world.versusEnemyInput = parseDirection(fields.get("enemyInput", &err), &err)
+    if err != nil {
+        return err
+    }
+    scores := world.versusScores
+    if _, err := fmt.Sscanf(fields["scores"], "%d/%d,%d/%d", &scores[0].dots, &scores[0].catches, &scores[1].dots, &scores[1].catches); err != nil {
+        return fmt.Errorf("scores: %v", err)
+    }
+    world.versusScores = scores
+    return nil
+}
+
+/*
+    Function: readSnapshotMaze
+    Read the rows of a maze. The size (columns x rows) is on the maze line, and the rows follow it
+    Inputs: game info of the section, size of the maze and the function to read the next line
+*/
+func readSnapshotMaze(info *GameInfo, size string, nextLine func() (string, bool)) error {
+    width, height := 0, 0
+    if _, err := fmt.Sscanf(size, "%dx%d", &width, &height); err != nil {
+        return fmt.Errorf("maze size %q: %v", size, err)
+    }
+    info.maze = []string{}
+    for row := 0; row < height; row++ {
+        line, ok := nextLine()
+        if !ok {
+            return fmt.Errorf("the maze has %d rows, %d are given", height, row)
+        }
+        if len(line) != width {
+            return fmt.Errorf("row %d of the maze has %d columns, %d are expected", row, len(line), width)
+        }
+        info.maze = append(info.maze, line)
+    }
+    return nil
+}
+
+/*
+    Function: putSnapshotUnders
+    Take the game objects off the maze of the game, by putting back the maze character under each game object
+    Inputs: rows of the maze and the maze characters under the game objects
+*/
+func putSnapshotUnders(maze []string, unders []SnapshotUnder) error {
+    for _, under := range unders {
+        col, row := getMazePointFromPosition(under.x, under.y)
+        if under.char == "none" || row < 0 || row >= len(maze) || col < 0 || col >= len(maze[row]) {
+            continue
+        }
+        char := under.char
+        if char == "space" {
+            char = " "
+        }
+        if len(char) != 1 {
+            return fmt.Errorf("the maze character under a game object can't be %q", char)
+        }
+        maze[row] = maze[row][:col]+char+maze[row][col+1:]
+    }
+    return nil
+}
+
+/*
+    Function: parseDirection
+    Read a direction (- is no direction)
+    Inputs: direction and the variable to keep the first error
+*/
+func parseDirection(value string, err *error) byte {
+    switch value {
+    case "-":
+        return 0
+    case "U", "R", "D", "L", "I":
+        return value[0]
+    }
+    if *err == nil {
+        *err = fmt.Errorf("unknown direction %q", value)
+    }
+    return 0
+}
+
+/*
+    Function: splitSnapshotLine
+    Split a line into the key (before the colon) and the value. A line without a colon is only a key (Ex: [game])
+    Input: line
+*/
+func splitSnapshotLine(line string) (string, string) {
+    parts := strings.SplitN(line, ":", 2)
+    if len(parts) == 1 {
+        return strings.TrimSpace(line), ""
+    }
+    return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
+
+/*
+    #######################
+    ## Fields of a line ##
+    #######################
+*/
+
+// Structure which keeps the key=value fields of a line
+type SnapshotFields map[string]string
+
+// Structure which keeps the maze character under a game object, until the maze is read
+type SnapshotUnder struct {
+    x float64 // holds the x position of the game object
+    y float64 // holds the y position of the game object
+    char string // holds the maze character under the game object (space or none are written as words)
+}
+
+/*
+    Function: parseSnapshotFields
+    Read the key=value fields of a value. Words without = are skipped
+    Input: value
+*/
+func parseSnapshotFields(value string) SnapshotFields {
+    fields := SnapshotFields{}
+    for _, word := range strings.Fields(value) {
+        if parts := strings.SplitN(word, "=", 2); len(parts) == 2 {
+            fields[parts[0]] = parts[1]
+        }
+    }
+    return fields
+}
+
+/*
+    Function: get
+    Get a field. The first missing field is kept in the error
+    Inputs: key and the variable to keep the first error
+*/
+func (fields SnapshotFields) get(key string, err *error) string {
+    value, ok := fields[key]
+    if !ok && *err == nil {
+        *err = fmt.Errorf("%s is missing", key)
+    }
+    return value
+}
+
+/*
+    Function: getNumber
+    Get a field which is a number
+    Inputs: key and the variable to keep the first error
+*/
+func (fields SnapshotFields) getNumber(key string, err *error) float64 {
+    number, parseErr := strconv.ParseFloat(fields.get(key, err), 64)
+    if parseErr != nil && *err == nil {
+        *err = fmt.Errorf("%s: %v", key, parseErr)
+    }
+    return number
+}
+
+/*
+    Function: getInt
+    Get a field which is a whole number
+    Inputs: key and the variable to keep the first error
+*/
+func (fields SnapshotFields) getInt(key string, err *error) int {
+    number, parseErr := strconv.Atoi(fields.get(key, err))
+    if parseErr != nil && *err == nil {
+        *err = fmt.Errorf("%s: %v", key, parseErr)
+    }
+    return number
+}
+
+/*
+    Function: getBool
+    Get a field which is true or false
+    Inputs: key and the variable to keep the first error
+*/
+func (fields SnapshotFields) getBool(key string, err *error) bool {
+    value, parseErr := strconv.ParseBool(fields.get(key, err))
+    if parseErr != nil && *err == nil {
+        *err = fmt.Errorf("%s: %v", key, parseErr)
+    }
+    return value
+}
+
+/*
+    Function: getPair
+    Get a field which is two numbers (Ex: 17,16)
+    Inputs: key and the variable to keep the first error
+*/
+func (fields SnapshotFields) getPair(key string, err *error) (float64, float64) {
+    parts := strings.Split(fields.get(key, err), ",")
+    if len(parts) != 2 {
+        if *err == nil {
+            *err = fmt.Errorf("%s should be two numbers", key)
+        }
+        return 0, 0
+    }
+    first, firstErr := strconv.ParseFloat(parts[0], 64)
+    second, secondErr := strconv.ParseFloat(parts[1], 64)
+    if (firstErr != nil || secondErr != nil) && *err == nil {
+        *err = fmt.Errorf("%s should be two numbers", key)
+    }
+    return first, second
+}
+
+/*
+    Function: getTile
+    Get a field which is a maze point, as the position of the maze point
+    Inputs: key and the variable to keep the first error
+*/
+func (fields SnapshotFields) getTile(key string, err *error) (float64, float64) {
+    col, row := fields.getPair(key, err)
+    return getPositionFromMazePoint(int(col), int(row))
+}
+
+/*
+    Function: getPosition
+    Get the position of a game object from its maze point (tile) and its offset from the maze point
+    Input: variable to keep the first error
+*/
+func (fields SnapshotFields) getPosition(err *error) (float64, float64) {
+    x, y := fields.getTile("tile", err)
+    offsetX, offsetY := fields.getPair("offset", err)
+    return x+offsetX, y+offsetY
+}
+
+/*
+    ###########################
+    ## Snapshots in the game ##
+    ###########################
+*/
+
+/*
+    Function: loadWorld
+    Put a world read from a snapshot into the game. The game objects are created for the level and the number of PacMen
+    of the snapshot, and the world takes their images
+    Input: world read from a snapshot
+*/
+func loadWorld(world World) error {
+    gameInfo.stats = append([]PlayerStats{}, world.gameInfo.stats...)
+    setMaze(world.gameInfo.level, world.gameInfo.maze)
+    if len(world.pacmen) != len(pacmen) || len(world.enemies) != len(enemies) {
+        return fmt.Errorf("level %d has %d PacMen and %d enemies, the snapshot has %d PacMen and %d enemies",
+            world.gameInfo.level, len(pacmen), len(enemies), len(world.pacmen), len(world.enemies))
+    }
+
+    // the images never change, so let's take them from the game objects of the level
+    for i := range world.pacmen {
+        world.pacmen[i].faces = pacmen[i].faces
+        world.pacmen[i].tint = pacmen[i].tint
+        world.pacmen[i].img = pacmen[i].img
+        if face, ok := pacmen[i].faces[world.pacmen[i].direction]; ok {
+            world.pacmen[i].img = face
+        }
+    }
+    for i := range world.enemies {
+        // older snapshots and saved games have no aggression, the enemy takes the one of the level
+        if world.enemies[i].aggression < 0 {
+            world.enemies[i].aggression = enemies[i].aggression
+        }
+        world.enemies[i].faces = enemies[i].faces
+        world.enemies[i].img = enemies[i].faces['N']
+        if world.enemies[i].isFrightened {
+            world.enemies[i].img = enemies[i].faces['F']
+        }
+    }
+    world.fruit.img = fruit.img
+
+    restoreWorld(world)
+    return nil
+}
+
+/*
+    Function: readSnapshotFile
+    Read a snapshot file and put its world into the game
+    Input: path to the snapshot file
+*/
+func readSnapshotFile(fileName string) error {
+    content, err := ioutil.ReadFile(fileName)
+    if err != nil {
+        return err
+    }
+    world, err := parseWorld(string(content))
+    if err != nil {
+        return fmt.Errorf("%s: %v", fileName, err)
+    }
+    return loadWorld(world)
+}
+
+/*
+    Function: writeSnapshotFile
+    Write a snapshot of the game into a new file named with the current time (Ex: snapshot-20060102-150405.txt)
+*/
+func writeSnapshotFile() {
+    fileName := "snapshot-"+time.Now().Format("20060102-150405")+".txt"
+    if err := ioutil.WriteFile(fileName, []byte(formatWorld(saveWorld())), 0644); err != nil {
+        log.Println("the snapshot can't be written:", err)
+        return
+    }
+    log.Println("snapshot written to", fileName)
+}
+
+/*
+    Function: runSnapshotCheck
+    Start the game from a snapshot, run it for a number of frames without pressing any key and compare the world with
+    the expected snapshot. Without an expected snapshot, the world is printed (Ex: to write the expected snapshot)
+    Inputs: path to the snapshot file, number of frames and the path to the expected snapshot file
+*/
+func runSnapshotCheck(fileName string, frames int, expectedFile string) error {
+    if err := readSnapshotFile(fileName); err != nil {
+        return err
+    }
+    for frame := 0; frame < frames; frame++ {
+        updateGame()
+    }
+
+    snapshot := formatWorld(saveWorld())
+    if expectedFile == "" {
+        fmt.Print(snapshot)
+        return nil
+    }
+    expected, err := ioutil.ReadFile(expectedFile)
+    if err != nil {
+        return err
+    }
+
+    // let's show the first line which is different, lines are compared without the line endings
+    lines := strings.Split(strings.TrimRight(snapshot, "\n"), "\n")
+    expectedLines := strings.Split(strings.TrimRight(strings.Replace(string(expected), "\r\n", "\n", -1), "\n"), "\n")
+    for i := 0; i < len(lines) || i < len(expectedLines); i++ {
+        line, expectedLine := "", ""
+        if i < len(lines) {
+            line = lines[i]
+        }
+        if i < len(expectedLines) {
+            expectedLine = expectedLines[i]
+        }
+        if line != expectedLine {
+            return fmt.Errorf("after %d frames, line %d is %q, %s has %q", frames, i+1, line, expectedFile, expectedLine)
+        }
+    }
+    fmt.Println("the world is the same as", expectedFile, "after", frames, "frames")
+    return nil
+}
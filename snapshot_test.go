@@ -0,0 +1,81 @@
+package main
+
+/*
+    This file contains the tests of the snapshots (see snapshot.go).
+
+    testdata/snapshot-before.txt is a game on the first level, and testdata/snapshot-after.txt is the same game
+    snapshotTestFrames frames later. If the game logic is changed on purpose, the expected snapshot is written again with:
+    ./SimplePacmanGame -snapshotcheck testdata/snapshot-before.txt -frames 40 > testdata/snapshot-after.txt
+*/
+import (
+    "io/ioutil"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// Let's define the snapshot files of the tests and the frames run between them
+var snapshotTestBefore = "testdata/snapshot-before.txt"
+var snapshotTestAfter = "testdata/snapshot-after.txt"
+var snapshotTestFrames = 40
+
+/*
+    Function: readTestSnapshot
+    Read a snapshot file of the tests and parse its world
+    Inputs: test and path to the snapshot file
+*/
+func readTestSnapshot(t *testing.T, fileName string) (string, World) {
+    content, err := ioutil.ReadFile(fileName)
+    if err != nil {
+        t.Fatal(err)
+    }
+    world, err := parseWorld(string(content))
+    if err != nil {
+        t.Fatalf("%s: %v", fileName, err)
+    }
+    return string(content), world
+}
+
+func TestSnapshotCheck(t *testing.T) {
+    // the game is set up as main does before -snapshotcheck
+    setScreenSize()
+    newGame()
+    if err := runSnapshotCheck(snapshotTestBefore, snapshotTestFrames, snapshotTestAfter); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+    for _, fileName := range []string{snapshotTestBefore, snapshotTestAfter} {
+        text, world := readTestSnapshot(t, fileName)
+
+        // writing the world gives the same text, and reading it again gives the same world
+        formatted := formatWorld(world)
+        if formatted != text {
+            t.Errorf("%s is written differently:\n%s", fileName, formatted)
+        }
+        parsed, err := parseWorld(formatted)
+        if err != nil {
+            t.Fatalf("%s: %v", fileName, err)
+        }
+        if !reflect.DeepEqual(parsed, world) {
+            t.Errorf("%s: the world is different after writing and reading it again", fileName)
+        }
+    }
+}
+
+func TestSnapshotOldVersion(t *testing.T) {
+    // a snapshot of version 1 has no aggression, its enemies get -1 so they take the one of the level (see loadWorld)
+    text, _ := readTestSnapshot(t, snapshotTestBefore)
+    old := strings.Replace(text, "ThePacMan world 2", "ThePacMan world 1", 1)
+    old = strings.Replace(old, " aggression=0.3", "", -1)
+    world, err := parseWorld(old)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for i, enemy := range world.enemies {
+        if enemy.aggression != -1 {
+            t.Errorf("enemy %d of a version 1 snapshot has aggression %v, expected -1", i, enemy.aggression)
+        }
+    }
+}
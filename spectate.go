@@ -0,0 +1,410 @@
+package main
+
+/*
+    This file contains the spectator stream of a running game.
+
+    A game can publish its state on a TCP port (-stream flag), and spectators watch it with -watch. A spectator gets the
+    same snapshots as a player who joined a host (see net.go): a full snapshot first, so a spectator can join in the
+    middle of a game, and then delta snapshots. The spectator draws the game from the snapshots like a player who joined.
+    Spectators can't play: they never send a direction, and anything they send to the stream is read and thrown away.
+
+    The stream can also be read with a WebSocket on the same port (Ex: from a page in a browser). When the first bytes
+    of a connection are an HTTP request, the connection is upgraded to a WebSocket and each message is sent as a binary
+    WebSocket frame. A WebSocket spectator doesn't send the protocol version, it reads it from the hello message.
+
+    A game can also run without a window (-headless flag), Ex: on a server which streams a game to a TV. Nobody plays a
+    headless game, so the autopilot plays PacMan and the game goes on from the screens by itself.
+*/
+import (
+    "bufio"
+    "crypto/sha1"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "log"
+    "net"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure which keeps a spectator who is watching the stream
+type Spectator struct {
+    conn net.Conn // holds the connection to the spectator
+    writer io.Writer // holds the writer of the messages, the connection or a WebSocket writer
+    snapshots chan []byte // holds the latest snapshot waiting to be sent. If the spectator is slow, older snapshots are dropped
+    done chan struct{} // closed when the spectator is disconnected
+}
+
+// Structure which keeps the stream of a game
+type SpectatorServer struct {
+    listener net.Listener // holds the listener which accepts the spectators
+    mutex sync.Mutex // spectators join and leave in other goroutines, so the fields below are guarded by a mutex
+    spectators map[*Spectator]bool // holds the spectators who are watching
+    tick uint32 // holds the number of snapshots published
+    lastSnapshot []byte // holds the last snapshot published
+}
+
+// Structure which writes each message as a binary WebSocket frame
+type WebSocketWriter struct {
+    conn net.Conn // holds the connection to the spectator
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the port of the stream when the address doesn't have one
+var streamDefaultPort = "7779"
+
+// Let's define the player number in the hello message sent to a spectator
+var netSpectatorNumber byte = 255
+
+// Let's define the most spectators who can watch a stream at the same time
+var maxSpectators = 32
+
+// Let's define the key which is added to the key of the browser to accept a WebSocket (it's the same for all WebSockets)
+var webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Let's define how often (frames) a headless game goes on from a screen, like a player pressing space
+var headlessSpaceFrames = 180
+
+// Variable to hold the stream of the game (nil if the game isn't streamed)
+var spectatorServer *SpectatorServer
+
+// Variables to know if the game runs without a window, and if space is pressed by the headless game in this frame
+var isHeadless = false
+var headlessSpace = false
+
+/*
+    ###################################
+    ## Functions to publish a stream ##
+    ###################################
+*/
+
+/*
+    Function: startStream
+    Start publishing the game for spectators on an address
+    Input: address to listen on (Ex: :7779)
+*/
+func startStream(address string) (*SpectatorServer, error) {
+    listener, err := net.Listen("tcp", getStreamAddress(address))
+    if err != nil {
+        return nil, err
+    }
+
+    server := &SpectatorServer{
+        listener: listener,
+        spectators: map[*Spectator]bool{},
+    }
+    go server.acceptSpectators()
+    return server, nil
+}
+
+/*
+    Function: getStreamAddress
+    Add the default port of the stream to an address if it doesn't have a port
+    Input: address (Ex: 192.168.1.10 or 192.168.1.10:7779)
+*/
+func getStreamAddress(address string) string {
+    if _, _, err := net.SplitHostPort(address); err != nil {
+        return net.JoinHostPort(address, streamDefaultPort)
+    }
+    return address
+}
+
+/*
+    Function: acceptSpectators
+    Accept spectators until the listener is closed. Each spectator is handled in its own goroutine
+*/
+func (server *SpectatorServer) acceptSpectators() {
+    for {
+        conn, err := server.listener.Accept()
+        if err != nil {
+            return
+        }
+        go server.addSpectator(conn)
+    }
+}
+
+/*
+    Function: addSpectator
+    Find out if the spectator uses a WebSocket, check the protocol version and start sending snapshots
+    Input: connection to the spectator
+*/
+func (server *SpectatorServer) addSpectator(conn net.Conn) {
+    conn.SetReadDeadline(time.Now().Add(5*time.Second))
+    reader := bufio.NewReader(conn)
+    first, err := reader.Peek(1)
+    if err != nil {
+        conn.Close()
+        return
+    }
+
+    // a WebSocket starts with an HTTP request (GET), other spectators start with the protocol version
+    var writer io.Writer = conn
+    if first[0] == 'G' {
+        if err := acceptWebSocket(conn, reader); err != nil {
+            log.Println("spectator from", conn.RemoteAddr(), "is not accepted:", err)
+            conn.Close()
+            return
+        }
+        writer = &WebSocketWriter{conn: conn}
+    } else {
+        version, _ := reader.ReadByte()
+        if version != netProtocolVersion {
+            writeMessage(conn, 'R', []byte(fmt.Sprintf("the stream has version %d, this game has version %d", netProtocolVersion, version)))
+            conn.Close()
+            return
+        }
+    }
+    conn.SetReadDeadline(time.Time{})
+
+    server.mutex.Lock()
+    if len(server.spectators) >= maxSpectators {
+        server.mutex.Unlock()
+        writeMessage(writer, 'R', []byte("the stream has too many spectators"))
+        conn.Close()
+        return
+    }
+    spectator := &Spectator{
+        conn: conn,
+        writer: writer,
+        snapshots: make(chan []byte, 1),
+        done: make(chan struct{}),
+    }
+    server.spectators[spectator] = true
+    server.mutex.Unlock()
+
+    log.Println("spectator is watching from", conn.RemoteAddr())
+    if err := writeMessage(writer, 'H', []byte{netProtocolVersion, netSpectatorNumber}); err != nil {
+        server.removeSpectator(spectator)
+        return
+    }
+    go server.sendSnapshots(spectator)
+
+    // spectators can't play. Anything they send is thrown away, until they disconnect
+    io.Copy(ioutil.Discard, reader)
+    server.removeSpectator(spectator)
+}
+
+/*
+    Function: removeSpectator
+    Close the connection to a spectator who left
+    Input: spectator
+*/
+func (server *SpectatorServer) removeSpectator(spectator *Spectator) {
+    server.mutex.Lock()
+    defer server.mutex.Unlock()
+    if !server.spectators[spectator] {
+        return
+    }
+    delete(server.spectators, spectator)
+    close(spectator.done)
+    spectator.conn.Close()
+    log.Println("spectator from", spectator.conn.RemoteAddr(), "left")
+}
+
+/*
+    Function: sendSnapshots
+    Send the snapshots to a spectator. The first snapshot is sent in full, the next ones as the changes from the previous one
+    Input: spectator
+*/
+func (server *SpectatorServer) sendSnapshots(spectator *Spectator) {
+    var previous []byte
+    for {
+        var snapshot []byte
+        select {
+        case snapshot = <-spectator.snapshots:
+        case <-spectator.done:
+            return
+        }
+
+        kind, data := byte('F'), snapshot
+        if len(previous) == len(snapshot) {
+            kind, data = 'D', encodeDelta(previous, snapshot)
+        }
+        if err := writeMessage(spectator.writer, kind, data); err != nil {
+            // the connection is broken, closing it stops addSpectator which removes the spectator
+            spectator.conn.Close()
+            return
+        }
+        previous = snapshot
+    }
+}
+
+/*
+    Function: publish
+    Send the state of the game to all the spectators. It's called on each frame by the update function
+*/
+func (server *SpectatorServer) publish() {
+    snapshot := encodeSnapshot(server.tick)
+
+    server.mutex.Lock()
+    defer server.mutex.Unlock()
+    server.tick++
+    server.lastSnapshot = snapshot
+    for spectator := range server.spectators {
+        select {
+        case <-spectator.snapshots:
+        default:
+        }
+        spectator.snapshots <- snapshot
+    }
+}
+
+/*
+    Function: getSpectatorCount
+    Get the number of spectators who are watching
+*/
+func (server *SpectatorServer) getSpectatorCount() int {
+    server.mutex.Lock()
+    defer server.mutex.Unlock()
+    return len(server.spectators)
+}
+
+/*
+    Function: close
+    Stop the stream and disconnect all the spectators
+*/
+func (server *SpectatorServer) close() {
+    server.listener.Close()
+    server.mutex.Lock()
+    spectators := []*Spectator{}
+    for spectator := range server.spectators {
+        spectators = append(spectators, spectator)
+    }
+    server.mutex.Unlock()
+    for _, spectator := range spectators {
+        server.removeSpectator(spectator)
+    }
+}
+
+/*
+    ############################
+    ## Functions of WebSocket ##
+    ############################
+*/
+
+/*
+    Function: acceptWebSocket
+    Read the HTTP request of a WebSocket and answer that the connection is upgraded to a WebSocket
+    Inputs: connection and the reader of the connection
+*/
+func acceptWebSocket(conn net.Conn, reader *bufio.Reader) error {
+    request, err := http.ReadRequest(reader)
+    if err != nil {
+        return err
+    }
+    key := request.Header.Get("Sec-WebSocket-Key")
+    if !strings.EqualFold(request.Header.Get("Upgrade"), "websocket") || key == "" {
+        fmt.Fprint(conn, "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n")
+        return errors.New("the request is not a WebSocket")
+    }
+
+    hash := sha1.Sum([]byte(key+webSocketGUID))
+    _, err = fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+        base64.StdEncoding.EncodeToString(hash[:]))
+    return err
+}
+
+/*
+    Function: Write
+    Write data as a single binary WebSocket frame. Frames sent by a server are not masked
+    Input: data
+*/
+func (writer *WebSocketWriter) Write(data []byte) (int, error) {
+    // the first byte is the final frame flag and the binary type, then the size in 1, 2 or 8 bytes
+    frame := []byte{0x82}
+    size := len(data)
+    switch {
+    case size < 126:
+        frame = append(frame, byte(size))
+    case size < 1<<16:
+        frame = append(frame, 126, byte(size>>8), byte(size))
+    default:
+        frame = append(frame, 127)
+        for shift := 56; shift >= 0; shift = shift-8 {
+            frame = append(frame, byte(size>>uint(shift)))
+        }
+    }
+
+    if _, err := writer.conn.Write(append(frame, data...)); err != nil {
+        return 0, err
+    }
+    return size, nil
+}
+
+/*
+    #################################
+    ## Functions to watch a stream ##
+    #################################
+*/
+
+/*
+    Function: watchGame
+    Connect to the stream of a game as a spectator
+    Input: address of the stream (Ex: 192.168.1.10:7779)
+*/
+func watchGame(address string) (*NetClient, error) {
+    client, err := joinGame(getStreamAddress(address))
+    if err != nil {
+        return nil, err
+    }
+    if !client.isSpectator() {
+        client.conn.Close()
+        return nil, errors.New("the address is a game to join (-join), not a stream")
+    }
+    return client, nil
+}
+
+/*
+    Function: isSpectator
+    Check if the client is a spectator, who can't send directions
+*/
+func (client *NetClient) isSpectator() bool {
+    return client.number == int(netSpectatorNumber)
+}
+
+/*
+    ##################################
+    ## Functions of a headless game ##
+    ##################################
+*/
+
+/*
+    Function: runHeadless
+    Run the game without a window, 60 frames per second. The game is sent to the players who joined and the spectators
+*/
+func runHeadless() {
+    ticker := time.NewTicker(time.Second/60)
+    defer ticker.Stop()
+    for frame := 1; ; frame++ {
+        <-ticker.C
+
+        // nobody can press space, so the game goes on from the screens now and then
+        headlessSpace = frame%headlessSpaceFrames == 0
+        if gameBot != nil {
+            gameBot.update()
+        }
+        updateGame()
+
+        if netHost != nil {
+            netHost.broadcast()
+            netHost.announce()
+        }
+        if spectatorServer != nil {
+            spectatorServer.publish()
+        }
+    }
+}
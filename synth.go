@@ -0,0 +1,329 @@
+package main
+
+/*
+    This file contains a small synthesizer which creates the sound effects of the game.
+
+    Instead of shipping audio files, each sound effect is described in a sound definition file (sounds.json).
+    A sound effect is a list of notes played one after the other. Each note has
+
+    wave    - the shape of the wave: "square", "triangle", "sine" or "noise"
+    from    - frequency (Hz) at the start of the note
+    to      - frequency (Hz) at the end of the note (pitch sweep). If it's 0, the frequency doesn't change
+    seconds - length of the note
+    volume  - loudness of the note, from 0 to 1
+    duty    - part of the wave which is high for square waves (0.5 is a normal square wave)
+    attack, decay, sustain, release - the envelope of the note (see getEnvelope)
+
+    Ex:
+    {
+        "food": [
+            {"wave": "square", "from": 500, "to": 700, "seconds": 0.05, "volume": 0.4}
+        ]
+    }
+
+    The same definition always creates the same samples (noise uses its own generator instead of a random number generator),
+    so the sounds can be checked or exported to wav files with the -exportsounds flag.
+*/
+import (
+    "encoding/binary"
+    "encoding/json"
+    "fmt"
+    "io"
+    "math"
+    "os"
+    "path/filepath"
+    "sort"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure which keeps the description of a single note of a sound effect. Fields are exported so the json package can fill them
+type SoundNote struct {
+    Wave string `json:"wave"`
+    From float64 `json:"from"`
+    To float64 `json:"to"`
+    Seconds float64 `json:"seconds"`
+    Volume float64 `json:"volume"`
+    Duty float64 `json:"duty"`
+    Attack float64 `json:"attack"`
+    Decay float64 `json:"decay"`
+    Sustain float64 `json:"sustain"`
+    Release float64 `json:"release"`
+}
+
+// Structure which keeps the state of the noise generator
+// The noise is made by a 15 bit shift register (like old game consoles), so it's the same every time
+type NoiseGenerator struct {
+    register uint16 // holds the bits of the shift register
+    value float64 // holds the current noise value (-1 or 1)
+}
+
+/*
+    ##############################
+    ## Functions to read sounds ##
+    ##############################
+*/
+
+/*
+    Function: loadSoundDefinitions
+    Read the sound definition file
+    Input: path to the sound definition file
+    Outputs a map with the notes of each sound effect
+*/
+func loadSoundDefinitions(fileName string) (map[string][]SoundNote, error) {
+    file, err := openGameFile(fileName)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    definitions := map[string][]SoundNote{}
+    if err := json.NewDecoder(file).Decode(&definitions); err != nil {
+        return nil, fmt.Errorf("sound definitions %s: %v", fileName, err)
+    }
+
+    // let's check each note, so a mistake in the file is found when the game starts
+    for name, notes := range definitions {
+        for i, note := range notes {
+            if err := checkSoundNote(note); err != nil {
+                return nil, fmt.Errorf("sound definitions %s: %s note %d: %v", fileName, name, i+1, err)
+            }
+        }
+    }
+    return definitions, nil
+}
+
+/*
+    Function: checkSoundNote
+    Check if a note has valid values
+    Input: note
+*/
+func checkSoundNote(note SoundNote) error {
+    switch note.Wave {
+    case "square", "triangle", "sine", "noise":
+    default:
+        return fmt.Errorf("unknown wave %q", note.Wave)
+    }
+    if note.Seconds <= 0 {
+        return fmt.Errorf("seconds should be more than 0")
+    }
+    if note.From <= 0 {
+        return fmt.Errorf("from frequency should be more than 0")
+    }
+    if note.Volume < 0 || note.Volume > 1 {
+        return fmt.Errorf("volume should be from 0 to 1")
+    }
+    return nil
+}
+
+/*
+    ################################
+    ## Functions to create sounds ##
+    ################################
+*/
+
+/*
+    Function: getOscillatorValue
+    Get the value (-1 to 1) of a wave at the given position of the wave
+    Inputs: note (for the wave shape and duty), position in the wave (every whole number is a full wave) and the noise generator
+*/
+func getOscillatorValue(note SoundNote, phase float64, noise *NoiseGenerator) float64 {
+    switch note.Wave {
+    case "square":
+        duty := note.Duty
+        if duty <= 0 || duty >= 1 {
+            duty = 0.5
+        }
+        if phase-math.Floor(phase) < duty {
+            return 1
+        }
+        return -1
+    case "triangle":
+        return triangleWave(phase)
+    case "sine":
+        return math.Sin(2*math.Pi*phase)
+    case "noise":
+        return noise.value
+    }
+    return 0
+}
+
+/*
+    Function: getEnvelope
+    Get how loud the note is (0 to 1) at the given time of the note
+
+    The envelope has 4 parts:
+    attack  - seconds to go from silent to the full volume
+    decay   - seconds to go from the full volume to the sustain level
+    sustain - level (0 to 1) kept until the release. If it's 0, the full volume is kept
+    release - seconds to fade out at the end of the note
+    Inputs: note and the time (seconds) from the start of the note
+*/
+func getEnvelope(note SoundNote, time float64) float64 {
+    sustain := note.Sustain
+    if sustain <= 0 {
+        sustain = 1
+    }
+
+    level := sustain
+    switch {
+    case time < note.Attack:
+        level = time/note.Attack
+    case time < note.Attack+note.Decay:
+        level = 1-(1-sustain)*(time-note.Attack)/note.Decay
+    }
+
+    // fade out during the release
+    timeLeft := note.Seconds-time
+    if timeLeft < note.Release {
+        level = level*timeLeft/note.Release
+    }
+    return level
+}
+
+/*
+    Function: step
+    Move the noise generator forward by one step and get the new value
+*/
+func (noise *NoiseGenerator) step() float64 {
+    bit := (noise.register ^ (noise.register >> 1)) & 1
+    noise.register = (noise.register >> 1) | (bit << 14)
+    if noise.register&1 == 1 {
+        noise.value = 1
+    } else {
+        noise.value = -1
+    }
+    return noise.value
+}
+
+/*
+    Function: synthesize
+    Create the samples of a sound effect (values from -1 to 1) by playing its notes one after the other
+    Inputs: notes of the sound effect and the sample rate
+*/
+func synthesize(notes []SoundNote, rate int) []float64 {
+    samples := []float64{}
+    noise := &NoiseGenerator{register: 1}
+    noise.step()
+
+    for _, note := range notes {
+        count := int(note.Seconds*float64(rate))
+        endFrequency := note.To
+        if endFrequency <= 0 {
+            endFrequency = note.From
+        }
+
+        phase := 0.0
+        for i := 0; i < count; i++ {
+            time := float64(i)/float64(rate)
+            frequency := note.From+(endFrequency-note.From)*float64(i)/float64(count)
+
+            value := getOscillatorValue(note, phase, noise)
+            samples = append(samples, value*note.Volume*getEnvelope(note, time))
+
+            // noise changes its value once every wave, so higher frequencies make brighter noise
+            nextPhase := phase+frequency/float64(rate)
+            if math.Floor(nextPhase) != math.Floor(phase) {
+                noise.step()
+            }
+            phase = nextPhase
+        }
+    }
+    return samples
+}
+
+/*
+    Function: samplesToPCM
+    Convert samples (-1 to 1) to 16 bit stereo PCM data, which can be played by the audio package
+    Input: samples
+*/
+func samplesToPCM(samples []float64) []byte {
+    pcm := make([]byte, len(samples)*4)
+    for i, sample := range samples {
+        sample = math.Max(-1, math.Min(1, sample))
+        putSample(pcm[i*4:], int16(sample*math.MaxInt16))
+    }
+    return pcm
+}
+
+/*
+    ####################################
+    ## Functions to export wave files ##
+    ####################################
+*/
+
+/*
+    Function: writeWav
+    Write 16 bit stereo PCM data as a wav file
+    Inputs: writer, PCM data and the sample rate
+*/
+func writeWav(writer io.Writer, pcm []byte, rate int) error {
+    channels := 2
+    bytesPerSample := 2
+
+    // wav files start with a RIFF header, a format chunk and a data chunk
+    header := []interface{}{
+        []byte("RIFF"),
+        uint32(36+len(pcm)),
+        []byte("WAVE"),
+        []byte("fmt "),
+        uint32(16), // size of the format chunk
+        uint16(1), // PCM format
+        uint16(channels),
+        uint32(rate),
+        uint32(rate*channels*bytesPerSample), // bytes per second
+        uint16(channels*bytesPerSample), // bytes per sample (all channels)
+        uint16(bytesPerSample*8), // bits per sample
+        []byte("data"),
+        uint32(len(pcm)),
+    }
+    for _, value := range header {
+        if err := binary.Write(writer, binary.LittleEndian, value); err != nil {
+            return err
+        }
+    }
+
+    _, err := writer.Write(pcm)
+    return err
+}
+
+/*
+    Function: exportSounds
+    Create all the sound effects of a sound definition file and write each one as a wav file into a folder
+    Inputs: path to the sound definition file and the folder to write the wav files
+*/
+func exportSounds(definitionFile string, folder string) error {
+    definitions, err := loadSoundDefinitions(definitionFile)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(folder, 0755); err != nil {
+        return err
+    }
+
+    // let's export the sounds in the order of their names, so the output is the same every time
+    names := []string{}
+    for name := range definitions {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    for _, name := range names {
+        pcm := samplesToPCM(synthesize(definitions[name], sampleRate))
+
+        file, err := os.Create(filepath.Join(folder, name+".wav"))
+        if err != nil {
+            return err
+        }
+        err = writeWav(file, pcm, sampleRate)
+        file.Close()
+        if err != nil {
+            return err
+        }
+        fmt.Println("exported", filepath.Join(folder, name+".wav"))
+    }
+    return nil
+}
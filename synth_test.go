@@ -0,0 +1,135 @@
+package main
+
+/*
+    This file contains the tests of the synthesizer (see synth.go).
+
+    The tests link the whole game, so on a machine without a display they're run with:
+    go test -tags nowindow
+*/
+import (
+    "bytes"
+    "crypto/sha256"
+    "fmt"
+    "io/ioutil"
+    "math"
+    "path/filepath"
+    "testing"
+)
+
+// Let's define a sound effect which uses every wave and every part of the envelope, for the checksum test
+var testSoundNotes = []SoundNote{
+    {Wave: "square", From: 440, To: 880, Seconds: 0.05, Volume: 0.5, Duty: 0.25, Attack: 0.01, Release: 0.01},
+    {Wave: "triangle", From: 300, Seconds: 0.05, Volume: 0.4, Decay: 0.02, Sustain: 0.5},
+    {Wave: "sine", From: 1000, To: 500, Seconds: 0.05, Volume: 0.6},
+    {Wave: "noise", From: 2000, Seconds: 0.05, Volume: 0.3, Release: 0.02},
+}
+
+// Let's define the sha256 of the wav file of testSoundNotes at 8000 samples per second
+var testSoundChecksum = "d80168b4cf043806b58a936a1c44849f6f1d14028ff0a0d692f7ff8db74b417c"
+
+/*
+    Function: checkSamples
+    Fail the test if the samples aren't the expected ones
+    Inputs: test, samples and the expected samples
+*/
+func checkSamples(t *testing.T, samples []float64, expected []float64) {
+    if len(samples) != len(expected) {
+        t.Fatalf("got %d samples, expected %d", len(samples), len(expected))
+    }
+    for i := range expected {
+        if math.Abs(samples[i]-expected[i]) > 1e-9 {
+            t.Errorf("sample %d is %v, expected %v", i, samples[i], expected[i])
+        }
+    }
+}
+
+func TestSynthesizeSquare(t *testing.T) {
+    // 1000Hz at 8000 samples per second is 8 samples per wave: 4 high, then 4 low
+    notes := []SoundNote{{Wave: "square", From: 1000, Seconds: 0.001, Volume: 0.5}}
+    checkSamples(t, synthesize(notes, 8000), []float64{0.5, 0.5, 0.5, 0.5, -0.5, -0.5, -0.5, -0.5})
+}
+
+func TestSynthesizeSquareDuty(t *testing.T) {
+    // a duty of 0.25 keeps the wave high for a quarter of it
+    notes := []SoundNote{{Wave: "square", From: 1000, Seconds: 0.001, Volume: 1, Duty: 0.25}}
+    checkSamples(t, synthesize(notes, 8000), []float64{1, 1, -1, -1, -1, -1, -1, -1})
+}
+
+func TestSynthesizeSine(t *testing.T) {
+    // 2000Hz at 8000 samples per second is 4 samples per wave
+    notes := []SoundNote{{Wave: "sine", From: 2000, Seconds: 0.001, Volume: 1}}
+    checkSamples(t, synthesize(notes, 8000), []float64{0, 1, 0, -1, 0, 1, 0, -1})
+}
+
+func TestSynthesizeTriangle(t *testing.T) {
+    notes := []SoundNote{{Wave: "triangle", From: 1000, Seconds: 0.001, Volume: 1}}
+    checkSamples(t, synthesize(notes, 8000), []float64{1, 0.5, 0, -0.5, -1, -0.5, 0, 0.5})
+}
+
+func TestSynthesizeEnvelope(t *testing.T) {
+    // the attack goes up over the first 4 samples and the release goes down over the last 4
+    notes := []SoundNote{{Wave: "square", From: 100, Seconds: 0.002, Volume: 1, Attack: 0.0005, Release: 0.0005}}
+    checkSamples(t, synthesize(notes, 8000), []float64{
+        0, 0.25, 0.5, 0.75, 1, 1, 1, 1,
+        1, 1, 1, 1, 1, 0.75, 0.5, 0.25,
+    })
+}
+
+func TestSynthesizeNotesInOrder(t *testing.T) {
+    // the notes are played one after the other
+    notes := []SoundNote{
+        {Wave: "square", From: 1000, Seconds: 0.0005, Volume: 1},
+        {Wave: "square", From: 1000, Seconds: 0.0005, Volume: 0.5},
+    }
+    checkSamples(t, synthesize(notes, 8000), []float64{1, 1, 1, 1, 0.5, 0.5, 0.5, 0.5})
+}
+
+func TestSamplesToPCM(t *testing.T) {
+    // samples are 16 bit little endian, the same on both channels, and clipped to -1 and 1
+    pcm := samplesToPCM([]float64{0, 1, -2})
+    expected := []byte{0, 0, 0, 0, 0xff, 0x7f, 0xff, 0x7f, 0x01, 0x80, 0x01, 0x80}
+    if !bytes.Equal(pcm, expected) {
+        t.Errorf("got % x, expected % x", pcm, expected)
+    }
+}
+
+func TestWavChecksum(t *testing.T) {
+    var wav bytes.Buffer
+    if err := writeWav(&wav, samplesToPCM(synthesize(testSoundNotes, 8000)), 8000); err != nil {
+        t.Fatal(err)
+    }
+    if checksum := fmt.Sprintf("%x", sha256.Sum256(wav.Bytes())); checksum != testSoundChecksum {
+        t.Errorf("the wav file changed, its sha256 is %s, expected %s", checksum, testSoundChecksum)
+    }
+}
+
+func TestExportSoundsIsRepeatable(t *testing.T) {
+    // the sounds of the game are exported twice, the wav files should be the same byte for byte
+    folders := []string{t.TempDir(), t.TempDir()}
+    for _, folder := range folders {
+        if err := exportSounds("sounds.json", folder); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    files, err := filepath.Glob(filepath.Join(folders[0], "*.wav"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(files) == 0 {
+        t.Fatal("no sounds are exported")
+    }
+    for _, file := range files {
+        first, err := ioutil.ReadFile(file)
+        if err != nil {
+            t.Fatal(err)
+        }
+        second, err := ioutil.ReadFile(filepath.Join(folders[1], filepath.Base(file)))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !bytes.Equal(first, second) {
+            t.Errorf("%s is different when it's exported again", filepath.Base(file))
+        }
+    }
+}
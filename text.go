@@ -0,0 +1,120 @@
+package main
+
+/*
+    This file contains the code which draws text on the screen.
+
+    Text is drawn with a real font instead of the debug font of ebiten.
+    The font is taken from the "font" asset of the theme:
+    - path to a TTF or OTF file
+    - "bitmap" to use a small bitmap font
+    If the theme does not give a font, the Go Mono font (which comes with the golang.org/x/image package) is used.
+*/
+import (
+    "golang.org/x/image/font"
+    "golang.org/x/image/font/basicfont"
+    "golang.org/x/image/font/gofont/gomono"
+    "golang.org/x/image/font/opentype"
+    "image/color"
+    "log"
+)
+
+// Let's have a variable to define the size of the font
+var fontSize = 12.0
+
+// Variable to hold the font used to draw text
+var gameFont font.Face
+
+// Variable to keep the already loaded fonts, so a font file is read only once
+var loadedFonts = map[string]font.Face{}
+
+/*
+    Function: loadFont
+    Load a font to draw text
+    Input: path to a TTF/OTF file, "bitmap" for the bitmap font or an empty string for the default font
+*/
+func loadFont(fontFile string) font.Face {
+    if fontFile == "bitmap" {
+        return basicfont.Face7x13
+    }
+
+    // return the font if it's already loaded
+    if face, ok := loadedFonts[fontFile]; ok {
+        return face
+    }
+
+    // read the font file. If no file is given, let's use the Go Mono font
+    fontBytes := gomono.TTF
+    if fontFile != "" {
+        var err error
+        fontBytes, err = readGameFile(fontFile)
+        if err != nil {
+            log.Fatal(err)
+        }
+    }
+
+    parsedFont, err := opentype.Parse(fontBytes)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    face, err := opentype.NewFace(parsedFont, &opentype.FaceOptions{
+        Size: fontSize,
+        DPI: 72,
+        Hinting: font.HintingFull,
+    })
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    loadedFonts[fontFile] = face
+    return face
+}
+
+/*
+    Function: measureText
+    Get the width and the height of the given text when drawn with the game font
+    Input: text
+*/
+func measureText(str string) (int, int) {
+    metrics := gameFont.Metrics()
+    return font.MeasureString(gameFont, str).Ceil(), (metrics.Ascent+metrics.Descent).Ceil()
+}
+
+/*
+    Function: drawText
+    Draw text on the screen. (x, y) is the top left corner of the text
+    Inputs: screen, text, position and color
+*/
+func drawText(screen *Image, str string, x int, y int, clr color.Color) {
+    // ebiten draws text from the baseline, so let's move the text down by the ascent of the font
+    drawFontText(screen, str, gameFont, x, y+gameFont.Metrics().Ascent.Ceil(), clr)
+}
+
+/*
+    Function: drawTextCentered
+    Draw text on the screen centered around the given x position
+    Inputs: screen, text, center x position, top y position and color
+*/
+func drawTextCentered(screen *Image, str string, centerX int, y int, clr color.Color) {
+    width, _ := measureText(str)
+    drawText(screen, str, centerX-width/2, y, clr)
+}
+
+/*
+    Function: drawTextRight
+    Draw text on the screen ending at the given x position
+    Inputs: screen, text, right x position, top y position and color
+*/
+func drawTextRight(screen *Image, str string, rightX int, y int, clr color.Color) {
+    width, _ := measureText(str)
+    drawText(screen, str, rightX-width, y, clr)
+}
+
+/*
+    Function: getTextColor
+    Get the text color from the palette of the current theme
+*/
+func getTextColor() color.Color {
+    clr, _ := getPaletteColor("text")
+    return clr
+}
@@ -0,0 +1,243 @@
+package main
+
+/*
+    This file contains the code which loads themes (skins) of the game.
+
+    A theme is a folder with a theme.json manifest file. The manifest maps logical asset names to image files and
+    gives a palette of colors. Paths in the manifest are relative to the folder of the manifest.
+
+    Ex: themes/retro/theme.json
+    {
+        "name": "Retro",
+        "assets": {
+            "pacman": "pacman.png",
+            "wall": "wall.png"
+        },
+        "palette": {
+            "wall": "#00ff00",
+            "background": "#101010"
+        }
+    }
+
+    If a theme does not give an asset or a color, the one from the default theme is used.
+*/
+import (
+    "encoding/json"
+    "fmt"
+    "image/color"
+    "log"
+    "path/filepath"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+// Structure which keeps the information of a loaded theme
+type Theme struct {
+    name string // holds the name of the theme
+    assets map[string]string // holds the file path of each logical asset name
+    palette map[string]color.RGBA // holds the colors of the theme (wall, background, text)
+}
+
+// Structure which matches the theme.json manifest file. Fields are exported so the json package can fill them
+type ThemeManifest struct {
+    Name string `json:"name"`
+    Assets map[string]string `json:"assets"`
+    Palette map[string]string `json:"palette"`
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's have a variable to define the folder where themes are kept
+var themesDir = "themes"
+
+// Let's define the default theme. Other themes fall back to this theme for missing entries
+var defaultTheme = Theme{
+    name: "default",
+    assets: map[string]string{
+        "pacman": "assets/pacman.png",
+        "pacmanU": "assets/pacmanU.png",
+        "pacmanR": "assets/pacmanR.png",
+        "pacmanD": "assets/pacmanD.png",
+        "pacmanL": "assets/pacmanL.png",
+        "pacmanI": "assets/pacmanI.png",
+        "enemy": "assets/enemy.png",
+        "enemyFrightened": "assets/enemyFrightened.png",
+        "food": "assets/food.png",
+        "pellet": "assets/pellet.png",
+        "fruit": "assets/fruit.png",
+        "gameOver": "assets/gameover.png",
+        "levelComplete": "assets/levelcomplete.png",
+        "win": "assets/win.png",
+        "start": "assets/start.png",
+        "sounds": "sounds.json",
+        // "wall" (a single image for each wall cell) and "wallTiles" (a strip of 47 wall tiles) are not given,
+        // so walls are drawn as outlines with the wall color
+        // "font" is not given, so the Go Mono font is used (see text.go)
+    },
+    palette: map[string]color.RGBA{
+        "background": color.RGBA{A: 255},
+        "text": color.RGBA{R: 255, G: 255, B: 255, A: 255},
+    },
+}
+
+// Variable to hold the theme given with the -theme flag. Levels without their own theme use this theme
+var selectedTheme = defaultTheme
+
+// Variable to hold the theme of the current level
+var currentTheme = defaultTheme
+
+// Variable to keep the already loaded themes, so a theme file is read only once
+var loadedThemes = map[string]Theme{}
+
+/*
+    #############################
+    ## Functions to use themes ##
+    #############################
+*/
+
+/*
+    Function: loadTheme
+    Load a theme from its manifest file. The game can't go on without its theme, so an error stops the game
+    The theme can be given as a name of a folder inside the themes folder, a folder or a path to the manifest file
+    Input: name or path of the theme
+*/
+func loadTheme(name string) Theme {
+    if name == "" || name == defaultTheme.name {
+        return defaultTheme
+    }
+
+    // return the theme if it's already loaded
+    if theme, ok := loadedThemes[name]; ok {
+        return theme
+    }
+    theme, err := readTheme(name)
+    if err != nil {
+        log.Fatal(err)
+    }
+    loadedThemes[name] = theme
+    return theme
+}
+
+/*
+    Function: readTheme
+    Read a theme from its manifest file, like loadTheme, and give back the error if it can't be read
+    (Ex: to check the theme of a level pack before playing it)
+    Input: name or path of the theme
+*/
+func readTheme(name string) (Theme, error) {
+    // let's find the manifest file
+    manifestFile := name
+    if info, err := statGameFile(name); err != nil || info.IsDir() {
+        manifestFile = filepath.Join(name, "theme.json")
+        if err != nil {
+            // it's not an existing path, so it should be a theme inside the themes folder
+            manifestFile = filepath.Join(themesDir, name, "theme.json")
+        }
+    }
+
+    file, err := openGameFile(manifestFile)
+    if err != nil {
+        return Theme{}, err
+    }
+    defer file.Close()
+
+    // read the manifest json into the manifest structure
+    manifest := ThemeManifest{}
+    if err := json.NewDecoder(file).Decode(&manifest); err != nil {
+        return Theme{}, fmt.Errorf("theme %s: %v", manifestFile, err)
+    }
+
+    theme := Theme{
+        name: manifest.Name,
+        assets: map[string]string{},
+        palette: map[string]color.RGBA{},
+    }
+    if theme.name == "" {
+        theme.name = name
+    }
+
+    // asset paths are relative to the folder of the manifest
+    themeFolder := filepath.Dir(manifestFile)
+    for asset, path := range manifest.Assets {
+        if !filepath.IsAbs(path) {
+            path = filepath.Join(themeFolder, path)
+        }
+        theme.assets[asset] = path
+    }
+
+    for key, value := range manifest.Palette {
+        clr, err := parseHexColor(value)
+        if err != nil {
+            return Theme{}, fmt.Errorf("theme %s: palette %s: %v", manifestFile, key, err)
+        }
+        theme.palette[key] = clr
+    }
+    return theme, nil
+}
+
+/*
+    Function: parseHexColor
+    Convert a color written as #rrggbb or #rrggbbaa to a color
+    Input: color as a string
+*/
+func parseHexColor(value string) (color.RGBA, error) {
+    clr := color.RGBA{A: 255}
+    var err error
+    switch len(value) {
+    case 7:
+        _, err = fmt.Sscanf(value, "#%02x%02x%02x", &clr.R, &clr.G, &clr.B)
+    case 9:
+        _, err = fmt.Sscanf(value, "#%02x%02x%02x%02x", &clr.R, &clr.G, &clr.B, &clr.A)
+    default:
+        err = fmt.Errorf("invalid color %q, expected #rrggbb or #rrggbbaa", value)
+    }
+    return clr, err
+}
+
+/*
+    Function: getLevelTheme
+    Get the theme of a level. If the level does not have its own theme, the theme of its maze is used (the theme key
+    of the maze file, see maze.go), then the selected theme
+    Input: level
+*/
+func getLevelTheme(level int) Theme {
+    if LEVELS[level].theme != "" {
+        return loadTheme(LEVELS[level].theme)
+    }
+    if theme := getLevelMaze(level).theme; theme != "" {
+        return loadTheme(theme)
+    }
+    return selectedTheme
+}
+
+/*
+    Function: getAsset
+    Get the file path of a logical asset from the current theme. Returns an empty string if no theme has the asset
+    Input: logical name of the asset (Ex: pacman, enemy, food)
+*/
+func getAsset(name string) string {
+    if path, ok := currentTheme.assets[name]; ok {
+        return path
+    }
+    return defaultTheme.assets[name]
+}
+
+/*
+    Function: getPaletteColor
+    Get a color from the palette of the current theme. The second value is false if no theme has the color
+    Input: name of the color (Ex: wall, background, text)
+*/
+func getPaletteColor(name string) (color.RGBA, bool) {
+    if clr, ok := currentTheme.palette[name]; ok {
+        return clr, true
+    }
+    clr, ok := defaultTheme.palette[name]
+    return clr, ok
+}
@@ -0,0 +1,826 @@
+package main
+
+/*
+    This file contains the importer of maps made with the Tiled map editor (https://www.mapeditor.org).
+
+    A maze file ending with .tmx (Tiled XML map) or .tmj/.json (Tiled JSON map) is read as a Tiled map instead of the
+    text format (see readMazeFile). The map is changed to the maze of the game:
+    - tile layers give the walls, food and power pellets. A tile is a wall, food or pellet when its tile in the tileset
+      has a "tile" property (wall, food, pellet, empty, spawn, player 1 ...) or a type (class) with one of these names.
+      Tiles without them take the meaning from the name of their layer: "walls", "food" (or "dots") and "pellets"
+    - object layers give the starts of the players (type "pacman", "player 1" ... "player 4"), the spawn points of the
+      enemies ("enemy" or "spawn"), the fruit ("fruit") and the tunnels ("tunnel"). The two ends of a tunnel point to
+      each other with a "to" object property, or have the same name. The type of an object can also be its name
+    - the custom properties of the map are the header of the maze (name, author, pacman speed, enemy speed, enemies,
+      wall color, par time, see maze.go)
+
+    Things of Tiled which have no place in the maze (Ex: image layers, shapes of objects, layer offsets) are ignored
+    with a warning which names the layer and the object.
+*/
+import (
+    "bytes"
+    "compress/gzip"
+    "compress/zlib"
+    "encoding/base64"
+    "encoding/binary"
+    "encoding/json"
+    "encoding/xml"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "math"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+/*
+    ################
+    ## Structures ##
+    ################
+*/
+
+// Structure of a Tiled map, the same for TMX and JSON maps
+type TiledMap struct {
+    orientation string // holds the orientation of the map (only orthogonal maps can be mazes)
+    infinite bool // holds whether the map is infinite (its tiles are in chunks)
+    width int // holds the size of the map in tiles
+    height int
+    tileWidth int // holds the size of a tile in pixels
+    tileHeight int
+    properties []TiledProperty // holds the custom properties of the map
+    tileTiles map[uint32]byte // holds the tile of the game of each tile (global tile id) which has one
+    layers []TiledLayer // holds the layers, from the bottom to the top
+    warnings []string // holds the warnings found while reading the map
+}
+
+// Structure of a custom property of a Tiled map, layer, tile or object
+type TiledProperty struct {
+    name string
+    value string
+}
+
+// Structure of a layer of a Tiled map
+type TiledLayer struct {
+    name string
+    kind string // holds the type of the layer (tilelayer, objectgroup, imagelayer or group)
+    offsetX float64 // holds how far the layer is moved in pixels
+    offsetY float64
+    tiles []uint32 // holds the global tile id of each tile of a tile layer, row by row
+    objects []TiledObject // holds the objects of an object layer
+}
+
+// Structure of an object of an object layer
+type TiledObject struct {
+    id int
+    name string
+    kind string // holds the type (class) of the object
+    x float64 // holds the position of the object in pixels
+    y float64
+    width float64
+    height float64
+    rotation float64
+    gid uint32 // holds the global tile id of a tile object (0 for other objects)
+    shape string // holds the shape of the object when it's not a rectangle (point, ellipse, polygon, polyline or text)
+    template string // holds the template file of the object
+    properties []TiledProperty
+}
+
+// Structures which match the TMX file. Fields are exported so the xml package can fill them
+type TMXMap struct {
+    Orientation string `xml:"orientation,attr"`
+    Infinite int `xml:"infinite,attr"`
+    Width int `xml:"width,attr"`
+    Height int `xml:"height,attr"`
+    TileWidth int `xml:"tilewidth,attr"`
+    TileHeight int `xml:"tileheight,attr"`
+    Properties []TMXProperty `xml:"properties>property"`
+    Tilesets []TMXTileset `xml:"tileset"`
+    TMXLayers
+}
+
+// Structure of the layers of a TMX map or group. The xml package keeps each kind of layer in its own list
+type TMXLayers struct {
+    Layers []TMXLayer `xml:"layer"`
+    ObjectGroups []TMXObjectGroup `xml:"objectgroup"`
+    ImageLayers []TMXLayer `xml:"imagelayer"`
+    Groups []TMXLayer `xml:"group"`
+}
+
+type TMXProperty struct {
+    Name string `xml:"name,attr"`
+    Value string `xml:"value,attr"`
+    Text string `xml:",chardata"` // multiline string properties keep the value here
+}
+
+type TMXTileset struct {
+    FirstGid uint32 `xml:"firstgid,attr"`
+    Source string `xml:"source,attr"`
+    Tiles []TMXTile `xml:"tile"`
+}
+
+type TMXTile struct {
+    Id uint32 `xml:"id,attr"`
+    Type string `xml:"type,attr"`
+    Class string `xml:"class,attr"`
+    Properties []TMXProperty `xml:"properties>property"`
+}
+
+type TMXLayer struct {
+    Name string `xml:"name,attr"`
+    OffsetX float64 `xml:"offsetx,attr"`
+    OffsetY float64 `xml:"offsety,attr"`
+    Data TMXData `xml:"data"`
+}
+
+type TMXData struct {
+    Encoding string `xml:"encoding,attr"`
+    Compression string `xml:"compression,attr"`
+    Text string `xml:",chardata"`
+    Tiles []struct {
+        Gid uint32 `xml:"gid,attr"`
+    } `xml:"tile"`
+    Chunks []struct{} `xml:"chunk"`
+}
+
+type TMXObjectGroup struct {
+    Name string `xml:"name,attr"`
+    OffsetX float64 `xml:"offsetx,attr"`
+    OffsetY float64 `xml:"offsety,attr"`
+    Objects []TMXObject `xml:"object"`
+}
+
+type TMXObject struct {
+    Id int `xml:"id,attr"`
+    Name string `xml:"name,attr"`
+    Type string `xml:"type,attr"`
+    Class string `xml:"class,attr"`
+    X float64 `xml:"x,attr"`
+    Y float64 `xml:"y,attr"`
+    Width float64 `xml:"width,attr"`
+    Height float64 `xml:"height,attr"`
+    Rotation float64 `xml:"rotation,attr"`
+    Gid uint32 `xml:"gid,attr"`
+    Template string `xml:"template,attr"`
+    Properties []TMXProperty `xml:"properties>property"`
+    Point *struct{} `xml:"point"`
+    Ellipse *struct{} `xml:"ellipse"`
+    Polygon *struct{} `xml:"polygon"`
+    Polyline *struct{} `xml:"polyline"`
+    Text *struct{} `xml:"text"`
+}
+
+// Structures which match the JSON map file. Fields are exported so the json package can fill them
+type TiledJSONMap struct {
+    Orientation string `json:"orientation"`
+    Infinite bool `json:"infinite"`
+    Width int `json:"width"`
+    Height int `json:"height"`
+    TileWidth int `json:"tilewidth"`
+    TileHeight int `json:"tileheight"`
+    Properties []TiledJSONProperty `json:"properties"`
+    Tilesets []TiledJSONTileset `json:"tilesets"`
+    Layers []TiledJSONLayer `json:"layers"`
+}
+
+type TiledJSONProperty struct {
+    Name string `json:"name"`
+    Value interface{} `json:"value"`
+}
+
+type TiledJSONTileset struct {
+    FirstGid uint32 `json:"firstgid"`
+    Source string `json:"source"`
+    Tiles []struct {
+        Id uint32 `json:"id"`
+        Type string `json:"type"`
+        Class string `json:"class"`
+        Properties []TiledJSONProperty `json:"properties"`
+    } `json:"tiles"`
+}
+
+type TiledJSONLayer struct {
+    Name string `json:"name"`
+    Type string `json:"type"`
+    OffsetX float64 `json:"offsetx"`
+    OffsetY float64 `json:"offsety"`
+    Data json.RawMessage `json:"data"` // an array of global tile ids, or a base64 string
+    Encoding string `json:"encoding"`
+    Compression string `json:"compression"`
+    Objects []TiledJSONObject `json:"objects"`
+}
+
+type TiledJSONObject struct {
+    Id int `json:"id"`
+    Name string `json:"name"`
+    Type string `json:"type"`
+    Class string `json:"class"`
+    X float64 `json:"x"`
+    Y float64 `json:"y"`
+    Width float64 `json:"width"`
+    Height float64 `json:"height"`
+    Rotation float64 `json:"rotation"`
+    Gid uint32 `json:"gid"`
+    Template string `json:"template"`
+    Properties []TiledJSONProperty `json:"properties"`
+    Point bool `json:"point"`
+    Ellipse bool `json:"ellipse"`
+    Polygon []interface{} `json:"polygon"`
+    Polyline []interface{} `json:"polyline"`
+    Text interface{} `json:"text"`
+}
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the file extensions of Tiled maps
+var tiledMapExtensions = map[string]bool{".tmx": true, ".tmj": true, ".json": true}
+
+// Let's define the tile of the game of a tile without a "tile" property, by the name of its layer
+var tiledLayerTiles = map[string]byte{
+    "walls": '0',
+    "wall": '0',
+    "food": '.',
+    "dots": '.',
+    "pellets": 'o',
+    "power pellets": 'o',
+}
+
+// Let's define the tile of the game of each type of object which is a tile of the maze
+var tiledObjectTiles = map[string]byte{
+    "pacman": 'P',
+    "player": 'P',
+    "player 1": 'P',
+    "player 2": '2',
+    "player 3": '3',
+    "player 4": '4',
+    "enemy": 'E',
+    "spawn": 'E',
+}
+
+// Let's define the bits of a global tile id which flip or rotate the tile. They don't change the tile of the maze
+var tiledFlipBits = uint32(0xf0000000)
+
+/*
+    #########################
+    ## Reading a Tiled map ##
+    #########################
+*/
+
+/*
+    Function: isTiledMapFile
+    Check if a maze file is a Tiled map, by the extension of the file
+    Input: path to the maze file
+*/
+func isTiledMapFile(fileName string) bool {
+    return tiledMapExtensions[strings.ToLower(filepath.Ext(fileName))]
+}
+
+/*
+    Function: readTiledMapFile
+    Read a Tiled map (TMX or JSON) and change it to a maze
+    Outputs the maze and the warnings about the things of the map which are ignored
+    Input: path to the map file
+*/
+func readTiledMapFile(fileName string) (Maze, []string, error) {
+    data, err := readGameFile(fileName)
+    if err != nil {
+        return Maze{}, nil, err
+    }
+    var tiledMap *TiledMap
+    if strings.ToLower(filepath.Ext(fileName)) == ".tmx" {
+        tiledMap, err = parseTMXMap(data, filepath.Dir(fileName))
+    } else {
+        tiledMap, err = parseTiledJSONMap(data, filepath.Dir(fileName))
+    }
+    if err != nil {
+        return Maze{}, nil, err
+    }
+    maze, err := tiledMap.getMaze()
+    return maze, tiledMap.warnings, err
+}
+
+/*
+    Function: parseTMXMap
+    Read a Tiled map from a TMX file
+    Inputs: contents of the file and the folder of the file (external tilesets are relative to it)
+*/
+func parseTMXMap(data []byte, folder string) (*TiledMap, error) {
+    tmx := TMXMap{}
+    if err := xml.Unmarshal(data, &tmx); err != nil {
+        return nil, err
+    }
+    tiledMap := &TiledMap{
+        orientation: tmx.Orientation,
+        infinite: tmx.Infinite == 1,
+        width: tmx.Width,
+        height: tmx.Height,
+        tileWidth: tmx.TileWidth,
+        tileHeight: tmx.TileHeight,
+        properties: getTMXProperties(tmx.Properties),
+        tileTiles: map[uint32]byte{},
+    }
+
+    for _, tileset := range tmx.Tilesets {
+        tiles := tileset.Tiles
+        // an external tileset is a TSX file, with the same tiles as a tileset inside the map
+        if tileset.Source != "" {
+            external := TMXTileset{}
+            sourceData, err := readGameFile(filepath.Join(folder, tileset.Source))
+            if err == nil {
+                err = xml.Unmarshal(sourceData, &external)
+            }
+            if err != nil {
+                tiledMap.warn("tileset %q can't be read, its tile properties are not used: %v", tileset.Source, err)
+            }
+            tiles = external.Tiles
+        }
+        for _, tile := range tiles {
+            tiledMap.addTileTile(tileset.FirstGid+tile.Id, getTMXProperties(tile.Properties), tile.Type, tile.Class)
+        }
+    }
+
+    if err := tiledMap.addTMXLayers(tmx.TMXLayers); err != nil {
+        return nil, err
+    }
+    return tiledMap, nil
+}
+
+/*
+    Function: addTMXLayers
+    Add the layers of a TMX map. Image layers and groups are not used
+    Input: layers of the TMX map
+*/
+func (tiledMap *TiledMap) addTMXLayers(layers TMXLayers) error {
+    for _, tmxLayer := range layers.Layers {
+        layer := TiledLayer{name: tmxLayer.Name, kind: "tilelayer", offsetX: tmxLayer.OffsetX, offsetY: tmxLayer.OffsetY}
+        if len(tmxLayer.Data.Chunks) > 0 {
+            return fmt.Errorf("layer %q: infinite maps are not supported", layer.name)
+        }
+        var err error
+        if tmxLayer.Data.Encoding == "" {
+            for _, tile := range tmxLayer.Data.Tiles {
+                layer.tiles = append(layer.tiles, tile.Gid)
+            }
+        } else if tmxLayer.Data.Encoding == "csv" {
+            for _, value := range strings.Split(tmxLayer.Data.Text, ",") {
+                gid, parseErr := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+                if parseErr != nil {
+                    return fmt.Errorf("layer %q: %v", layer.name, parseErr)
+                }
+                layer.tiles = append(layer.tiles, uint32(gid))
+            }
+        } else if tmxLayer.Data.Encoding == "base64" {
+            layer.tiles, err = decodeTiledBase64(tmxLayer.Data.Text, tmxLayer.Data.Compression)
+        } else {
+            err = fmt.Errorf("unknown encoding %q", tmxLayer.Data.Encoding)
+        }
+        if err != nil {
+            return fmt.Errorf("layer %q: %v", layer.name, err)
+        }
+        tiledMap.layers = append(tiledMap.layers, layer)
+    }
+
+    for _, group := range layers.ObjectGroups {
+        layer := TiledLayer{name: group.Name, kind: "objectgroup", offsetX: group.OffsetX, offsetY: group.OffsetY}
+        for _, tmxObject := range group.Objects {
+            object := TiledObject{
+                id: tmxObject.Id,
+                name: tmxObject.Name,
+                kind: tmxObject.Type,
+                x: tmxObject.X,
+                y: tmxObject.Y,
+                width: tmxObject.Width,
+                height: tmxObject.Height,
+                rotation: tmxObject.Rotation,
+                gid: tmxObject.Gid,
+                template: tmxObject.Template,
+                properties: getTMXProperties(tmxObject.Properties),
+            }
+            if object.kind == "" {
+                object.kind = tmxObject.Class
+            }
+            switch {
+            case tmxObject.Point != nil:
+                object.shape = "point"
+            case tmxObject.Ellipse != nil:
+                object.shape = "ellipse"
+            case tmxObject.Polygon != nil:
+                object.shape = "polygon"
+            case tmxObject.Polyline != nil:
+                object.shape = "polyline"
+            case tmxObject.Text != nil:
+                object.shape = "text"
+            }
+            layer.objects = append(layer.objects, object)
+        }
+        tiledMap.layers = append(tiledMap.layers, layer)
+    }
+
+    for _, layer := range layers.ImageLayers {
+        tiledMap.layers = append(tiledMap.layers, TiledLayer{name: layer.Name, kind: "imagelayer"})
+    }
+    for _, layer := range layers.Groups {
+        tiledMap.layers = append(tiledMap.layers, TiledLayer{name: layer.Name, kind: "group"})
+    }
+    return nil
+}
+
+/*
+    Function: getTMXProperties
+    Get the custom properties of a TMX element
+    Input: properties of the TMX element
+*/
+func getTMXProperties(tmxProperties []TMXProperty) []TiledProperty {
+    properties := []TiledProperty{}
+    for _, property := range tmxProperties {
+        value := property.Value
+        if value == "" {
+            value = property.Text
+        }
+        properties = append(properties, TiledProperty{property.Name, value})
+    }
+    return properties
+}
+
+/*
+    Function: parseTiledJSONMap
+    Read a Tiled map from a JSON file
+    Inputs: contents of the file and the folder of the file (external tilesets are relative to it)
+*/
+func parseTiledJSONMap(data []byte, folder string) (*TiledMap, error) {
+    jsonMap := TiledJSONMap{}
+    if err := json.Unmarshal(data, &jsonMap); err != nil {
+        return nil, err
+    }
+    tiledMap := &TiledMap{
+        orientation: jsonMap.Orientation,
+        infinite: jsonMap.Infinite,
+        width: jsonMap.Width,
+        height: jsonMap.Height,
+        tileWidth: jsonMap.TileWidth,
+        tileHeight: jsonMap.TileHeight,
+        properties: getTiledJSONProperties(jsonMap.Properties),
+        tileTiles: map[uint32]byte{},
+    }
+    if tiledMap.infinite {
+        return nil, errors.New("infinite maps are not supported")
+    }
+
+    for _, tileset := range jsonMap.Tilesets {
+        // an external tileset is a JSON file, with the same tiles as a tileset inside the map
+        if tileset.Source != "" {
+            firstGid := tileset.FirstGid
+            sourceData, err := readGameFile(filepath.Join(folder, tileset.Source))
+            if err == nil {
+                err = json.Unmarshal(sourceData, &tileset)
+            }
+            if err != nil {
+                tiledMap.warn("tileset %q can't be read, its tile properties are not used: %v", tileset.Source, err)
+            }
+            tileset.FirstGid = firstGid
+        }
+        for _, tile := range tileset.Tiles {
+            tiledMap.addTileTile(tileset.FirstGid+tile.Id, getTiledJSONProperties(tile.Properties), tile.Type, tile.Class)
+        }
+    }
+
+    for _, jsonLayer := range jsonMap.Layers {
+        layer := TiledLayer{name: jsonLayer.Name, kind: jsonLayer.Type, offsetX: jsonLayer.OffsetX, offsetY: jsonLayer.OffsetY}
+        if layer.kind == "tilelayer" {
+            var err error
+            if jsonLayer.Encoding == "base64" {
+                var text string
+                err = json.Unmarshal(jsonLayer.Data, &text)
+                if err == nil {
+                    layer.tiles, err = decodeTiledBase64(text, jsonLayer.Compression)
+                }
+            } else {
+                err = json.Unmarshal(jsonLayer.Data, &layer.tiles)
+            }
+            if err != nil {
+                return nil, fmt.Errorf("layer %q: %v", layer.name, err)
+            }
+        }
+        for _, jsonObject := range jsonLayer.Objects {
+            object := TiledObject{
+                id: jsonObject.Id,
+                name: jsonObject.Name,
+                kind: jsonObject.Type,
+                x: jsonObject.X,
+                y: jsonObject.Y,
+                width: jsonObject.Width,
+                height: jsonObject.Height,
+                rotation: jsonObject.Rotation,
+                gid: jsonObject.Gid,
+                template: jsonObject.Template,
+                properties: getTiledJSONProperties(jsonObject.Properties),
+            }
+            if object.kind == "" {
+                object.kind = jsonObject.Class
+            }
+            switch {
+            case jsonObject.Point:
+                object.shape = "point"
+            case jsonObject.Ellipse:
+                object.shape = "ellipse"
+            case jsonObject.Polygon != nil:
+                object.shape = "polygon"
+            case jsonObject.Polyline != nil:
+                object.shape = "polyline"
+            case jsonObject.Text != nil:
+                object.shape = "text"
+            }
+            layer.objects = append(layer.objects, object)
+        }
+        tiledMap.layers = append(tiledMap.layers, layer)
+    }
+    return tiledMap, nil
+}
+
+/*
+    Function: getTiledJSONProperties
+    Get the custom properties of a JSON map element. Values which are not strings (numbers, booleans, objects) are
+    written as text
+    Input: properties of the JSON element
+*/
+func getTiledJSONProperties(jsonProperties []TiledJSONProperty) []TiledProperty {
+    properties := []TiledProperty{}
+    for _, property := range jsonProperties {
+        properties = append(properties, TiledProperty{property.Name, fmt.Sprint(property.Value)})
+    }
+    return properties
+}
+
+/*
+    Function: decodeTiledBase64
+    Decode the tiles of a layer written as base64, with zlib, gzip or no compression
+    Inputs: base64 text and the compression
+*/
+func decodeTiledBase64(text string, compression string) ([]uint32, error) {
+    data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+    if err != nil {
+        return nil, err
+    }
+    switch compression {
+    case "":
+    case "zlib":
+        reader, zlibErr := zlib.NewReader(bytes.NewReader(data))
+        if zlibErr != nil {
+            return nil, zlibErr
+        }
+        data, err = ioutil.ReadAll(reader)
+    case "gzip":
+        reader, gzipErr := gzip.NewReader(bytes.NewReader(data))
+        if gzipErr != nil {
+            return nil, gzipErr
+        }
+        data, err = ioutil.ReadAll(reader)
+    default:
+        return nil, fmt.Errorf("compression %q is not supported", compression)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    // each tile is a little endian 32 bit global tile id
+    tiles := make([]uint32, len(data)/4)
+    for i := range tiles {
+        tiles[i] = binary.LittleEndian.Uint32(data[i*4:])
+    }
+    return tiles, nil
+}
+
+/*
+    Function: addTileTile
+    Remember the tile of the game of a tile of a tileset, from its "tile" property or its type
+    Inputs: global tile id, custom properties, type and class of the tile
+*/
+func (tiledMap *TiledMap) addTileTile(gid uint32, properties []TiledProperty, kind string, class string) {
+    for _, name := range []string{getTiledProperty(properties, "tile"), kind, class} {
+        if tile, ok := mazeTileNames[strings.ToLower(name)]; ok {
+            tiledMap.tileTiles[gid] = tile
+            return
+        }
+    }
+}
+
+/*
+    Function: getTiledProperty
+    Get the value of a custom property. It's empty if there's no property with the name
+    Inputs: custom properties and the name of the property
+*/
+func getTiledProperty(properties []TiledProperty, name string) string {
+    for _, property := range properties {
+        if strings.EqualFold(property.name, name) {
+            return property.value
+        }
+    }
+    return ""
+}
+
+/*
+    Function: warn
+    Remember a warning about a thing of the map which is ignored
+    Inputs: format of the warning and its values (like fmt.Sprintf)
+*/
+func (tiledMap *TiledMap) warn(format string, values ...interface{}) {
+    tiledMap.warnings = append(tiledMap.warnings, fmt.Sprintf(format, values...))
+}
+
+/*
+    ################################
+    ## Changing the map to a maze ##
+    ################################
+*/
+
+/*
+    Function: getMaze
+    Change the Tiled map to a maze of the game
+*/
+func (tiledMap *TiledMap) getMaze() (Maze, error) {
+    maze := Maze{}
+    if tiledMap.width <= 0 || tiledMap.height <= 0 || tiledMap.tileWidth <= 0 || tiledMap.tileHeight <= 0 {
+        return maze, errors.New("the map has no size")
+    }
+    if tiledMap.orientation != "" && tiledMap.orientation != "orthogonal" {
+        tiledMap.warn("the map is %s, it's read as an orthogonal map", tiledMap.orientation)
+    }
+
+    // the custom properties of the map are the header of the maze
+    for _, property := range tiledMap.properties {
+        line := property.name+": "+property.value
+        if strings.HasPrefix(strings.ToLower(property.name), "tile ") {
+            tiledMap.warn("map property %q is not used, tiles are given by the tileset", property.name)
+        } else if err := parseMazeHeaderLine(&maze, map[byte]byte{}, line); err != nil {
+            tiledMap.warn("map property %q is not used: %v", property.name, err)
+        }
+    }
+
+    // the grid starts with empty paths
+    grid := make([][]byte, tiledMap.height)
+    for row := range grid {
+        grid[row] = bytes.Repeat([]byte{' '}, tiledMap.width)
+    }
+
+    // the tunnel objects, which are paired after reading all the layers
+    tunnels := []TiledObject{}
+    tunnelLayers := map[int]string{}
+    for _, layer := range tiledMap.layers {
+        if layer.offsetX != 0 || layer.offsetY != 0 {
+            tiledMap.warn("layer %q: the offset of the layer is not supported, it's ignored", layer.name)
+        }
+        switch layer.kind {
+        case "tilelayer":
+            tiledMap.addTileLayer(grid, layer)
+        case "objectgroup":
+            for _, object := range layer.objects {
+                if object, ok := tiledMap.addObject(grid, &maze, layer, object); ok {
+                    tunnels = append(tunnels, object)
+                    tunnelLayers[object.id] = layer.name
+                }
+            }
+        case "imagelayer":
+            tiledMap.warn("layer %q: image layers are not supported, it's ignored", layer.name)
+        case "group":
+            tiledMap.warn("layer %q: group layers are not supported, it's ignored with the layers inside it", layer.name)
+        default:
+            tiledMap.warn("layer %q: layers of type %q are not supported, it's ignored", layer.name, layer.kind)
+        }
+    }
+    tiledMap.addTunnels(&maze, tunnels, tunnelLayers)
+
+    for _, line := range grid {
+        maze.rows = append(maze.rows, string(line))
+    }
+    return maze, nil
+}
+
+/*
+    Function: addTileLayer
+    Put the tiles of a tile layer on the grid. Empty tiles (global tile id 0) don't change the grid
+    Inputs: grid and the tile layer
+*/
+func (tiledMap *TiledMap) addTileLayer(grid [][]byte, layer TiledLayer) {
+    if len(layer.tiles) != tiledMap.width*tiledMap.height {
+        tiledMap.warn("layer %q: the layer has %d tiles instead of %d, the tiles outside the map are ignored", layer.name, len(layer.tiles), tiledMap.width*tiledMap.height)
+    }
+    layerTile, hasLayerTile := tiledLayerTiles[strings.ToLower(layer.name)]
+    unknown := map[uint32]bool{}
+    for i, gid := range layer.tiles {
+        gid = gid &^ tiledFlipBits
+        if gid == 0 || i >= tiledMap.width*tiledMap.height {
+            continue
+        }
+        tile, ok := tiledMap.tileTiles[gid]
+        if !ok && hasLayerTile {
+            tile, ok = layerTile, true
+        }
+        if !ok {
+            if !unknown[gid] {
+                tiledMap.warn("layer %q: tile %d has no \"tile\" property and the layer name doesn't tell what it is, it's ignored", layer.name, gid)
+                unknown[gid] = true
+            }
+            continue
+        }
+        grid[i/tiledMap.width][i%tiledMap.width] = tile
+    }
+}
+
+/*
+    Function: addObject
+    Put an object of an object layer on the maze. A tunnel object is returned, to pair it with the other end later
+    Inputs: grid, maze, the object layer and the object
+*/
+func (tiledMap *TiledMap) addObject(grid [][]byte, maze *Maze, layer TiledLayer, object TiledObject) (TiledObject, bool) {
+    kind := strings.ToLower(object.kind)
+    if kind == "" {
+        kind = strings.ToLower(object.name)
+    }
+    tile, isTile := tiledObjectTiles[kind]
+    if !isTile && kind != "fruit" && kind != "tunnel" {
+        tiledMap.warn("layer %q, object %q (id %d): objects of type %q are not supported, it's ignored", layer.name, object.name, object.id, kind)
+        return object, false
+    }
+
+    // the object is on the maze point under its center. The position of a tile object is its bottom left corner
+    switch object.shape {
+    case "text":
+        tiledMap.warn("layer %q, object %q (id %d): text objects are not supported, it's ignored", layer.name, object.name, object.id)
+        return object, false
+    case "polygon", "polyline":
+        tiledMap.warn("layer %q, object %q (id %d): the %s shape is not supported, the first point is used", layer.name, object.name, object.id, object.shape)
+        object.width, object.height = 0, 0
+    }
+    if object.rotation != 0 {
+        tiledMap.warn("layer %q, object %q (id %d): rotated objects are not supported, the rotation is ignored", layer.name, object.name, object.id)
+    }
+    if object.template != "" {
+        tiledMap.warn("layer %q, object %q (id %d): templates are not supported, only the values of the object are used", layer.name, object.name, object.id)
+    }
+    centerY := object.y+object.height/2
+    if object.gid != 0 {
+        centerY = object.y-object.height/2
+    }
+    col := int(math.Floor((object.x+object.width/2)/float64(tiledMap.tileWidth)))
+    row := int(math.Floor(centerY/float64(tiledMap.tileHeight)))
+    if col < 0 || col >= tiledMap.width || row < 0 || row >= tiledMap.height {
+        tiledMap.warn("layer %q, object %q (id %d): the object is outside the map, it's ignored", layer.name, object.name, object.id)
+        return object, false
+    }
+
+    switch {
+    case isTile:
+        grid[row][col] = tile
+    case kind == "fruit":
+        maze.fruit = []int{col, row}
+    case kind == "tunnel":
+        // the maze point of the tunnel end is kept in the position until the ends are paired
+        object.x, object.y = float64(col), float64(row)
+        return object, true
+    }
+    return object, false
+}
+
+/*
+    Function: addTunnels
+    Pair the ends of the tunnels. An end points to the other end with a "to" property (the id of the other object),
+    otherwise the two ends have the same name
+    Inputs: maze, tunnel objects (their position is their maze point) and the layer of each tunnel object
+*/
+func (tiledMap *TiledMap) addTunnels(maze *Maze, tunnels []TiledObject, layers map[int]string) {
+    paired := map[int]bool{}
+    for i, end := range tunnels {
+        if paired[end.id] {
+            continue
+        }
+        other := -1
+        if to := getTiledProperty(end.properties, "to"); to != "" {
+            for j, candidate := range tunnels {
+                if strconv.Itoa(candidate.id) == to && j != i {
+                    other = j
+                }
+            }
+        } else {
+            for j, candidate := range tunnels {
+                if j != i && !paired[candidate.id] && candidate.name != "" && candidate.name == end.name && getTiledProperty(candidate.properties, "to") == "" {
+                    other = j
+                    break
+                }
+            }
+        }
+        if other < 0 || paired[tunnels[other].id] {
+            tiledMap.warn("layer %q, object %q (id %d): the tunnel has no other end, it's ignored", layers[end.id], end.name, end.id)
+            continue
+        }
+        paired[end.id] = true
+        paired[tunnels[other].id] = true
+        maze.tunnels = append(maze.tunnels, [2][2]int{
+            {int(end.x), int(end.y)},
+            {int(tunnels[other].x), int(tunnels[other].y)},
+        })
+    }
+}
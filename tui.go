@@ -0,0 +1,424 @@
+package main
+
+/*
+    This file contains the terminal renderer (-tui flag).
+
+    The game runs with the same game logic as in the window, but the maze, food, PacMen and enemies are drawn as
+    colored characters in the terminal with ANSI escape codes, so the game can be played over SSH on a machine
+    without a display. Each maze point is drawn as two characters, so the maze keeps about the same shape.
+
+    The terminal is put in raw mode (with the stty command), so each key is read as soon as it's pressed. A terminal
+    only tells when a key is pressed and not when it's released, so PacMan keeps going in the last direction pressed.
+    Keys: arrow keys (or W A S D) move PacMan, Space starts the game, 2 starts the two player mode, Enter continues the
+    saved game, Tab turns the assist on or off and Q (or Ctrl+C) quits.
+
+    -printmaze draws a maze file once with the same characters and colors, to check a maze file quickly.
+*/
+import (
+    "fmt"
+    "image/color"
+    "log"
+    "os"
+    "os/exec"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Let's define how often the terminal is drawn (every 3 frames is 20 times per second, enough for a terminal over SSH)
+var tuiDrawFrames = 3
+
+// Let's define the colors of the characters in the terminal
+var tuiPacmanColor = color.RGBA{R: 255, G: 255, B: 0, A: 255}
+var tuiFoodColor = color.RGBA{R: 255, G: 184, B: 151, A: 255}
+var tuiFrightenedColor = color.RGBA{R: 33, G: 33, B: 255, A: 255}
+var tuiFruitColor = color.RGBA{R: 255, G: 0, B: 0, A: 255}
+var tuiEnemyColors = []color.RGBA{
+    {R: 255, G: 0, B: 0, A: 255},
+    {R: 255, G: 184, B: 255, A: 255},
+    {R: 0, G: 255, B: 255, A: 255},
+    {R: 255, G: 184, B: 82, A: 255},
+    {R: 0, G: 255, B: 0, A: 255},
+}
+
+// Let's define the key of each arrow key escape code (ESC [ A to ESC [ D). Arrow keys are given as U, R, D and L
+var tuiArrowKeys = map[byte]byte{'A': 'U', 'B': 'D', 'C': 'R', 'D': 'L'}
+
+// Let's define the direction of each letter key which moves PacMan
+var tuiLetterKeys = map[byte]byte{'w': 'U', 'd': 'R', 's': 'D', 'a': 'L'}
+
+// Let's define the character of each key of the start screen which can be pressed in the terminal
+var tuiModeKeys = map[Key]byte{Key2: '2', KeyEnter: '\r'}
+
+// Variable to know if the game is played in the terminal
+var isTUI = false
+
+// Variable to hold the direction PacMan keeps going in the terminal
+var tuiMove = byte('I')
+
+// Variable to hold the keys pressed in the terminal since the previous frame
+var tuiPressed = map[byte]bool{}
+
+/*
+    #############################
+    ## Running in the terminal ##
+    #############################
+*/
+
+/*
+    Function: runTUI
+    Run the game in the terminal until Q or Ctrl+C is pressed
+*/
+func runTUI() {
+    state, err := startRawMode()
+    if err != nil {
+        log.Fatal("-tui needs a terminal: ", err)
+    }
+
+    // let's draw on the other screen of the terminal without the cursor, so the shell is back as it was after the game
+    fmt.Print("\x1b[?1049h\x1b[?25l\x1b[2J")
+    defer func() {
+        fmt.Print("\x1b[0m\x1b[?25h\x1b[?1049l")
+        stopRawMode(state)
+    }()
+
+    keys := make(chan byte, 64)
+    go readTUIKeys(keys)
+
+    ticker := time.NewTicker(time.Second/60)
+    defer ticker.Stop()
+    for frame := 0; ; frame++ {
+        <-ticker.C
+
+        // let's take all the keys pressed since the previous frame
+        tuiPressed = map[byte]bool{}
+        for hasKeys := true; hasKeys; {
+            select {
+            case key := <-keys:
+                if key == 'q' || key == 3 {
+                    return
+                }
+                if direction, ok := tuiLetterKeys[key]; ok {
+                    key = direction
+                }
+                if key == 'U' || key == 'R' || key == 'D' || key == 'L' {
+                    tuiMove = key
+                }
+                tuiPressed[key] = true
+            default:
+                hasKeys = false
+            }
+        }
+
+        // PacMan stops on the screens between the games, so he doesn't run off when the next level starts
+        if !gameInfo.isStarted || gameInfo.isLevelComplete || gameInfo.isGameOver {
+            tuiMove = 'I'
+        }
+        if tuiPressed['\t'] {
+            isAssistOn = !isAssistOn
+        }
+
+        updateGame()
+        if netHost != nil {
+            netHost.broadcast()
+            netHost.announce()
+        }
+        if spectatorServer != nil {
+            spectatorServer.publish()
+        }
+
+        if frame%tuiDrawFrames == 0 {
+            os.Stdout.WriteString(drawTUI())
+        }
+    }
+}
+
+/*
+    Function: startRawMode
+    Put the terminal in raw mode, so keys are read one by one without being shown
+    Outputs the settings of the terminal before, to restore them with stopRawMode
+*/
+func startRawMode() (string, error) {
+    command := exec.Command("stty", "-g")
+    command.Stdin = os.Stdin
+    state, err := command.Output()
+    if err != nil {
+        return "", err
+    }
+
+    command = exec.Command("stty", "raw", "-echo")
+    command.Stdin = os.Stdin
+    return strings.TrimSpace(string(state)), command.Run()
+}
+
+/*
+    Function: stopRawMode
+    Restore the settings of the terminal
+    Input: settings given by startRawMode
+*/
+func stopRawMode(state string) {
+    command := exec.Command("stty", state)
+    command.Stdin = os.Stdin
+    if err := command.Run(); err != nil {
+        log.Println("the terminal can't be restored, run reset:", err)
+    }
+}
+
+/*
+    Function: readTUIKeys
+    Read the keys from the terminal and send them to the channel. Arrow keys are sent as U, R, D and L, letters as lower case
+    Input: channel of the keys
+*/
+func readTUIKeys(keys chan byte) {
+    buffer := make([]byte, 64)
+    for {
+        count, err := os.Stdin.Read(buffer)
+        if err != nil {
+            // the terminal is closed, let's quit
+            keys <- 'q'
+            return
+        }
+
+        for i := 0; i < count; i++ {
+            key := buffer[i]
+
+            // an arrow key is ESC [ A (or ESC O A), other escape codes are skipped
+            if key == 27 {
+                if i+2 < count && (buffer[i+1] == '[' || buffer[i+1] == 'O') {
+                    if arrow, ok := tuiArrowKeys[buffer[i+2]]; ok {
+                        keys <- arrow
+                    }
+                    i = i+2
+                }
+                continue
+            }
+            if key >= 'A' && key <= 'Z' {
+                key = key-'A'+'a'
+            }
+            keys <- key
+        }
+    }
+}
+
+/*
+    Function: isTUIKeyPressed
+    Check if a key was pressed in the terminal since the previous frame
+    Input: key
+*/
+func isTUIKeyPressed(key byte) bool {
+    return tuiPressed[key]
+}
+
+/*
+    ##########################
+    ## Drawing the terminal ##
+    ##########################
+*/
+
+/*
+    Function: drawTUI
+    Draw the HUD, the maze with the game objects and the message of the screen as ANSI text
+    Outputs the text which draws a frame from the top left corner of the terminal
+*/
+func drawTUI() string {
+    lines := []string{getTUIHUD()}
+
+    // let's put the characters of the maze, then the game objects on top of them
+    cells := getTUIMazeCells(gameInfo.maze, getWallColor(gameInfo.level))
+    if gameInfo.isStarted && !gameInfo.isLevelComplete && !gameInfo.isGameOver {
+        if fruit.visibility {
+            setTUICell(cells, fruit.x, fruit.y, "%", tuiFruitColor)
+        }
+        for i, enemy := range enemies {
+            clr := tuiEnemyColors[i%len(tuiEnemyColors)]
+            if enemy.isFrightened {
+                clr = tuiFrightenedColor
+            }
+            setTUICell(cells, enemy.x, enemy.y, "M", clr)
+        }
+        for _, pacman := range pacmen {
+            if isPacmanAlive(pacman) {
+                setTUICell(cells, pacman.x, pacman.y, getTUIPacman(pacman), tuiPacmanColor)
+            }
+        }
+    }
+    lines = append(lines, getTUIRows(cells)...)
+    lines = append(lines, getTUIMessage())
+
+    // each line clears the rest of the terminal line, and the lines below the frame are cleared at the end.
+    // The terminal is in raw mode, so each new line needs a carriage return too
+    return "\x1b[H"+strings.Join(lines, "\x1b[0m\x1b[K\r\n")+"\x1b[0m\x1b[J"
+}
+
+/*
+    Function: getTUIHUD
+    Get the line with the points, high score, level and lives
+*/
+func getTUIHUD() string {
+    if len(gameInfo.stats) == 0 {
+        return ""
+    }
+    stats := gameInfo.stats[0]
+    hud := "SCORE "+strconv.Itoa(stats.points)+"   HIGH SCORE "+strconv.Itoa(highScore)+"   LEVEL "+strconv.Itoa(gameInfo.level)+"   LIVES "+strconv.Itoa(stats.lives)
+    if numPlayers > 1 {
+        hud = getPlayerLabel()+"   "+hud
+    }
+    if isAssistOn {
+        hud = hud+"   ASSIST"
+    }
+    return hud
+}
+
+/*
+    Function: getTUIMessage
+    Get the line with the message of the current screen (start, ready, level complete, win or game over)
+*/
+func getTUIMessage() string {
+    if !gameInfo.isStarted {
+        if isNewGame && hasSaveGame {
+            return "Space: START  2: 2 PLAYERS  Enter: CONTINUE  Q: QUIT"
+        }
+        if isNewGame {
+            return "Space: START  2: 2 PLAYERS  Tab: ASSIST  Q: QUIT"
+        }
+        return "Press Space to START"
+    }
+    if gameInfo.isLevelComplete {
+        if gameInfo.level+1 <= len(LEVELS) {
+            return "LEVEL COMPLETE!  Press Space to START"
+        }
+        return "YOU WIN!  Press Space to START"
+    }
+    if gameInfo.isGameOver {
+        return "GAME OVER  Press Space to START"
+    }
+    if gameInfo.freezeTimer > 0 {
+        if numPlayers > 1 {
+            return getPlayerLabel()+"  READY!"
+        }
+        return "READY!"
+    }
+    return ""
+}
+
+/*
+    Function: getTUIPacman
+    Get the character of a PacMan, with the mouth open to the direction he's moving
+    Input: reference to a PacMan game object
+*/
+func getTUIPacman(pacman *Sprite) string {
+    switch pacman.direction {
+    case 'U':
+        return "V"
+    case 'D':
+        return "^"
+    case 'L':
+        return ">"
+    }
+    return "<"
+}
+
+/*
+    ##########################
+    ## Maze in the terminal ##
+    ##########################
+*/
+
+// Structure of a single maze point drawn in the terminal
+type TUICell struct {
+    text string // holds the two characters of the maze point
+    clr color.RGBA // holds the color of the characters
+}
+
+/*
+    Function: getTUIMazeCells
+    Get the characters of each maze point: walls, food, power pellets and the starts of the players
+    Inputs: rows of the maze and the wall color
+*/
+func getTUIMazeCells(maze []string, wallColor color.Color) [][]TUICell {
+    r, g, b, _ := wallColor.RGBA()
+    wall := color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 255}
+
+    cells := make([][]TUICell, len(maze))
+    for row, line := range maze {
+        cells[row] = make([]TUICell, len(line))
+        for col, char := range line {
+            switch char {
+            case '0':
+                cells[row][col] = TUICell{"██", wall}
+            case '.':
+                cells[row][col] = TUICell{"· ", tuiFoodColor}
+            case 'o':
+                cells[row][col] = TUICell{"● ", tuiFoodColor}
+            default:
+                cells[row][col] = TUICell{"  ", wall}
+            }
+        }
+    }
+    return cells
+}
+
+/*
+    Function: setTUICell
+    Put a game object on the maze point it's closest to
+    Inputs: characters of the maze, position of the game object, its character and its color
+*/
+func setTUICell(cells [][]TUICell, x float64, y float64, text string, clr color.RGBA) {
+    col, row := getMazePointFromPosition(x, y)
+    if row >= 0 && row < len(cells) && col >= 0 && col < len(cells[row]) {
+        cells[row][col] = TUICell{text+" ", clr}
+    }
+}
+
+/*
+    Function: getTUIRows
+    Get the lines of the maze with the ANSI color codes. A color code is written only when the color changes
+    Input: characters of the maze
+*/
+func getTUIRows(cells [][]TUICell) []string {
+    rows := []string{}
+    for _, line := range cells {
+        row := strings.Builder{}
+        current := color.RGBA{}
+        for _, cell := range line {
+            if cell.clr != current {
+                row.WriteString(fmt.Sprintf("\x1b[38;2;%d;%d;%dm", cell.clr.R, cell.clr.G, cell.clr.B))
+                current = cell.clr
+            }
+            row.WriteString(cell.text)
+        }
+        rows = append(rows, row.String())
+    }
+    return rows
+}
+
+/*
+    Function: printMazeFile
+    Print a maze file with the characters and colors of the terminal renderer. The starts of the players are shown as their number
+    and the spawn points of the enemies as E. The name, the author and the par time of the maze are printed above it
+    Input: path to the maze file
+*/
+func printMazeFile(fileName string) {
+    mazeFile := readMazeFile(fileName)
+    maze := mazeFile.rows
+    wallColor := color.Color(defaultWallColor)
+    if mazeFile.wallColor.A != 0 {
+        wallColor = mazeFile.wallColor
+    }
+    if info := getMazeInfo(mazeFile); info != "" {
+        fmt.Println(info)
+    }
+    cells := getTUIMazeCells(maze, wallColor)
+    for row, line := range maze {
+        for col, char := range line {
+            if char == 'P' || (char >= '1' && char <= '4') {
+                cells[row][col] = TUICell{string(char)+" ", tuiPacmanColor}
+            } else if char == 'E' {
+                cells[row][col] = TUICell{"E ", tuiEnemyColors[0]}
+            }
+        }
+    }
+    for _, row := range getTUIRows(cells) {
+        fmt.Println(row+"\x1b[0m")
+    }
+}
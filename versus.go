@@ -0,0 +1,243 @@
+package main
+
+/*
+    This file contains the versus mode.
+
+    In the versus mode two players play against each other. One player moves PacMan and the other player steers one of
+    the enemies, while the other enemies are moved by the game. The enemy of the player turns only at a junction, in
+    the same place the game chooses a new direction for the other enemies. The direction pressed last is kept until
+    the enemy gets to a junction where it can turn that way.
+
+    Player 1 uses the arrow keys and player 2 uses W A S D (or their gamepads), whichever role they have.
+
+    A match has a number of rounds (set with the -rounds flag). The players swap roles after each round, and a round
+    is over when PacMan has no lives left or eats all the food.
+    The player moving PacMan gets a point for each dot eaten, the player steering the enemy gets points for each time
+    PacMan is caught by their enemy. The player with the most points after all the rounds wins the match.
+*/
+import (
+    "image/color"
+    "strconv"
+)
+
+// Structure which keeps the score of a player in a versus match
+type VersusScore struct {
+    dots int // holds the number of dots (food and power pellets) eaten while playing PacMan
+    catches int // holds the number of times PacMan was caught while playing the enemy
+}
+
+// Let's define the number of rounds in a match (set with the -rounds flag)
+var versusRounds = 4
+
+// Let's define the points a player gets for catching PacMan (a dot is 1 point)
+var versusCatchPoints = 50
+
+// Let's define the color of the enemy steered by a player, so it can be told apart from the other enemies
+var versusEnemyColor = color.RGBA{R: 255, G: 255, B: 120, A: 255}
+
+// Variable to know if the versus mode is being played
+var isVersus = false
+
+// Variable to hold the current round of the match, starting from 1
+var versusRound = 0
+
+// Variable to hold the scores of the two players of the match
+var versusScores [2]VersusScore
+
+// Variable to hold the player who moves PacMan in this round (0 or 1). The other player steers the enemy
+var versusPacmanPlayer = 0
+
+// Variable to hold the enemy steered by a player
+var versusEnemy *Sprite
+
+// Variable to hold the direction the enemy player pressed last, which is used at the next junction
+var versusEnemyInput byte = 'I'
+
+/*
+    Function: startVersus
+    Start a new versus match from the first round
+*/
+func startVersus() {
+    isVersus = true
+    versusRound = 1
+    versusScores = [2]VersusScore{}
+    startVersusRound()
+}
+
+/*
+    Function: startVersusRound
+    Start the current round of the match. PacMan has all the lives again, and the players have the roles of this round
+    Each maze is played twice in a row, so both players get to be PacMan in the same maze
+*/
+func startVersusRound() {
+    versusPacmanPlayer = (versusRound-1)%2
+    versusEnemyInput = 'I'
+
+    gameInfo.stats = []PlayerStats{newPlayerStats()}
+    gameInfo.fruits = 0
+    initLevel((versusRound-1)/2%len(LEVELS)+1)
+
+    // the first enemy is steered by the player. A level without enemies (Ex: set in LEVELS) gets one for the player
+    if len(enemies) == 0 {
+        enemies = append(enemies, createEnemy(LEVELS[gameInfo.level].enemyAggression))
+    }
+    versusEnemy = enemies[0]
+    versusEnemy.tint = versusEnemyColor
+
+    startPlayers(1)
+}
+
+/*
+    Function: nextVersusRound
+    Start the next round of the match, or go back to the start screen when the match is over
+*/
+func nextVersusRound() {
+    if isVersusMatchOver() {
+        saveHighScore()
+        newGame()
+        return
+    }
+    versusRound = versusRound+1
+    startVersusRound()
+}
+
+/*
+    Function: isVersusRoundOver
+    Check if the current round is over (PacMan has no lives left or ate all the food)
+*/
+func isVersusRoundOver() bool {
+    return gameInfo.isGameOver || gameInfo.isLevelComplete
+}
+
+/*
+    Function: isVersusMatchOver
+    Check if all the rounds of the match are played
+*/
+func isVersusMatchOver() bool {
+    return isVersusRoundOver() && versusRound >= versusRounds
+}
+
+/*
+    Function: getVersusEnemyPlayer
+    Get the player who steers the enemy in this round (0 or 1)
+*/
+func getVersusEnemyPlayer() int {
+    return 1-versusPacmanPlayer
+}
+
+/*
+    Function: getPacmanController
+    Get the player whose keys (or gamepad) move a PacMan. In the versus mode it's the player who is PacMan in this round
+    Input: reference to a PacMan game object
+*/
+func getPacmanController(pacman *Sprite) int {
+    if isVersus {
+        return versusPacmanPlayer
+    }
+    return pacman.player
+}
+
+/*
+    Function: readVersusInput
+    Remember the direction the enemy player is pressing, so it can be used at the next junction
+*/
+func readVersusInput() {
+    if input := getPlayerInput(getVersusEnemyPlayer()); input != 'I' {
+        versusEnemyInput = input
+    }
+}
+
+/*
+    Function: getVersusEnemyDirection
+    Get the direction of the enemy steered by a player at a junction
+    The enemy turns to the direction the player pressed last if there's no wall, otherwise it keeps going.
+    At a dead end, where it can't go on, it gets a movable direction like the other enemies
+    Inputs: maze point and the current direction of the enemy
+*/
+func getVersusEnemyDirection(col int, row int, currentDirection byte) byte {
+    if isMovableDirection(col, row, versusEnemyInput) {
+        return versusEnemyInput
+    }
+    if isMovableDirection(col, row, currentDirection) {
+        return currentDirection
+    }
+    return getMovableDirection(col, row, currentDirection)
+}
+
+/*
+    Function: countVersusCatch
+    Give the enemy player points when their enemy catches PacMan
+    Input: reference to the enemy game object which caught PacMan
+*/
+func countVersusCatch(enemy *Sprite) {
+    if isVersus && enemy == versusEnemy {
+        versusScores[getVersusEnemyPlayer()].catches++
+    }
+}
+
+/*
+    Function: countVersusDot
+    Give the PacMan player a point for each dot eaten. This listens to the game events (see events.go)
+    Input: name of the event
+*/
+func countVersusDot(event string) {
+    if isVersus && (event == eventFoodEaten || event == eventPelletEaten) {
+        versusScores[versusPacmanPlayer].dots++
+    }
+}
+
+/*
+    Function: getVersusPoints
+    Get the points of a player in the match
+    Input: player (0 or 1)
+*/
+func getVersusPoints(player int) int {
+    return versusScores[player].dots+versusScores[player].catches*versusCatchPoints
+}
+
+/*
+    Function: getVersusRole
+    Get the label of a player with the role of the player in this round. Ex: P1 PACMAN
+    Input: player (0 or 1)
+*/
+func getVersusRole(player int) string {
+    if player == versusPacmanPlayer {
+        return "P"+strconv.Itoa(player+1)+" PACMAN"
+    }
+    return "P"+strconv.Itoa(player+1)+" ENEMY"
+}
+
+/*
+    Function: getVersusResult
+    Get the text shown when a round is over. After the last round it tells who won the match
+*/
+func getVersusResult() string {
+    if !isVersusMatchOver() {
+        return "ROUND "+strconv.Itoa(versusRound)+" OVER, Press Space"
+    }
+
+    first := getVersusPoints(0)
+    second := getVersusPoints(1)
+    switch {
+    case first > second:
+        return "P1 WINS THE MATCH, Press Space"
+    case second > first:
+        return "P2 WINS THE MATCH, Press Space"
+    }
+    return "THE MATCH IS A DRAW, Press Space"
+}
+
+/*
+    Function: drawVersusRoundOver
+    Show the screen at the end of a round, or the winner of the match after the last round
+    Input: screen
+*/
+func drawVersusRoundOver(screen *Image) {
+    // PacMan ate all the food or got caught
+    popup := &gameOver
+    if gameInfo.isLevelComplete {
+        popup = &levelComplete
+    }
+    drawSprite(screen, popup)
+    drawPrompt(screen, popup, getVersusResult())
+}
@@ -0,0 +1,27 @@
+package main
+
+/*
+    This file contains the tests of the versus mode (see versus.go).
+*/
+import (
+    "testing"
+)
+
+func TestVersusLevelWithoutEnemies(t *testing.T) {
+    // a level without enemies still gets an enemy for the player who steers it
+    level := LEVELS[1]
+    defer func() {
+        LEVELS[1] = level
+        newGame()
+    }()
+    noEnemies := level
+    noEnemies.numEnemies = 0
+    LEVELS[1] = noEnemies
+
+    setScreenSize()
+    newGame()
+    startVersus()
+    if len(enemies) != 1 || versusEnemy != enemies[0] {
+        t.Fatalf("the versus mode has %d enemies, expected the enemy of the player", len(enemies))
+    }
+}
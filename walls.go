@@ -0,0 +1,287 @@
+package main
+
+/*
+    This file contains the code which draws the maze walls.
+    Instead of drawing the same square image on every wall cell, each wall cell looks at its 8 neighbours
+    and gets a tile which draws only the outline of the wall (like the original Pac-Man corridors).
+
+    A theme can change how walls look:
+    wall      - a single image drawn on every wall cell (the old square blocks)
+    wallTiles - an image with the 47 wall tiles in a row, ordered by their wall mask (see getWallTileIndex)
+    If the theme has neither, the outlines are drawn with the wall color.
+*/
+import (
+    "image"
+    "image/color"
+    "log"
+)
+
+/*
+    ####################
+    ## Wall Tile Bits ##
+    ####################
+
+    Each neighbour of a wall cell is given a bit. If the neighbour is also a wall, the bit is set.
+
+        NW | N | NE
+        ---+---+---
+        W  | X | E
+        ---+---+---
+        SW | S | SE
+*/
+const (
+    wallN = 1 << iota
+    wallNE
+    wallE
+    wallSE
+    wallS
+    wallSW
+    wallW
+    wallNW
+)
+
+// Let's have a variable to define the distance between the cell border and the wall outline
+var wallInset = 3.0
+
+// Let's have a variable to define the thickness of the wall outline
+var wallLineWidth = 2.0
+
+// Let's have a variable to define the wall color used when a level doesn't give one
+var defaultWallColor = color.RGBA{R: 33, G: 33, B: 222, A: 255}
+
+// Variable to keep the already created wall tiles. key is the wall mask, so each different tile is created only once per level
+var wallTiles map[int]*Image
+
+// Variable to keep the wallTiles image of the theme, so it's loaded only once per level and each tile is cut from it
+var wallTilesImage *Image
+
+/*
+    Function: isWall
+    Check if there's a wall on the given maze point
+    Points outside the maze are also treated as walls, so the outline is drawn only towards the paths
+    Inputs: Maze Point (column, row)
+*/
+func isWall(col int, row int) bool {
+    if !isValidPoint(col, row) {
+        return true
+    }
+    return gameInfo.maze[row][col] == '0'
+}
+
+/*
+    Function: getWallMask
+    Get the mask (which neighbours are walls) of a wall cell
+
+    A corner neighbour only matters when both the neighbours next to it are walls.
+    Ex: if N is a path, NE does not change the look of the tile as the top edge is drawn anyway.
+    Clearing those corner bits leaves only 47 different masks (the well known 47 tile set).
+    Inputs: Maze Point (column, row)
+*/
+func getWallMask(col int, row int) int {
+    mask := 0
+
+    // let's set the bits of the 4 sides first
+    if isWall(col, row-1) {
+        mask |= wallN
+    }
+    if isWall(col+1, row) {
+        mask |= wallE
+    }
+    if isWall(col, row+1) {
+        mask |= wallS
+    }
+    if isWall(col-1, row) {
+        mask |= wallW
+    }
+
+    // now the corners, only if both the sides next to the corner are walls
+    if mask&wallN != 0 && mask&wallE != 0 && isWall(col+1, row-1) {
+        mask |= wallNE
+    }
+    if mask&wallS != 0 && mask&wallE != 0 && isWall(col+1, row+1) {
+        mask |= wallSE
+    }
+    if mask&wallS != 0 && mask&wallW != 0 && isWall(col-1, row+1) {
+        mask |= wallSW
+    }
+    if mask&wallN != 0 && mask&wallW != 0 && isWall(col-1, row-1) {
+        mask |= wallNW
+    }
+
+    return mask
+}
+
+/*
+    Function: drawWallQuarter
+    Draw one quarter of a wall tile.
+
+    Each quarter of the tile depends only on the two sides and the corner next to it.
+    The quarter is drawn as if it is the top right (NE) quarter. flipX and flipY mirror it to the other quarters.
+    Inputs: tile image, whether the vertical side (N or S) is a wall, whether the horizontal side (E or W) is a wall,
+            whether the corner is a wall, mirror flags and the wall color
+*/
+func drawWallQuarter(img *Image, sideV bool, sideH bool, corner bool, flipX bool, flipY bool, clr color.Color) {
+    size := float64(blockSize)
+    half := size/2.0
+    d := wallInset
+    w := wallLineWidth
+
+    // drawRect draws a rectangle given in NE quarter coordinates, mirrored into the correct quarter
+    drawRect := func(x float64, y float64, width float64, height float64) {
+        if flipX {
+            x = size-x-width
+        }
+        if flipY {
+            y = size-y-height
+        }
+        fillRect(img, x, y, width, height, clr)
+    }
+
+    switch {
+    case !sideV && !sideH:
+        // outer corner, the top and the right edges meet
+        drawRect(half, d, size-d-half, w)
+        drawRect(size-d-w, d, w, half-d)
+    case !sideV && sideH:
+        // only the top is open, draw a horizontal edge
+        drawRect(half, d, size-half, w)
+    case sideV && !sideH:
+        // only the right is open, draw a vertical edge
+        drawRect(size-d-w, 0, w, half)
+    case !corner:
+        // both sides are walls but the corner is open, draw a small inner corner to join the neighbour edges
+        drawRect(size-d-w, 0, w, d+w)
+        drawRect(size-d-w, d, d+w, w)
+    }
+    // if the sides and the corner are all walls, this quarter is inside the wall and nothing is drawn
+}
+
+/*
+    Function: getWallTileIndex
+    Get the position of a wall mask in the list of all the 47 wall masks (sorted from smallest to largest)
+    This is the position of the tile in a wallTiles image of a theme
+    Input: wall mask
+*/
+func getWallTileIndex(mask int) int {
+    index := 0
+    for m := 0; m < mask; m++ {
+        // a mask is one of the 47 masks only if none of its corner bits are set without both of their sides
+        if m&wallNE != 0 && m&(wallN|wallE) != wallN|wallE ||
+            m&wallSE != 0 && m&(wallS|wallE) != wallS|wallE ||
+            m&wallSW != 0 && m&(wallS|wallW) != wallS|wallW ||
+            m&wallNW != 0 && m&(wallN|wallW) != wallN|wallW {
+            continue
+        }
+        index++
+    }
+    return index
+}
+
+/*
+    Function: getWallTilesImage
+    Get the wallTiles image of the theme. It's loaded from the file the first time on a level
+    Input: wallTiles image file
+*/
+func getWallTilesImage(tilesFile string) *Image {
+    if wallTilesImage == nil {
+        tiles, err := loadGameImage(tilesFile)
+        if err != nil {
+            log.Fatal(err)
+        }
+        wallTilesImage = tiles
+    }
+    return wallTilesImage
+}
+
+/*
+    Function: createWallTileFromImage
+    Cut the tile of the given mask from a wallTiles image and resize it to the block size
+    Input: wallTiles image and the wall mask
+*/
+func createWallTileFromImage(tiles *Image, mask int) *Image {
+    // tiles are squares, so the size of a tile is the height of the image
+    _, tileSize := tiles.Size()
+    tileX := getWallTileIndex(mask)*tileSize
+    tile := tiles.SubImage(image.Rect(tileX, 0, tileX+tileSize, tileSize)).(*Image)
+
+    img, _ := newImage(blockSize, blockSize)
+    opts := &DrawImageOptions{}
+    opts.GeoM.Scale(float64(blockSize)/float64(tileSize), float64(blockSize)/float64(tileSize))
+    img.DrawImage(tile, opts)
+    return img
+}
+
+/*
+    Function: createWallTile
+    Create the image of a wall tile for the given mask
+    Inputs: wall mask and the wall color
+*/
+func createWallTile(mask int, clr color.Color) *Image {
+    img, _ := newImage(blockSize, blockSize)
+
+    has := func(bit int) bool {
+        return mask&bit != 0
+    }
+
+    // draw the 4 quarters of the tile
+    drawWallQuarter(img, has(wallN), has(wallE), has(wallNE), false, false, clr)
+    drawWallQuarter(img, has(wallS), has(wallE), has(wallSE), false, true, clr)
+    drawWallQuarter(img, has(wallS), has(wallW), has(wallSW), true, true, clr)
+    drawWallQuarter(img, has(wallN), has(wallW), has(wallNW), true, false, clr)
+
+    return img
+}
+
+/*
+    Function: createWallSprite
+    Returns a wall Sprite for the given maze point with the tile matching its neighbours
+    Inputs: Maze Point (column, row) and the screen position (x, y)
+*/
+func createWallSprite(col int, row int, x float64, y float64) Sprite {
+    // if the theme gives a single wall image, let's draw it on every wall cell
+    if wallFile := getAsset("wall"); wallFile != "" {
+        return createSprite(wallFile, blockSize, blockSize, x, y)
+    }
+
+    mask := getWallMask(col, row)
+
+    // create the tile only if it's not created before (and only for a window, see hasWindow)
+    tile, ok := wallTiles[mask]
+    if !ok && hasWindow {
+        if tilesFile := getAsset("wallTiles"); tilesFile != "" {
+            tile = createWallTileFromImage(getWallTilesImage(tilesFile), mask)
+        } else {
+            tile = createWallTile(mask, getWallColor(gameInfo.level))
+        }
+        wallTiles[mask] = tile
+    }
+
+    return Sprite{
+        img: tile,
+        visibility: true,
+        x: x,
+        y: y,
+        speed: 1,
+    }
+}
+
+/*
+    Function: getWallColor
+    Get the wall color of a level.
+    The wall color of the theme is used first, then the color of the maze and the color of the level. If none of them gives
+    a color, the default color is used
+    Input: level
+*/
+func getWallColor(level int) color.Color {
+    if clr, ok := getPaletteColor("wall"); ok {
+        return clr
+    }
+    if clr := getLevelMaze(level).wallColor; clr.A != 0 {
+        return clr
+    }
+    clr := LEVELS[level].wallColor
+    if clr.A == 0 {
+        return defaultWallColor
+    }
+    return clr
+}
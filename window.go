@@ -0,0 +1,222 @@
+//go:build !nowindow
+// +build !nowindow
+
+package main
+
+/*
+    This file contains the window of the game: the images, the drawing, the keyboard, the mouse and the gamepads, which
+    all come from the ebiten library.
+
+    The rest of the game uses only the types and the functions of this file, so the game can also be built without ebiten
+    with the nowindow tag (see nowindow.go). ebiten opens a display when the game starts, so a game which is played
+    headless or in the terminal on a machine without a display is built with:
+    go build -tags nowindow
+*/
+import (
+    "github.com/hajimehoshi/ebiten"
+    "github.com/hajimehoshi/ebiten/ebitenutil"
+    "github.com/hajimehoshi/ebiten/inpututil"
+    "github.com/hajimehoshi/ebiten/text"
+    "golang.org/x/image/font"
+    "image"
+    "image/color"
+)
+
+/*
+    ###########
+    ## Types ##
+    ###########
+*/
+// Let's use the image, the drawing options, the keys and the mouse buttons of ebiten
+type Image = ebiten.Image
+type DrawImageOptions = ebiten.DrawImageOptions
+type Key = ebiten.Key
+type MouseButton = ebiten.MouseButton
+
+/*
+    ###############################
+    ## Defining Global Variables ##
+    ###############################
+*/
+
+// Let's define the keys and the mouse buttons used by the game
+const (
+    Key1 = ebiten.Key1
+    Key2 = ebiten.Key2
+    KeyA = ebiten.KeyA
+    KeyC = ebiten.KeyC
+    KeyD = ebiten.KeyD
+    KeyI = ebiten.KeyI
+    KeyJ = ebiten.KeyJ
+    KeyK = ebiten.KeyK
+    KeyL = ebiten.KeyL
+    KeyM = ebiten.KeyM
+    KeyS = ebiten.KeyS
+    KeyT = ebiten.KeyT
+    KeyV = ebiten.KeyV
+    KeyW = ebiten.KeyW
+    KeyY = ebiten.KeyY
+    KeyZ = ebiten.KeyZ
+    KeyKP4 = ebiten.KeyKP4
+    KeyKP5 = ebiten.KeyKP5
+    KeyKP6 = ebiten.KeyKP6
+    KeyKP8 = ebiten.KeyKP8
+    KeyF5 = ebiten.KeyF5
+    KeyF9 = ebiten.KeyF9
+    KeyUp = ebiten.KeyUp
+    KeyRight = ebiten.KeyRight
+    KeyDown = ebiten.KeyDown
+    KeyLeft = ebiten.KeyLeft
+    KeyControl = ebiten.KeyControl
+    KeyEnter = ebiten.KeyEnter
+    KeyEscape = ebiten.KeyEscape
+    KeySpace = ebiten.KeySpace
+    KeyTab = ebiten.KeyTab
+    KeyMax = ebiten.KeyMax
+    MouseButtonLeft = ebiten.MouseButtonLeft
+    MouseButtonRight = ebiten.MouseButtonRight
+)
+
+/*
+    #############################
+    ## Functions of the window ##
+    #############################
+*/
+
+/*
+    Function: runWindow
+    Open the window and call the update function 60 times per second until the window is closed
+    Inputs: update function, size of the screen, scale of the window and its title
+*/
+func runWindow(update func(*Image) error, width int, height int, scale float64, title string) error {
+    return ebiten.Run(update, width, height, scale, title)
+}
+
+/*
+    Function: resizeWindow
+    Change the size of the screen while the window is open
+    Input: size of the screen
+*/
+func resizeWindow(width int, height int) {
+    ebiten.SetScreenSize(width, height)
+}
+
+/*
+    Function: isDrawingSkipped
+    Check if the screen of this frame isn't shown, because the game is running slow
+*/
+func isDrawingSkipped() bool {
+    return ebiten.IsDrawingSkipped()
+}
+
+/*
+    Function: newImage
+    Create an empty image
+    Input: size of the image
+*/
+func newImage(width int, height int) (*Image, error) {
+    return ebiten.NewImage(width, height, ebiten.FilterDefault)
+}
+
+/*
+    Function: newImageFromImage
+    Create an image from a decoded image file
+    Input: decoded image
+*/
+func newImageFromImage(img image.Image) (*Image, error) {
+    return ebiten.NewImageFromImage(img, ebiten.FilterDefault)
+}
+
+/*
+    Function: fillRect
+    Draw a filled rectangle on an image
+    Inputs: image, position, size and color of the rectangle
+*/
+func fillRect(img *Image, x float64, y float64, width float64, height float64, clr color.Color) {
+    ebitenutil.DrawRect(img, x, y, width, height, clr)
+}
+
+/*
+    Function: drawFontText
+    Draw text with a font on an image. (x, y) is the start of the baseline of the text
+    Inputs: image, text, font, position and color
+*/
+func drawFontText(img *Image, str string, face font.Face, x int, y int, clr color.Color) {
+    text.Draw(img, str, face, x, y, clr)
+}
+
+/*
+    ########################
+    ## Functions of input ##
+    ########################
+*/
+
+/*
+    Function: isKeyPressed
+    Check if a key is held down
+    Input: key
+*/
+func isKeyPressed(key Key) bool {
+    return ebiten.IsKeyPressed(key)
+}
+
+/*
+    Function: isKeyJustPressed
+    Check if a key is pressed on this frame
+    Input: key
+*/
+func isKeyJustPressed(key Key) bool {
+    return inpututil.IsKeyJustPressed(key)
+}
+
+/*
+    Function: isMouseButtonPressed
+    Check if a mouse button is held down
+    Input: mouse button
+*/
+func isMouseButtonPressed(button MouseButton) bool {
+    return ebiten.IsMouseButtonPressed(button)
+}
+
+/*
+    Function: isMouseButtonJustPressed
+    Check if a mouse button is pressed on this frame
+    Input: mouse button
+*/
+func isMouseButtonJustPressed(button MouseButton) bool {
+    return inpututil.IsMouseButtonJustPressed(button)
+}
+
+/*
+    Function: cursorPosition
+    Get the position of the mouse on the screen
+*/
+func cursorPosition() (int, int) {
+    return ebiten.CursorPosition()
+}
+
+/*
+    Function: gamepadIDs
+    Get the ids of the connected gamepads
+*/
+func gamepadIDs() []int {
+    return ebiten.GamepadIDs()
+}
+
+/*
+    Function: gamepadAxisNum
+    Get the number of axes of a gamepad
+    Input: id of the gamepad
+*/
+func gamepadAxisNum(id int) int {
+    return ebiten.GamepadAxisNum(id)
+}
+
+/*
+    Function: gamepadAxis
+    Get the value of an axis of a gamepad, from -1 to 1
+    Inputs: id of the gamepad and the axis
+*/
+func gamepadAxis(id int, axis int) float64 {
+    return ebiten.GamepadAxis(id, axis)
+}
@@ -0,0 +1,194 @@
+package main
+
+/*
+    This file contains the world, which is a copy of everything that changes while the game runs.
+
+    The game keeps its state in global variables (gameInfo, pacmen, enemies, ...) and the game objects are referenced
+    with pointers, so the state can't be copied as it is. saveWorld copies the state into a World, which has only values
+    (the maze and the game objects are copied too). restoreWorld puts a saved World back into the global variables.
+    Images of the game objects are shared by all the copies, because they never change.
+
+    Saving and restoring the world lets the rollback game (see rollback.go) go back to an earlier frame and run the game
+    again, and lets two games run in the same process by swapping their worlds.
+*/
+import (
+    "encoding/binary"
+    "hash/fnv"
+    "math"
+)
+
+// Structure which keeps a copy of the state of the game
+type World struct {
+    gameInfo GameInfo // holds the game info, with its own copy of the maze and the stats
+    pacmen []Sprite // holds a copy of each PacMan
+    enemies []Sprite // holds a copy of each enemy
+    fruit Sprite // holds a copy of the fruit
+    random uint64 // holds the state of the random number generator
+    isNewGame bool // holds whether the start screen is for a new game
+    numPlayers int // holds the number of players taking turns
+    currentPlayer int // holds the player who has the turn
+    players []PlayerState // holds the saved games of the players taking turns
+    isVersus bool // holds whether the versus mode is being played
+    versusRound int // holds the round of the versus match
+    versusRounds int // holds the number of rounds of the versus match
+    versusPacmanPlayer int // holds the player who is PacMan in this round
+    versusScores [2]VersusScore // holds the scores of the versus match
+    versusEnemyInput byte // holds the direction the enemy player pressed last
+}
+
+/*
+    Function: saveWorld
+    Copy the state of the game into a World
+*/
+func saveWorld() World {
+    world := World{
+        gameInfo: gameInfo,
+        fruit: fruit,
+        random: gameRandom,
+        isNewGame: isNewGame,
+        numPlayers: numPlayers,
+        currentPlayer: currentPlayer,
+        players: append([]PlayerState{}, players...),
+        isVersus: isVersus,
+        versusRound: versusRound,
+        versusRounds: versusRounds,
+        versusPacmanPlayer: versusPacmanPlayer,
+        versusScores: versusScores,
+        versusEnemyInput: versusEnemyInput,
+    }
+    world.gameInfo.maze = append([]string{}, gameInfo.maze...)
+    world.gameInfo.stats = append([]PlayerStats{}, gameInfo.stats...)
+
+    for _, pacman := range pacmen {
+        world.pacmen = append(world.pacmen, *pacman)
+    }
+    for _, enemy := range enemies {
+        world.enemies = append(world.enemies, *enemy)
+    }
+    return world
+}
+
+/*
+    Function: restoreWorld
+    Put a saved World back into the game. The saved World is not changed, so it can be restored again
+    Input: saved world
+*/
+func restoreWorld(world World) {
+    gameInfo.stats = append([]PlayerStats{}, world.gameInfo.stats...)
+    setMaze(world.gameInfo.level, world.gameInfo.maze)
+
+    gameInfo = world.gameInfo
+    gameInfo.maze = append([]string{}, world.gameInfo.maze...)
+    gameInfo.stats = append([]PlayerStats{}, world.gameInfo.stats...)
+
+    for i, pacman := range pacmen {
+        *pacman = world.pacmen[i]
+    }
+    for i, enemy := range enemies {
+        *enemy = world.enemies[i]
+    }
+    fruit = world.fruit
+
+    gameRandom = world.random
+    isNewGame = world.isNewGame
+    numPlayers = world.numPlayers
+    currentPlayer = world.currentPlayer
+    players = append([]PlayerState{}, world.players...)
+    isVersus = world.isVersus
+    versusRound = world.versusRound
+    versusRounds = world.versusRounds
+    versusPacmanPlayer = world.versusPacmanPlayer
+    versusScores = world.versusScores
+    versusEnemyInput = world.versusEnemyInput
+
+    // the enemy of the versus mode is referenced with a pointer, so let's point it to the first enemy again
+    versusEnemy = nil
+    if isVersus && len(enemies) > 0 {
+        versusEnemy = enemies[0]
+    }
+}
+
+/*
+    Function: setMaze
+    Change the level and the maze of the game, and show or hide the food to match the maze
+    The game objects are created again if the level changes, the number of PacMen (gameInfo.stats) changes or the maze
+    has food where there's no food game object. Otherwise the game objects are kept as they are
+    Inputs: level and the maze
+*/
+func setMaze(level int, maze []string) {
+    rebuild := level != gameInfo.level || len(maze) != len(gameInfo.maze) || len(pacmen) != len(gameInfo.stats)
+
+    // food which is back in the maze (Ex: the next round of the versus mode) needs a food game object
+    for row := 0; !rebuild && row < len(maze); row++ {
+        for col := 0; col < len(maze[row]); col++ {
+            char := maze[row][col]
+            if (char == '.' || char == 'o') && (row >= len(mazeSprites) || col >= len(mazeSprites[row]) || mazeSprites[row][col] == nil) {
+                rebuild = true
+                break
+            }
+        }
+    }
+
+    if rebuild {
+        // locateGameObjects changes the maximum score, so let's keep it
+        maxScore := gameInfo.maxScore
+        gameInfo.level = level
+        currentTheme = getLevelTheme(level)
+        gameFont = loadFont(getAsset("font"))
+        gameInfo.maze = append([]string{}, maze...)
+        locateGameObjects()
+        gameInfo.maxScore = maxScore
+        return
+    }
+
+    // let's show and hide the food which changed
+    for row, line := range maze {
+        if line == gameInfo.maze[row] {
+            continue
+        }
+        for col := 0; col < len(line) && col < len(gameInfo.maze[row]); col++ {
+            if line[col] != gameInfo.maze[row][col] && mazeSprites[row][col] != nil {
+                mazeSprites[row][col].visibility = line[col] == '.' || line[col] == 'o'
+            }
+        }
+        gameInfo.maze[row] = line
+    }
+}
+
+/*
+    Function: getWorldChecksum
+    Get a checksum of a saved World. Two games which are in the same state have the same checksum
+    Input: saved world
+*/
+func getWorldChecksum(world World) uint64 {
+    hash := fnv.New64a()
+    write := func(values ...interface{}) {
+        for _, value := range values {
+            // writing into a hash doesn't fail, so the error of binary.Write is not checked
+            binary.Write(hash, binary.BigEndian, value)
+        }
+    }
+
+    info := world.gameInfo
+    write(int64(info.level), int64(info.score), int64(info.maxScore), int64(info.fruits))
+    write(int64(info.frightenedTimer), int64(info.fruitTimer), int64(info.freezeTimer))
+    write(info.isStarted, info.isGameOver, info.isLevelComplete, world.isNewGame)
+    for _, line := range info.maze {
+        hash.Write([]byte(line))
+    }
+    for _, stats := range info.stats {
+        write(int64(stats.lives), int64(stats.points), int64(stats.nextExtraLife))
+    }
+
+    sprites := append(append([]Sprite{}, world.pacmen...), world.enemies...)
+    for _, sprite := range append(sprites, world.fruit) {
+        write(math.Float64bits(sprite.x), math.Float64bits(sprite.y), sprite.direction, sprite.visibility, sprite.isFrightened)
+    }
+
+    write(world.random, int64(world.numPlayers), int64(world.currentPlayer), world.isVersus)
+    write(int64(world.versusRound), int64(world.versusPacmanPlayer), world.versusEnemyInput)
+    for _, score := range world.versusScores {
+        write(int64(score.dots), int64(score.catches))
+    }
+    return hash.Sum64()
+}